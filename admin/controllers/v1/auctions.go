@@ -4,20 +4,28 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/devzatruk/bizhubBackend/auctionservice"
 	"github.com/devzatruk/bizhubBackend/config"
+	ojocronlisteners "github.com/devzatruk/bizhubBackend/config/ojocron_listeners"
 	"github.com/devzatruk/bizhubBackend/helpers"
 	"github.com/devzatruk/bizhubBackend/models"
+	notificationmanager "github.com/devzatruk/bizhubBackend/notification_manager"
+	"github.com/devzatruk/bizhubBackend/ojologger"
 
 	// taskmanager "github.com/devzatruk/bizhubBackend/task_manager"
 	transactionmanager "github.com/devzatruk/bizhubBackend/transaction_manager"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/net/context"
 )
 
+var auctionsLogger = ojologger.LoggerService.Logger("Admin Auctions")
+
 func GetAuctions(c *fiber.Ctx) error {
 	errRes := helpers.ErrorResponse("Admin.GetAuctions")
 
@@ -107,6 +115,14 @@ func CreateNewAuction(c *fiber.Ctx) error {
 	if err != nil {
 		return c.JSON(errRes("StringToDate(finished_at)", err, config.BODY_NOT_PROVIDED))
 	}
+	payload.AntiSniping, err = antiSnipingFromForm(c)
+	if err != nil {
+		return c.JSON(errRes("antiSnipingFromForm()", err, config.BODY_NOT_PROVIDED))
+	}
+	payload.Rules, err = auctionRulesFromForm(c)
+	if err != nil {
+		return c.JSON(errRes("auctionRulesFromForm()", err, config.BODY_NOT_PROVIDED))
+	}
 	payload.Winners = make([]models.AuctionDetailNewWinner, 0)
 	payload.FinishedAt = finishedAt
 	payload.CreatedAt = time.Now()
@@ -145,6 +161,68 @@ func CreateNewAuction(c *fiber.Ctx) error {
 		Result:    config.CREATED,
 	})
 }
+
+// anti_sniping_within, anti_sniping_extend_by, anti_sniping_max_extension (minutlar) hokman dal,
+// berilmese anti-sniping isjen bolmaz.
+func antiSnipingFromForm(c *fiber.Ctx) (models.AuctionAntiSniping, error) {
+	var rule models.AuctionAntiSniping
+	fields := []struct {
+		key   string
+		value *int64
+	}{
+		{"anti_sniping_within", &rule.Within},
+		{"anti_sniping_extend_by", &rule.ExtendBy},
+		{"anti_sniping_max_extension", &rule.MaxExtension},
+	}
+	for _, field := range fields {
+		value, err := strconv.ParseInt(c.FormValue(field.key, "0"), 10, 64)
+		if err != nil {
+			return rule, fmt.Errorf("%v: %v", field.key, err)
+		}
+		if value < 0 {
+			return rule, fmt.Errorf("%v: negative value not allowed", field.key)
+		}
+		*field.value = value
+	}
+	return rule, nil
+}
+
+// increment_type (absolute|percent), increment, reserve_price, max_active_per_seller,
+// seller_types (regular,manufacturer) hokman dal.
+func auctionRulesFromForm(c *fiber.Ctx) (models.AuctionRules, error) {
+	rules := models.AuctionRules{
+		IncrementType: c.FormValue("increment_type", models.AuctionIncrementAbsolute),
+		SellerTypes:   make([]string, 0),
+	}
+	if rules.IncrementType != models.AuctionIncrementAbsolute && rules.IncrementType != models.AuctionIncrementPercent {
+		return rules, fmt.Errorf("increment_type: must be %v or %v", models.AuctionIncrementAbsolute, models.AuctionIncrementPercent)
+	}
+	var err error
+	rules.Increment, err = strconv.ParseFloat(c.FormValue("increment", "0"), 64)
+	if err != nil || rules.Increment < 0 {
+		return rules, fmt.Errorf("increment: invalid value %q", c.FormValue("increment"))
+	}
+	rules.ReservePrice, err = strconv.ParseFloat(c.FormValue("reserve_price", "0"), 64)
+	if err != nil || rules.ReservePrice < 0 {
+		return rules, fmt.Errorf("reserve_price: invalid value %q", c.FormValue("reserve_price"))
+	}
+	rules.MaxActivePerSeller, err = strconv.ParseInt(c.FormValue("max_active_per_seller", "0"), 10, 64)
+	if err != nil || rules.MaxActivePerSeller < 0 {
+		return rules, fmt.Errorf("max_active_per_seller: invalid value %q", c.FormValue("max_active_per_seller"))
+	}
+	for _, sellerType := range strings.Split(c.FormValue("seller_types"), ",") {
+		sellerType = strings.TrimSpace(sellerType)
+		if len(sellerType) == 0 {
+			continue
+		}
+		if !helpers.SliceContains(config.AUCTION_SELLER_TYPES, sellerType) {
+			return rules, fmt.Errorf("seller_types: %v not allowed", sellerType)
+		}
+		rules.SellerTypes = append(rules.SellerTypes, sellerType)
+	}
+	return rules, nil
+}
+
 func GetAuctionDetail(c *fiber.Ctx) error {
 	errRes := helpers.ErrorResponse("Admin.GetAuctionDetail")
 	auctionId, err := primitive.ObjectIDFromHex(c.Params("id"))
@@ -263,3 +341,374 @@ func GetAuctionDetail(c *fiber.Ctx) error {
 		Result:    auctionResult,
 	})
 }
+
+// auction-yn ahli bid synanysyklary, ?result= we ?seller_id= bilen filter edip bolyar.
+func GetAuctionBids(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.GetAuctionBids")
+	auctionObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	match := bson.M{
+		"auction_id": auctionObjId,
+	}
+	if result := c.Query("result"); len(result) > 0 {
+		match["result"] = result
+	}
+	if sellerId := c.Query("seller_id"); len(sellerId) > 0 {
+		sellerObjId, err := primitive.ObjectIDFromHex(sellerId)
+		if err != nil {
+			return c.JSON(errRes("Query(seller_id)", err, config.QUERY_NOT_PROVIDED))
+		}
+		match["seller_id"] = sellerObjId
+	}
+	return getAuctionBids(c, errRes, match)
+}
+
+// saklanan pulun gaytarylysy: outbid ya-da refunded bolan bid-ler.
+func GetAuctionRefunds(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.GetAuctionRefunds")
+	auctionObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	return getAuctionBids(c, errRes, bson.M{
+		"auction_id":  auctionObjId,
+		"refunded_at": bson.M{"$ne": nil},
+	})
+}
+
+func getAuctionBids(c *fiber.Ctx, errRes helpers.ResponseFunc, match bson.M) error {
+	pageIndex, err := strconv.Atoi(c.Query("page", "0"))
+	if err != nil {
+		return c.JSON(errRes("Query(page)", err, config.QUERY_NOT_PROVIDED))
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil {
+		return c.JSON(errRes("Query(limit)", err, config.QUERY_NOT_PROVIDED))
+	}
+	bidsColl := config.MI.DB.Collection(config.AUCTION_BIDS)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cursor, err := bidsColl.Aggregate(ctx, bson.A{
+		bson.M{
+			"$match": match,
+		},
+		bson.M{
+			"$sort": bson.M{
+				"created_at": -1,
+			},
+		},
+		bson.M{
+			"$skip": pageIndex * limit,
+		},
+		bson.M{
+			"$limit": limit,
+		},
+		bson.M{
+			"$lookup": bson.M{
+				"from":         config.SELLERS,
+				"localField":   "seller_id",
+				"foreignField": "_id",
+				"as":           "seller",
+				"pipeline": bson.A{
+					bson.M{
+						"$project": bson.M{
+							"_id":  1,
+							"name": 1,
+							"logo": 1,
+							"type": 1,
+						},
+					},
+				},
+			},
+		},
+		bson.M{
+			"$unwind": bson.M{
+				"path":                       "$seller",
+				"preserveNullAndEmptyArrays": true,
+			},
+		},
+	})
+	if err != nil {
+		return c.JSON(errRes("Aggregate()", err, config.DBQUERY_ERROR))
+	}
+	defer cursor.Close(ctx)
+	bids := make([]models.AuctionBidWithSeller, 0)
+	for cursor.Next(ctx) {
+		var bid models.AuctionBidWithSeller
+		err := cursor.Decode(&bid)
+		if err != nil {
+			return c.JSON(errRes("Decode()", err, config.CANT_DECODE))
+		}
+		bids = append(bids, bid)
+	}
+	if err = cursor.Err(); err != nil {
+		return c.JSON(errRes("cursor.Err()", err, config.DBQUERY_ERROR))
+	}
+	return c.JSON(models.Response[[]models.AuctionBidWithSeller]{
+		IsSuccess: true,
+		Result:    bids,
+	})
+}
+
+// auction baslamanka uytgedip bolyar. Dine berlen field-ler tazelenyar; anti_sniping_* ya-da
+// rules field-lerinden biri berilse, sol topar dolulygyna tazeden okalyar.
+func UpdateAuction(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.UpdateAuction")
+	auctionObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	update := bson.M{}
+	if heading := c.FormValue("heading"); len(heading) > 0 {
+		update["heading.en"] = heading
+	}
+	if description := c.FormValue("description"); len(description) > 0 {
+		update["description.en"] = description
+	}
+	if textColor := c.FormValue("text_color"); len(textColor) > 0 {
+		update["text_color"] = textColor
+	}
+	if value := c.FormValue("participants"); len(value) > 0 {
+		participants, err := strconv.Atoi(value)
+		if err != nil || participants <= 0 {
+			return c.JSON(errRes("Atoi(participants)", fmt.Errorf("invalid participants: %q", value), config.BODY_NOT_PROVIDED))
+		}
+		update["participants"] = participants
+	}
+	if value := c.FormValue("initial_minimal_bid"); len(value) > 0 {
+		initialMinimalBid, err := strconv.Atoi(value)
+		if err != nil || initialMinimalBid < 0 {
+			return c.JSON(errRes("Atoi(initial_minimal_bid)", fmt.Errorf("invalid initial_minimal_bid: %q", value), config.BODY_NOT_PROVIDED))
+		}
+		update["initial_minimal_bid"] = initialMinimalBid
+	}
+	if hasAnyFormValue(c, "anti_sniping_within", "anti_sniping_extend_by", "anti_sniping_max_extension") {
+		antiSniping, err := antiSnipingFromForm(c)
+		if err != nil {
+			return c.JSON(errRes("antiSnipingFromForm()", err, config.BODY_NOT_PROVIDED))
+		}
+		update["anti_sniping"] = antiSniping
+	}
+	if hasAnyFormValue(c, "increment_type", "increment", "reserve_price", "max_active_per_seller", "seller_types") {
+		rules, err := auctionRulesFromForm(c)
+		if err != nil {
+			return c.JSON(errRes("auctionRulesFromForm()", err, config.BODY_NOT_PROVIDED))
+		}
+		update["rules"] = rules
+	}
+	var newImage string
+	if _, err := c.FormFile("image"); err == nil {
+		newImage, err = helpers.SaveImageFile(c, "image", config.FOLDER_AUCTIONS)
+		if err != nil {
+			return c.JSON(errRes("SaveImageFile()", err, config.BODY_NOT_PROVIDED))
+		}
+		update["image"] = newImage
+	}
+	if len(update) == 0 {
+		return c.JSON(errRes("NoFields", errors.New("Nothing to update."), config.BODY_NOT_PROVIDED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	auctionsColl := config.MI.DB.Collection(config.AUCTIONS)
+	var oldAuction models.NewAuction
+	err = auctionsColl.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":         auctionObjId,
+			"is_finished": false,
+			"started_at":  bson.M{"$gt": time.Now()},
+		},
+		bson.M{"$set": update}).Decode(&oldAuction)
+	if err != nil {
+		if len(newImage) > 0 {
+			helpers.DeleteImageFile(newImage)
+		}
+		if err == mongo.ErrNoDocuments {
+			return c.JSON(errRes("FindOneAndUpdate()", errors.New("Auction not found or already started."), config.NOT_ALLOWED))
+		}
+		return c.JSON(errRes("FindOneAndUpdate()", err, config.CANT_UPDATE))
+	}
+	if len(newImage) > 0 && len(oldAuction.Image) > 0 {
+		helpers.DeleteImageFile(oldAuction.Image)
+	}
+	return c.JSON(models.Response[string]{
+		IsSuccess: true,
+		Result:    config.UPDATED,
+	})
+}
+
+func hasAnyFormValue(c *fiber.Ctx, keys ...string) bool {
+	for _, key := range keys {
+		if len(c.FormValue(key)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// started_at-y dine auction baslamanka uytgedip bolyar, finished_at-y bolsa gutarmanka.
+// auction_finished we auction_removed job-lary hem taze wagta gecirilyar.
+func RescheduleAuction(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.RescheduleAuction")
+	auctionObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	auctionsColl := config.MI.DB.Collection(config.AUCTIONS)
+	var auction models.BidAuctionFind
+	err = auctionsColl.FindOne(ctx, bson.M{
+		"_id":         auctionObjId,
+		"is_finished": false,
+	}).Decode(&auction)
+	if err != nil {
+		return c.JSON(errRes("FindOne(auction)", err, config.NOT_FOUND))
+	}
+	now := time.Now()
+	startedAt, finishedAt := auction.StartedAt, auction.FinishedAt
+	if value := c.FormValue("started_at"); len(value) > 0 {
+		if !auction.StartedAt.After(now) {
+			return c.JSON(errRes("StartedAt", errors.New("Auction already started."), config.NOT_ALLOWED))
+		}
+		startedAt, err = helpers.StringToDate(value)
+		if err != nil {
+			return c.JSON(errRes("StringToDate(started_at)", err, config.BODY_NOT_PROVIDED))
+		}
+	}
+	if value := c.FormValue("finished_at"); len(value) > 0 {
+		finishedAt, err = helpers.StringToDate(value)
+		if err != nil {
+			return c.JSON(errRes("StringToDate(finished_at)", err, config.BODY_NOT_PROVIDED))
+		}
+	}
+	if !finishedAt.After(startedAt) || !finishedAt.After(now) {
+		return c.JSON(errRes("FinishedAt", errors.New("finished_at must be after started_at and now."), config.NOT_ALLOWED))
+	}
+	// finished_at filter-de: anti-sniping bilen arada uzaldylan bolsa uytgetmeyas.
+	updateResult, err := auctionsColl.UpdateOne(ctx,
+		bson.M{
+			"_id":         auctionObjId,
+			"is_finished": false,
+			"finished_at": auction.FinishedAt,
+		},
+		bson.M{
+			"$set": bson.M{
+				"started_at":  startedAt,
+				"finished_at": finishedAt,
+			},
+		})
+	if err != nil {
+		return c.JSON(errRes("UpdateOne(auction)", err, config.CANT_UPDATE))
+	}
+	if updateResult.MatchedCount == 0 {
+		return c.JSON(errRes("UpdateOne(auction)", errors.New("Auction changed, please try again."), config.CANT_UPDATE))
+	}
+	err = config.OjoCronService.RescheduleJobsByGroup(auctionObjId, config.AUCTION_FINISHED, finishedAt)
+	if err != nil {
+		return c.JSON(errRes("RescheduleJobsByGroup(auction_finished)", err, config.CANT_UPDATE))
+	}
+	err = config.OjoCronService.RescheduleJobsByGroup(auctionObjId, config.AUCTION_REMOVED, finishedAt.Add(config.AUCTION_REMOVE_AFTER))
+	if err != nil {
+		return c.JSON(errRes("RescheduleJobsByGroup(auction_removed)", err, config.CANT_UPDATE))
+	}
+	if !finishedAt.Equal(auction.FinishedAt) {
+		config.AuctionService.Realtime.FinishedAtChanged(auctionservice.AuctionFinishedAtChanged{
+			AuctionId:  auctionObjId,
+			FinishedAt: finishedAt,
+		})
+	}
+	return c.JSON(models.Response[fiber.Map]{
+		IsSuccess: true,
+		Result: fiber.Map{
+			"started_at":  startedAt,
+			"finished_at": finishedAt,
+		},
+	})
+}
+
+// auction yatyrylyar: ahli in_auction saklanan pullar gaytarylyar (wallet_history-a refund),
+// job-lar pozulyar we seller-lere habar berilyar.
+func CancelAuction(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.CancelAuction")
+	auctionObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	auctionsColl := config.MI.DB.Collection(config.AUCTIONS)
+	now := time.Now()
+	// is_finished:true bolanson taze bid kabul edilmeyar, winners[] hem indi uytgemeyar.
+	var auction models.NewAuction
+	err = auctionsColl.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":         auctionObjId,
+			"is_finished": false,
+		},
+		bson.M{
+			"$set": bson.M{
+				"is_finished":  true,
+				"status":       config.STATUS_CANCELLED,
+				"cancelled_at": now,
+			},
+		}).Decode(&auction)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.JSON(errRes("FindOneAndUpdate()", errors.New("Auction not found or already finished."), config.NOT_FOUND))
+		}
+		return c.JSON(errRes("FindOneAndUpdate()", err, config.CANT_UPDATE))
+	}
+	// rollback: transaction_manager auction-y bilenok, ony ozumiz yzyna gaytaryas.
+	restoreAuction := func(err error) error {
+		_, restoreErr := auctionsColl.UpdateOne(ctx,
+			bson.M{"_id": auctionObjId},
+			bson.M{
+				"$set":   bson.M{"is_finished": false, "status": auction.Status},
+				"$unset": bson.M{"cancelled_at": ""},
+			})
+		if restoreErr != nil {
+			err = fmt.Errorf("%v - Restore: %v", err.Error(), restoreErr.Error())
+		}
+		return err
+	}
+	refundNote := notificationmanager.AuctionTranslation("AuctionCancelledRefund", auction.Heading)
+	tr_manager := transactionmanager.NewTransaction(&ctx, config.MI.DB, 3)
+	err = ojocronlisteners.ReleaseAuctionHolds(ctx, tr_manager, auctionObjId, auction.Heading, auction.Winners, refundNote)
+	if err != nil {
+		trErr := tr_manager.Rollback()
+		if trErr != nil {
+			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+		}
+		return c.JSON(errRes("ReleaseAuctionHolds()", restoreAuction(err), config.CANT_UPDATE))
+	}
+	err = config.OjoCronService.RemoveJobsByGroup(auctionObjId)
+	if err != nil {
+		// holds eyyam gaytaryldy, job-lar auction-y tapmasa hic zat etmez.
+		auctionsLogger.Group("CancelAuction()").Errorf("RemoveJobsByGroup(): %v", err)
+	}
+	note := refundNote.En
+	sellerIds := make([]primitive.ObjectID, 0, len(auction.Winners))
+	for _, winner := range auction.Winners {
+		sellerIds = append(sellerIds, winner.SellerId)
+		err = config.AuctionService.Bids.MarkRefunded(ctx, auctionObjId, winner.SellerId, winner.LastBid, &note)
+		if err != nil {
+			auctionsLogger.Group("CancelAuction()").Errorf("Bids.MarkRefunded(): %v", err)
+		}
+	}
+	config.AuctionService.Realtime.Cancelled(auctionservice.AuctionCancelled{
+		AuctionId: auctionObjId,
+	})
+	err = config.NotificationManager.NotifyAuctionSellers(ctx, auctionObjId, sellerIds, "AuctionCancelledTitle", refundNote)
+	if err != nil {
+		auctionsLogger.Group("CancelAuction()").Errorf("NotifyAuctionSellers(): %v", err)
+	}
+	return c.JSON(models.Response[fiber.Map]{
+		IsSuccess: true,
+		Result: fiber.Map{
+			"status":   config.STATUS_CANCELLED,
+			"refunded": len(sellerIds),
+		},
+	})
+}
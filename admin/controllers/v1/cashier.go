@@ -7,14 +7,17 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/devzatruk/bizhubBackend/codeservice"
 	"github.com/devzatruk/bizhubBackend/config"
 	"github.com/devzatruk/bizhubBackend/helpers"
+	"github.com/devzatruk/bizhubBackend/ledger"
 	"github.com/devzatruk/bizhubBackend/models"
 	ojoTr "github.com/devzatruk/bizhubBackend/transaction_manager"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type TransactionWithCode struct {
@@ -45,24 +48,88 @@ func Withdraw(c *fiber.Ctx) error {
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	shift, err := findOpenShift(ctx, employeeObjId)
+	if err != nil {
+		return c.JSON(errRes("findOpenShift()", err, config.NOT_ALLOWED))
+	}
 	// cashier withdraw etjek bolup durka, seller cancel eden bolsa, abort etmeli!
 	walletHistoryColl := config.MI.DB.Collection(config.WALLETHISTORY)
-	findResult := walletHistoryColl.FindOne(ctx, bson.M{
-		"_id":          payload.TransactionId,
-		"status":       config.STATUS_WAITING,
-		"completed_at": nil,
-	})
-	if err := findResult.Err(); err != nil {
+	var transaction models.MyWalletHistory
+	err = walletHistoryColl.FindOne(ctx, bson.M{
+		"_id":    payload.TransactionId,
+		"intent": config.INTENT_WITHDRAW,
+	}).Decode(&transaction)
+	if err != nil {
 		return c.JSON(errRes("FindOne(transaction)", err, config.NOT_FOUND))
 	}
 	now := time.Now()
-	// y, m, d := now.Date()
-	// today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	// expired request tolenmeyar: pul eyyam balance-a gaytaryldy. job gijikse hem wagty gecen bolsa.
+	expiresAt := transaction.CreatedAt.Add(config.WithdrawExpiresAfter())
+	if transaction.Status == config.STATUS_EXPIRED || (transaction.Status == config.STATUS_WAITING && !now.Before(expiresAt)) {
+		return c.JSON(errRes("WithdrawExpired", errors.New("Withdraw request expired."), config.NOT_ALLOWED))
+	}
+	if transaction.Status != config.STATUS_WAITING || transaction.CompletedAt != nil {
+		return c.JSON(errRes("FindOne(transaction)", errors.New("Withdraw request is not waiting."), config.NOT_FOUND))
+	}
+	err = config.CodeService.EnsureVerified(ctx, payload.TransactionId)
+	if err != nil {
+		return c.JSON(errRes("CodeService.EnsureVerified()", err, config.NOT_ALLOWED))
+	}
+	// threshold-dan uly bolsa pul manager tassyklanda berilyar.
+	if needsCashierApproval(transaction.Amount) {
+		_, err = requestCashierApproval(ctx, config.INTENT_WITHDRAW, transaction.SellerId, transaction.Amount,
+			&payload.TransactionId, employeeObjId, shift.Id)
+		if err != nil {
+			return cashierErrorResponse(c, errRes, err)
+		}
+		return c.JSON(models.Response[string]{
+			IsSuccess: true,
+			Result:    config.STATUS_PENDING_APPROVAL,
+		})
+	}
+	_, err = completeWithdraw(ctx, employeeObjId, shift.Id, payload.TransactionId, nil)
+	if err != nil {
+		return cashierErrorResponse(c, errRes, err)
+	}
+	return c.JSON(models.Response[string]{
+		IsSuccess: true,
+		Result:    config.TRANSACTION_SUCCESSFUL,
+	})
+}
 
+// wallet_history withdraw request-i completed edyar. approval nil dal bolsa request
+// pending_approval-dadyr we cashier_works-a tassyklan manager hem yazylyar.
+func completeWithdraw(ctx context.Context, employeeObjId primitive.ObjectID, shiftId primitive.ObjectID,
+	transactionId primitive.ObjectID, approval *models.CashierApproval) (*primitive.ObjectID, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id":        transactionId,
+		"status":     config.STATUS_WAITING,
+		"created_at": bson.M{"$gt": now.Add(-config.WithdrawExpiresAfter())},
+	}
+	rollbackStatus := config.STATUS_WAITING
+	if approval != nil {
+		// tassyklanmaga garasylan wagt withdraw expired bolmayar.
+		filter = bson.M{
+			"_id":    transactionId,
+			"status": config.STATUS_PENDING_APPROVAL,
+		}
+		rollbackStatus = config.STATUS_PENDING_APPROVAL
+	}
+	// status uytgemezden on ledger wallet-i acylyar, yogsa opening_balance-da withdraw_pending yetmez.
+	var pending models.MyWalletHistory
+	err := config.MI.DB.Collection(config.WALLETHISTORY).FindOne(ctx, filter).Decode(&pending)
+	if err != nil {
+		return nil, &cashierError{"FindOne(wallet_history)", err, config.NOT_FOUND}
+	}
+	err = config.Ledger.OpenWallets(ctx, pending.SellerId)
+	if err != nil {
+		return nil, &cashierError{"Ledger.OpenWallets()", err, config.CANT_UPDATE}
+	}
 	transaction_manager := ojoTr.NewTransaction(&ctx, config.MI.DB, 3)
 	tr_walletHisColl := transaction_manager.Collection(config.WALLETHISTORY)
 	update_model := ojoTr.NewModel().
-		SetFilter(bson.M{"_id": payload.TransactionId}).
+		SetFilter(filter).
 		SetUpdate(bson.M{
 			"$set": bson.M{
 				"status":       config.STATUS_COMPLETED,
@@ -72,40 +139,39 @@ func Withdraw(c *fiber.Ctx) error {
 		}).
 		SetRollbackUpdate(bson.M{
 			"$set": bson.M{
-				"status":       config.STATUS_WAITING,
+				"status":       rollbackStatus,
 				"employee_id":  nil,
 				"completed_at": nil,
 			},
-		})
-	walletHis_updateResult, err := tr_walletHisColl.FindOneAndUpdate(update_model)
-	if err != nil {
-		trErr := transaction_manager.Rollback()
-		if trErr != nil {
-			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
-		}
-		return c.JSON(errRes("FindOneAndUpdate(wallet_history)", err, config.CANT_UPDATE))
-	}
-	var old_walletHistory models.MyWalletHistory
-	err = walletHis_updateResult.Decode(&old_walletHistory)
+		}).
+		// status completed bolanson filter gabat gelmeyar, rollback dine _id bilen.
+		SetRollbackFilter(bson.M{"_id": transactionId})
+	// FindOneAndUpdate rollback update-i ulanmayar, sonun ucin UpdateOne.
+	_, err = tr_walletHisColl.UpdateOne(update_model)
 	if err != nil {
 		trErr := transaction_manager.Rollback()
 		if trErr != nil {
 			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
 		}
-		return c.JSON(errRes("Decode(wallet_history)", err, config.CANT_DECODE))
+		return nil, &cashierError{"UpdateOne(wallet_history)", err, config.CANT_UPDATE}
 	}
+	old_walletHistory := pending
 	// cashier_works.Add(completed_task)
 	completedTaskId := primitive.NewObjectID()
-	taskCode := old_walletHistory.Code
 	completedTask := models.CashierWork{
 		Id:         completedTaskId,
 		EmployeeId: employeeObjId,
 		SellerId:   old_walletHistory.SellerId,
 		Intent:     old_walletHistory.Intent,
 		Amount:     old_walletHistory.Amount,
-		Code:       taskCode,
+		Code:       nil,
+		ShiftId:    shiftId,
 		CreatedAt:  now,
 	}
+	if approval != nil {
+		completedTask.ApprovalId = &approval.Id
+		completedTask.ApprovedBy = approval.CheckerId
+	}
 	tr_cashierWorksColl := transaction_manager.Collection(config.CASHIERWORKS)
 	insert_model := ojoTr.NewModel().SetDocument(completedTask)
 	cashierWork_insertResult, err := tr_cashierWorksColl.InsertOne(insert_model)
@@ -114,16 +180,37 @@ func Withdraw(c *fiber.Ctx) error {
 		if trErr != nil {
 			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
 		}
-		return c.JSON(errRes("InsertOne(cashier_work)", err, config.CANT_INSERT))
+		return nil, &cashierError{"InsertOne(cashier_work)", err, config.CANT_INSERT}
 	}
-	employeeManager := config.EverydayWorkService.Of(employeeObjId)
-	employeeManager.CashierActivity(cashierWork_insertResult.InsertedID.(primitive.ObjectID))
-	config.OjoCronService.RemoveJobsByGroup(payload.TransactionId)
-
-	return c.JSON(models.Response[string]{
-		IsSuccess: true,
-		Result:    config.TRANSACTION_SUCCESSFUL,
-	})
+	err = addShiftWork(ctx, shiftId, config.INTENT_WITHDRAW, old_walletHistory.Amount)
+	if err != nil {
+		code := config.CANT_UPDATE
+		if err == errNoOpenShift {
+			code = config.NOT_ALLOWED
+		}
+		trErr := transaction_manager.Rollback()
+		if trErr != nil {
+			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+		}
+		return nil, &cashierError{"addShiftWork()", err, code}
+	}
+	// pul withdraw_pending-den kassadan cykdy.
+	_, err = config.Ledger.Post(ctx, ledger.WithdrawPayout(old_walletHistory.SellerId, old_walletHistory.Amount).
+		WithRef(config.WALLETHISTORY, transactionId))
+	if err != nil {
+		if shiftErr := removeShiftWork(ctx, shiftId, config.INTENT_WITHDRAW, old_walletHistory.Amount); shiftErr != nil {
+			err = fmt.Errorf("Source: %v - Shift: %v", err.Error(), shiftErr.Error())
+		}
+		trErr := transaction_manager.Rollback()
+		if trErr != nil {
+			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+		}
+		return nil, &cashierError{"Ledger.Post(withdraw_payout)", err, config.CANT_UPDATE}
+	}
+	cashierWorkId := cashierWork_insertResult.InsertedID.(primitive.ObjectID)
+	recordCashierActivity(employeeObjId, approval, cashierWorkId)
+	config.OjoCronService.RemoveJobsByGroup(transactionId)
+	return &cashierWorkId, nil
 }
 func Deposit(c *fiber.Ctx) error {
 	errRes := helpers.ErrorResponse("Admin.Deposit")
@@ -156,49 +243,53 @@ func Deposit(c *fiber.Ctx) error {
 	// }
 	// payload.Amount = amount
 	// end of test
-	if payload.Amount < 0 {
-		return c.JSON(errRes("NegativeAmount", errors.New("Negative amount not allowed."), config.NOT_ALLOWED))
+	if payload.Amount <= 0 {
+		return c.JSON(errRes("NegativeAmount", errors.New("Amount must be positive."), config.NOT_ALLOWED))
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	transaction_manager := ojoTr.NewTransaction(&ctx, config.MI.DB, 3)
-	walletsCollTrans := transaction_manager.Collection(config.WALLETS)
-	update_model := ojoTr.NewModel().
-		SetFilter(bson.M{"seller_id": payload.SellerId}).
-		SetUpdate(bson.M{
-			"$inc": bson.M{
-				"balance": payload.Amount,
-			},
-		}).
-		SetRollbackUpdate(bson.M{
-			"$inc": bson.M{
-				"balance": -payload.Amount,
-			},
-		})
-	walletUpdateResult, err := walletsCollTrans.FindOneAndUpdate(update_model)
+	shift, err := findOpenShift(ctx, employeeObjId)
 	if err != nil {
-		trErr := transaction_manager.Rollback()
-		if trErr != nil {
-			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+		return c.JSON(errRes("findOpenShift()", err, config.NOT_ALLOWED))
+	}
+	if needsCashierApproval(payload.Amount) {
+		_, err = requestCashierApproval(ctx, config.INTENT_DEPOSIT, payload.SellerId, payload.Amount,
+			nil, employeeObjId, shift.Id)
+		if err != nil {
+			return cashierErrorResponse(c, errRes, err)
 		}
-		return c.JSON(errRes("UpdateOne(wallets)", err, config.CANT_UPDATE))
+		return c.JSON(models.Response[string]{
+			IsSuccess: true,
+			Result:    config.STATUS_PENDING_APPROVAL,
+		})
+	}
+	_, err = completeDeposit(ctx, employeeObjId, shift.Id, payload.SellerId, payload.Amount, nil)
+	if err != nil {
+		return cashierErrorResponse(c, errRes, err)
 	}
+	return c.JSON(models.Response[string]{
+		IsSuccess: true,
+		Result:    config.TRANSACTION_SUCCESSFUL,
+	})
+}
+
+// seller-in wallet-ine pul salyar. approval nil dal bolsa cashier_works-a tassyklan manager hem yazylyar.
+func completeDeposit(ctx context.Context, employeeObjId primitive.ObjectID, shiftId primitive.ObjectID,
+	sellerId primitive.ObjectID, amount float64, approval *models.CashierApproval) (*primitive.ObjectID, error) {
+	walletsColl := config.MI.DB.Collection(config.WALLETS)
 	var walletBeforeUpdate models.SellerWallet
-	err = walletUpdateResult.Decode(&walletBeforeUpdate)
+	err := walletsColl.FindOne(ctx, bson.M{"seller_id": sellerId}).Decode(&walletBeforeUpdate)
 	if err != nil {
-		trErr := transaction_manager.Rollback()
-		if trErr != nil {
-			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
-		}
-		return c.JSON(errRes("FindOneAndUpdate(wallet)", err, config.CANT_UPDATE))
+		return nil, &cashierError{"FindOne(wallet)", err, config.NOT_FOUND}
 	}
+	transaction_manager := ojoTr.NewTransaction(&ctx, config.MI.DB, 3)
 	whistoriesColl := transaction_manager.Collection(config.WALLETHISTORY)
 	now := time.Now()
 	wh := models.MyWalletHistory{
-		SellerId:    payload.SellerId,
+		SellerId:    sellerId,
 		WalletId:    walletBeforeUpdate.Id,
 		OldBalance:  walletBeforeUpdate.Balance,
-		Amount:      payload.Amount,
+		Amount:      amount,
 		Intent:      config.INTENT_DEPOSIT,
 		Note:        nil,
 		Code:        nil,
@@ -214,12 +305,12 @@ func Deposit(c *fiber.Ctx) error {
 		if trErr != nil {
 			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
 		}
-		return c.JSON(errRes("InsertOne(wallet_history)", err, config.CANT_INSERT))
+		return nil, &cashierError{"InsertOne(wallet_history)", err, config.CANT_INSERT}
 	}
 	// seller.transfers[].push(wallet_history_id) etmeli
 	new_wh_transfer := wh_insertResult.InsertedID.(primitive.ObjectID)
 	tr_sellersColl := transaction_manager.Collection(config.SELLERS)
-	update_model = ojoTr.NewModel().
+	update_model := ojoTr.NewModel().
 		SetFilter(bson.M{"_id": wh.SellerId}).
 		SetUpdate(bson.M{
 			"$push": bson.M{
@@ -234,14 +325,15 @@ func Deposit(c *fiber.Ctx) error {
 			"$pull": bson.M{
 				"transfers": new_wh_transfer,
 			},
-		})
-	_, err = tr_sellersColl.FindOneAndUpdate(update_model)
+		}).
+		SetRollbackFilter(bson.M{"_id": wh.SellerId})
+	_, err = tr_sellersColl.UpdateOne(update_model)
 	if err != nil {
 		trErr := transaction_manager.Rollback()
 		if trErr != nil {
 			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
 		}
-		return c.JSON(errRes("FindOneAndUpdate(seller.transfers)", err, config.CANT_UPDATE))
+		return nil, &cashierError{"UpdateOne(seller.transfers)", err, config.CANT_UPDATE}
 	}
 	cashierActivityId := primitive.NewObjectID()
 	cashierDepositActivity := models.CashierWork{
@@ -249,10 +341,15 @@ func Deposit(c *fiber.Ctx) error {
 		EmployeeId: employeeObjId,
 		SellerId:   wh.SellerId,
 		Intent:     config.INTENT_DEPOSIT,
-		Amount:     payload.Amount,
+		Amount:     amount,
 		Code:       nil,
+		ShiftId:    shiftId,
 		CreatedAt:  now,
 	}
+	if approval != nil {
+		cashierDepositActivity.ApprovalId = &approval.Id
+		cashierDepositActivity.ApprovedBy = approval.CheckerId
+	}
 	insert_model = ojoTr.NewModel().SetDocument(cashierDepositActivity)
 	tr_cashierWorksColl := transaction_manager.Collection(config.CASHIERWORKS)
 	_, err = tr_cashierWorksColl.InsertOne(insert_model)
@@ -261,7 +358,7 @@ func Deposit(c *fiber.Ctx) error {
 		if trErr != nil {
 			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
 		}
-		return c.JSON(errRes("InsertOne(cashier_work)", err, config.CANT_INSERT))
+		return nil, &cashierError{"InsertOne(cashier_work)", err, config.CANT_INSERT}
 	}
 	if err = transaction_manager.Err(); err != nil && err != mongo.ErrNoDocuments {
 		fmt.Printf("\nInside transaction_manager.Err()\n")
@@ -269,15 +366,35 @@ func Deposit(c *fiber.Ctx) error {
 		if trErr != nil {
 			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
 		}
-		return c.JSON(errRes("Rollback()", err, config.TRANSACTION_FAILED))
+		return nil, &cashierError{"Rollback()", err, config.TRANSACTION_FAILED}
 	}
-	employeeManager := config.EverydayWorkService.Of(employeeObjId)
-	employeeManager.CashierActivity(cashierActivityId)
-
-	return c.JSON(models.Response[string]{
-		IsSuccess: true,
-		Result:    config.TRANSACTION_SUCCESSFUL,
-	})
+	err = addShiftWork(ctx, shiftId, config.INTENT_DEPOSIT, amount)
+	if err != nil {
+		code := config.CANT_UPDATE
+		if err == errNoOpenShift {
+			code = config.NOT_ALLOWED
+		}
+		trErr := transaction_manager.Rollback()
+		if trErr != nil {
+			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+		}
+		return nil, &cashierError{"addShiftWork()", err, code}
+	}
+	// balance ledger arkaly, in sonky adim.
+	_, err = config.Ledger.Post(ctx, ledger.Deposit(sellerId, amount).
+		WithRef(config.WALLETHISTORY, new_wh_transfer))
+	if err != nil {
+		if shiftErr := removeShiftWork(ctx, shiftId, config.INTENT_DEPOSIT, amount); shiftErr != nil {
+			err = fmt.Errorf("Source: %v - Shift: %v", err.Error(), shiftErr.Error())
+		}
+		trErr := transaction_manager.Rollback()
+		if trErr != nil {
+			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+		}
+		return nil, &cashierError{"Ledger.Post(deposit)", err, config.CANT_UPDATE}
+	}
+	recordCashierActivity(employeeObjId, approval, cashierActivityId)
+	return &cashierActivityId, nil
 }
 func Code(c *fiber.Ctx) error {
 	errRes := helpers.ErrorResponse("Admin.Code")
@@ -296,42 +413,37 @@ func Code(c *fiber.Ctx) error {
 	// payload.SellerId = sellerId
 	// payload.Code = c.Query("code")
 
-	aggregationArray := bson.A{
-		bson.M{
-			"$match": bson.M{
-				"seller_id": payload.SellerId,
-				"intent":    config.INTENT_WITHDRAW,
-				"code":      payload.Code,
-			},
-		},
-		bson.M{
-			"$project": bson.M{
-				"status":       1,
-				"completed_at": 1,
-				"amount":       1,
-			},
-		},
-	}
-	var walletHistory TransactionWithCode
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	walletHistoryColl := config.MI.DB.Collection(config.WALLETHISTORY)
-	cursor, err := walletHistoryColl.Aggregate(ctx, aggregationArray)
+	// code bir gezeklik: dogry bolsa su yerde ulanylyar, Withdraw() ony barlayar.
+	verifiedCode, err := config.CodeService.Verify(ctx, payload.SellerId, config.INTENT_WITHDRAW, payload.Code)
 	if err != nil {
-		return c.JSON(errRes("Aggregate()", err, config.DBQUERY_ERROR))
-	}
-	defer cursor.Close(ctx)
-	if cursor.Next(ctx) {
-		err = cursor.Decode(&walletHistory)
-		if err != nil {
-			return c.JSON(errRes("Decode()", err, config.CANT_DECODE))
+		switch err {
+		case codeservice.ErrCodeNotFound:
+			return c.JSON(errRes("NilObjectID", errors.New("Transaction not found."), config.NOT_FOUND))
+		case codeservice.ErrTooManyAttempts:
+			return c.JSON(errRes("CodeService.Verify()", err, config.TOO_MANY_REQUESTS))
+		case codeservice.ErrCodeExpired, codeservice.ErrCodeUsed:
+			return c.JSON(errRes("CodeService.Verify()", err, config.NOT_ALLOWED))
 		}
+		return c.JSON(errRes("CodeService.Verify()", err, config.DBQUERY_ERROR))
 	}
-	if err = cursor.Err(); err != nil {
-		return c.JSON(errRes("cursor.Err()", err, config.DBQUERY_ERROR))
-	}
-	if walletHistory.Id == primitive.NilObjectID {
-		return c.JSON(errRes("NilObjectID", errors.New("Transaction not found."), config.NOT_FOUND))
+	var walletHistory TransactionWithCode
+	walletHistoryColl := config.MI.DB.Collection(config.WALLETHISTORY)
+	err = walletHistoryColl.FindOne(ctx, bson.M{
+		"_id":       verifiedCode.WalletHistoryId,
+		"seller_id": payload.SellerId,
+		"intent":    config.INTENT_WITHDRAW,
+	}, options.FindOne().SetProjection(bson.M{
+		"status":       1,
+		"completed_at": 1,
+		"amount":       1,
+	})).Decode(&walletHistory)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.JSON(errRes("NilObjectID", errors.New("Transaction not found."), config.NOT_FOUND))
+		}
+		return c.JSON(errRes("FindOne(wallet_history)", err, config.DBQUERY_ERROR))
 	}
 	return c.JSON(models.Response[TransactionWithCode]{
 		IsSuccess: true,
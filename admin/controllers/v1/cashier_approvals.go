@@ -0,0 +1,336 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/cashierservice"
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/helpers"
+	"github.com/devzatruk/bizhubBackend/models"
+	"github.com/devzatruk/bizhubBackend/ojocronservice"
+	ojoTr "github.com/devzatruk/bizhubBackend/transaction_manager"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// completeDeposit(), completeWithdraw() we approval-lar yalnys bolsa haysy funksiyada
+// we haysy code bilen gaytarmalydygyny bilmek ucin.
+type cashierError struct {
+	Fn   string
+	Err  error
+	Code string
+}
+
+func (e *cashierError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Fn, e.Err)
+}
+
+func cashierErrorResponse(c *fiber.Ctx, errRes helpers.ResponseFunc, err error) error {
+	if cashierErr, ok := err.(*cashierError); ok {
+		return c.JSON(errRes(cashierErr.Fn, cashierErr.Err, cashierErr.Code))
+	}
+	return c.JSON(errRes("cashier", err, config.SERVER_ERROR))
+}
+
+func needsCashierApproval(amount float64) bool {
+	return amount > config.CashierApprovalThreshold()
+}
+
+// cashier (maker) we tassyklan manager (checker) ikisi hem EverydayWork-e yazylyar.
+func recordCashierActivity(employeeObjId primitive.ObjectID, approval *models.CashierApproval, cashierWorkId primitive.ObjectID) {
+	config.EverydayWorkService.Of(employeeObjId).CashierActivity(cashierWorkId)
+	if approval != nil && approval.CheckerId != nil {
+		config.EverydayWorkService.Of(*approval.CheckerId).CashierActivity(cashierWorkId)
+	}
+}
+
+// wallet-e degmeyar: deposit/withdraw manager tassyklanda completeDeposit()/completeWithdraw() bilen edilyar.
+// Withdraw request pending_approval bolyar, seller ony cancel edip bilmeyar we ol expired bolmayar.
+func requestCashierApproval(ctx context.Context, intent string, sellerId primitive.ObjectID, amount float64,
+	walletHistoryId *primitive.ObjectID, makerId primitive.ObjectID, shiftId primitive.ObjectID) (*models.CashierApproval, error) {
+	if intent == config.INTENT_DEPOSIT {
+		count, err := config.MI.DB.Collection(config.WALLETS).CountDocuments(ctx, bson.M{"seller_id": sellerId})
+		if err != nil {
+			return nil, &cashierError{"CountDocuments(wallet)", err, config.DBQUERY_ERROR}
+		}
+		if count == 0 {
+			return nil, &cashierError{"FindOne(wallet)", errors.New("Wallet not found."), config.NOT_FOUND}
+		}
+	}
+	now := time.Now()
+	approval := models.CashierApproval{
+		Id:              primitive.NewObjectID(),
+		Intent:          intent,
+		SellerId:        sellerId,
+		Amount:          amount,
+		WalletHistoryId: walletHistoryId,
+		MakerId:         makerId,
+		ShiftId:         shiftId,
+		Status:          config.STATUS_PENDING_APPROVAL,
+		ExpiresAt:       now.Add(config.CashierApprovalExpiresAfter()),
+		CreatedAt:       now,
+	}
+	transaction_manager := ojoTr.NewTransaction(&ctx, config.MI.DB, 3)
+	if walletHistoryId != nil {
+		tr_walletHisColl := transaction_manager.Collection(config.WALLETHISTORY)
+		_, err := tr_walletHisColl.UpdateOne(ojoTr.NewModel().
+			SetFilter(bson.M{
+				"_id":        *walletHistoryId,
+				"status":     config.STATUS_WAITING,
+				"created_at": bson.M{"$gt": now.Add(-config.WithdrawExpiresAfter())},
+			}).
+			SetUpdate(bson.M{
+				"$set": bson.M{"status": config.STATUS_PENDING_APPROVAL},
+			}).
+			SetRollbackUpdate(bson.M{
+				"$set": bson.M{"status": config.STATUS_WAITING},
+			}))
+		if err != nil {
+			trErr := transaction_manager.Rollback()
+			if trErr != nil {
+				err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+			}
+			return nil, &cashierError{"UpdateOne(wallet_history)", err, config.CANT_UPDATE}
+		}
+	}
+	tr_approvalsColl := transaction_manager.Collection(config.CASHIER_APPROVALS)
+	_, err := tr_approvalsColl.InsertOne(ojoTr.NewModel().SetDocument(approval))
+	if err != nil {
+		trErr := transaction_manager.Rollback()
+		if trErr != nil {
+			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+		}
+		return nil, &cashierError{"InsertOne(cashier_approval)", err, config.CANT_INSERT}
+	}
+	jobModel := ojocronservice.NewOjoCronJobModel()
+	jobModel.Group(approval.Id)
+	jobModel.ListenerName(config.CASHIER_APPROVAL_EXPIRED).Payload(map[string]interface{}{
+		"approval_id": approval.Id,
+	}).RunAt(approval.ExpiresAt)
+	err = config.OjoCronService.NewJob(jobModel)
+	if err != nil {
+		trErr := transaction_manager.Rollback()
+		if trErr != nil {
+			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+		}
+		return nil, &cashierError{"NewJob(cashier_approval_expired)", err, config.CANT_INSERT}
+	}
+	return &approval, nil
+}
+
+func findCashierApprovals(ctx context.Context, filter bson.M, skip int64, limit int64) ([]models.CashierApproval, error) {
+	cursor, err := config.MI.DB.Collection(config.CASHIER_APPROVALS).Find(ctx, filter, options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetSkip(skip).
+		SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	approvals := []models.CashierApproval{}
+	err = cursor.All(ctx, &approvals)
+	if err != nil {
+		return nil, err
+	}
+	return approvals, nil
+}
+
+// ?status=pending_approval&intent=withdraw: manager-ler ucin, default garasyanlar.
+func GetCashierApprovals(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.GetCashierApprovals")
+	pageIndex, err := strconv.Atoi(c.Query("page", "0"))
+	if err != nil {
+		return c.JSON(errRes("Query(page)", err, config.QUERY_NOT_PROVIDED))
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || limit <= 0 {
+		return c.JSON(errRes("Query(limit)", err, config.QUERY_NOT_PROVIDED))
+	}
+	filter := bson.M{
+		"status": c.Query("status", config.STATUS_PENDING_APPROVAL),
+	}
+	if intent := c.Query("intent"); intent != "" {
+		filter["intent"] = intent
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	approvals, err := findCashierApprovals(ctx, filter, int64(pageIndex*limit), int64(limit))
+	if err != nil {
+		return c.JSON(errRes("findCashierApprovals()", err, config.DBQUERY_ERROR))
+	}
+	return c.JSON(models.Response[[]models.CashierApproval]{
+		IsSuccess: true,
+		Result:    approvals,
+	})
+}
+
+// cashier-in oz approval-lary, ?status= bos bolsa hemmesi.
+func GetMyCashierApprovals(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.GetMyCashierApprovals")
+	var employeeObjId primitive.ObjectID
+	err := helpers.GetCurrentEmployee(c, &employeeObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentEmployee()", err, config.AUTH_REQUIRED))
+	}
+	pageIndex, err := strconv.Atoi(c.Query("page", "0"))
+	if err != nil {
+		return c.JSON(errRes("Query(page)", err, config.QUERY_NOT_PROVIDED))
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || limit <= 0 {
+		return c.JSON(errRes("Query(limit)", err, config.QUERY_NOT_PROVIDED))
+	}
+	filter := bson.M{"maker_id": employeeObjId}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	approvals, err := findCashierApprovals(ctx, filter, int64(pageIndex*limit), int64(limit))
+	if err != nil {
+		return c.JSON(errRes("findCashierApprovals()", err, config.DBQUERY_ERROR))
+	}
+	return c.JSON(models.Response[[]models.CashierApproval]{
+		IsSuccess: true,
+		Result:    approvals,
+	})
+}
+
+// manager tassyklayar: approval approved edilyar, son deposit/withdraw cashier-in adyndan edilyar.
+// Operasiya yalnys bolsa approval yzyna pending_approval bolyar.
+func ApproveCashierApproval(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.ApproveCashierApproval")
+	var checkerObjId primitive.ObjectID
+	err := helpers.GetCurrentEmployee(c, &checkerObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentEmployee()", err, config.AUTH_REQUIRED))
+	}
+	approvalObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	approvalsColl := config.MI.DB.Collection(config.CASHIER_APPROVALS)
+	var approval models.CashierApproval
+	err = approvalsColl.FindOne(ctx, bson.M{
+		"_id":    approvalObjId,
+		"status": config.STATUS_PENDING_APPROVAL,
+	}).Decode(&approval)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.JSON(errRes("FindOne(cashier_approval)", cashierservice.ErrApprovalNotPending, config.NOT_FOUND))
+		}
+		return c.JSON(errRes("FindOne(cashier_approval)", err, config.DBQUERY_ERROR))
+	}
+	if approval.MakerId == checkerObjId {
+		return c.JSON(errRes("SameEmployee", errors.New("Operation must be approved by another employee."), config.NOT_ALLOWED))
+	}
+	now := time.Now()
+	if !now.Before(approval.ExpiresAt) {
+		// job entek islemedik bolsa.
+		_, err = cashierservice.ReleaseCashierApproval(ctx, approval.Id, config.STATUS_EXPIRED, nil, nil)
+		if err != nil && err != cashierservice.ErrApprovalNotPending {
+			return c.JSON(errRes("ReleaseCashierApproval()", err, config.CANT_UPDATE))
+		}
+		config.OjoCronService.RemoveJobsByGroup(approval.Id)
+		return c.JSON(errRes("ApprovalExpired", errors.New("Cashier approval expired."), config.NOT_ALLOWED))
+	}
+	// filter-de status: parallel approve/reject/expire-den dine biri gecyar.
+	updateResult, err := approvalsColl.UpdateOne(ctx,
+		bson.M{
+			"_id":    approval.Id,
+			"status": config.STATUS_PENDING_APPROVAL,
+		},
+		bson.M{
+			"$set": bson.M{
+				"status":     config.STATUS_APPROVED,
+				"checker_id": checkerObjId,
+				"decided_at": now,
+			},
+		})
+	if err != nil {
+		return c.JSON(errRes("UpdateOne(cashier_approval)", err, config.CANT_UPDATE))
+	}
+	if updateResult.ModifiedCount == 0 {
+		return c.JSON(errRes("UpdateOne(cashier_approval)", cashierservice.ErrApprovalNotPending, config.NOT_FOUND))
+	}
+	approval.Status = config.STATUS_APPROVED
+	approval.CheckerId = &checkerObjId
+	approval.DecidedAt = &now
+	var cashierWorkId *primitive.ObjectID
+	if approval.Intent == config.INTENT_DEPOSIT {
+		cashierWorkId, err = completeDeposit(ctx, approval.MakerId, approval.ShiftId, approval.SellerId, approval.Amount, &approval)
+	} else {
+		cashierWorkId, err = completeWithdraw(ctx, approval.MakerId, approval.ShiftId, *approval.WalletHistoryId, &approval)
+	}
+	if err != nil {
+		_, revertErr := approvalsColl.UpdateOne(ctx, bson.M{"_id": approval.Id}, bson.M{
+			"$set": bson.M{
+				"status":     config.STATUS_PENDING_APPROVAL,
+				"checker_id": nil,
+				"decided_at": nil,
+			},
+		})
+		if revertErr != nil {
+			err = fmt.Errorf("Source: %v - Revert: %v", err.Error(), revertErr.Error())
+		}
+		return cashierErrorResponse(c, errRes, err)
+	}
+	approval.CashierWorkId = cashierWorkId
+	_, err = approvalsColl.UpdateOne(ctx, bson.M{"_id": approval.Id}, bson.M{
+		"$set": bson.M{"cashier_work_id": cashierWorkId},
+	})
+	if err != nil {
+		return c.JSON(errRes("UpdateOne(cashier_approval.cashier_work_id)", err, config.CANT_UPDATE))
+	}
+	config.OjoCronService.RemoveJobsByGroup(approval.Id)
+	return c.JSON(models.Response[models.CashierApproval]{
+		IsSuccess: true,
+		Result:    approval,
+	})
+}
+
+// body: {"reason": "..."}. Deposit-de wallet uytgemandi, withdraw request yzyna waiting bolyar.
+func RejectCashierApproval(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.RejectCashierApproval")
+	var checkerObjId primitive.ObjectID
+	err := helpers.GetCurrentEmployee(c, &checkerObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentEmployee()", err, config.AUTH_REQUIRED))
+	}
+	approvalObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	var payload struct {
+		Reason string `json:"reason"`
+	}
+	err = c.BodyParser(&payload)
+	if err != nil {
+		return c.JSON(errRes("BodyParser()", err, config.CANT_DECODE))
+	}
+	if payload.Reason == "" {
+		return c.JSON(errRes("NoReason", errors.New("Reason must be provided."), config.BODY_NOT_PROVIDED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	approval, err := cashierservice.ReleaseCashierApproval(ctx, approvalObjId, config.STATUS_REJECTED, &checkerObjId, &payload.Reason)
+	if err != nil {
+		if err == cashierservice.ErrApprovalNotPending {
+			return c.JSON(errRes("ReleaseCashierApproval()", err, config.NOT_FOUND))
+		}
+		return c.JSON(errRes("ReleaseCashierApproval()", err, config.CANT_UPDATE))
+	}
+	config.OjoCronService.RemoveJobsByGroup(approval.Id)
+	return c.JSON(models.Response[models.CashierApproval]{
+		IsSuccess: true,
+		Result:    *approval,
+	})
+}
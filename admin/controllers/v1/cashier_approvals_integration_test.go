@@ -0,0 +1,208 @@
+//go:build integration
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/ledger"
+	"github.com/devzatruk/bizhubBackend/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Manager tassyklanda withdraw payout ledger-e yazylyp bilinmese: wallet_history, smena we
+// approval yzyna gaytarylmaly, sonky approve bolsa adaty gecmeli. Test ucin ayratyn database
+// doredilyar we pozulyar. Transaction ucin mongo replica set gerek, .env okalmayar
+// (APP_ENV=production) we firebase file yoly env-den berilyar:
+//
+//	APP_ENV=production FIREBASE_CREDENTIALS=$PWD/config/firebase-config.json \
+//	MONGO_URI="mongodb://localhost:27017/?replicaSet=rs0" \
+//	go test -tags integration -run TestApproveWithdrawLedgerFailure ./admin/controllers/v1/
+
+func connectCashierTestDB(t *testing.T) {
+	uri := os.Getenv("MONGO_URI")
+	if len(uri) == 0 {
+		t.Skip("MONGO_URI not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("Connect(): %v", err)
+	}
+	db := client.Database(fmt.Sprintf("cashier_test_%v", primitive.NewObjectID().Hex()))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	config.MI = &config.MongoInstance{
+		Client: client,
+		DB:     db,
+	}
+	config.Ledger.Init(db)
+	config.OjoCronService.Init(db.Collection("ojocron_jobs"))
+	config.EverydayWorkService.Init(db.Collection(config.EMPLOYEES), db.Collection(config.EVERYDAYWORK))
+}
+
+func approveCashierApproval(t *testing.T, checkerId primitive.ObjectID, approvalId primitive.ObjectID) models.Response[any] {
+	app := fiber.New()
+	app.Post("/:id", func(c *fiber.Ctx) error {
+		c.Locals(config.CURRENT_EMPLOYEE, map[string]any{"_id": checkerId.Hex()})
+		return c.Next()
+	}, ApproveCashierApproval)
+	res, err := app.Test(httptest.NewRequest("POST", "/"+approvalId.Hex(), nil), -1)
+	if err != nil {
+		t.Fatalf("app.Test(): %v", err)
+	}
+	defer res.Body.Close()
+	var response models.Response[any]
+	if err = json.NewDecoder(res.Body).Decode(&response); err != nil {
+		t.Fatalf("Decode(response): %v", err)
+	}
+	return response
+}
+
+func TestApproveWithdrawLedgerFailure(t *testing.T) {
+	connectCashierTestDB(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	db := config.MI.DB
+	// withdraw_payout entry-leri kabul edilmeyar: Ledger.Post(WithdrawPayout) yalnys bolyar.
+	err := db.CreateCollection(ctx, ledger.CollEntries, options.CreateCollection().
+		SetValidator(bson.M{"type": bson.M{"$ne": ledger.EntryWithdrawPayout}}))
+	if err != nil {
+		t.Fatalf("CreateCollection(ledger_entries): %v", err)
+	}
+
+	now := time.Now()
+	amount := 500.0
+	sellerId := primitive.NewObjectID()
+	makerId := primitive.NewObjectID()
+	checkerId := primitive.NewObjectID()
+	shiftId := primitive.NewObjectID()
+	walletHistoryId := primitive.NewObjectID()
+	approvalId := primitive.NewObjectID()
+	_, err = db.Collection(config.WALLETS).InsertOne(ctx, models.SellerWallet{
+		SellerId:  sellerId,
+		Balance:   0,
+		CreatedAt: now,
+		Status:    config.SELLER_STATUS_PUBLISHED,
+		InAuction: make([]models.InAuctionObject, 0),
+	})
+	if err != nil {
+		t.Fatalf("InsertOne(wallet): %v", err)
+	}
+	_, err = db.Collection(config.WALLETHISTORY).InsertOne(ctx, bson.M{
+		"_id":        walletHistoryId,
+		"seller_id":  sellerId,
+		"amount":     amount,
+		"intent":     config.INTENT_WITHDRAW,
+		"status":     config.STATUS_PENDING_APPROVAL,
+		"created_at": now,
+	})
+	if err != nil {
+		t.Fatalf("InsertOne(wallet_history): %v", err)
+	}
+	_, err = db.Collection(config.CASHIER_SHIFTS).InsertOne(ctx, models.CashierShift{
+		Id:         shiftId,
+		EmployeeId: makerId,
+		Status:     config.STATUS_OPEN,
+		OpenedAt:   now,
+	})
+	if err != nil {
+		t.Fatalf("InsertOne(cashier_shift): %v", err)
+	}
+	_, err = db.Collection(config.CASHIER_APPROVALS).InsertOne(ctx, models.CashierApproval{
+		Id:              approvalId,
+		Intent:          config.INTENT_WITHDRAW,
+		SellerId:        sellerId,
+		Amount:          amount,
+		WalletHistoryId: &walletHistoryId,
+		MakerId:         makerId,
+		ShiftId:         shiftId,
+		Status:          config.STATUS_PENDING_APPROVAL,
+		ExpiresAt:       now.Add(time.Hour),
+		CreatedAt:       now,
+	})
+	if err != nil {
+		t.Fatalf("InsertOne(cashier_approval): %v", err)
+	}
+
+	response := approveCashierApproval(t, checkerId, approvalId)
+	if response.IsSuccess {
+		t.Fatalf("approve succeeded although ledger rejected withdraw_payout")
+	}
+	checkWithdrawApproval(t, walletHistoryId, approvalId, shiftId, config.STATUS_PENDING_APPROVAL, 0)
+
+	// ledger yene isleyar: sol approval ikinji gezek tassyklanyp bilinmeli.
+	err = db.RunCommand(ctx, bson.D{{Key: "collMod", Value: ledger.CollEntries}, {Key: "validator", Value: bson.M{}}}).Err()
+	if err != nil {
+		t.Fatalf("collMod(ledger_entries): %v", err)
+	}
+	response = approveCashierApproval(t, checkerId, approvalId)
+	if !response.IsSuccess {
+		t.Fatalf("second approve failed: %v", response.Error)
+	}
+	checkWithdrawApproval(t, walletHistoryId, approvalId, shiftId, config.STATUS_COMPLETED, amount)
+}
+
+// withdraw yalnys bolsa wallet_history pending_approval, smenada withdraw yok we cashier_works bos.
+func checkWithdrawApproval(t *testing.T, walletHistoryId primitive.ObjectID, approvalId primitive.ObjectID,
+	shiftId primitive.ObjectID, status string, withdrawals float64) {
+	ctx := context.Background()
+	db := config.MI.DB
+	completed := status == config.STATUS_COMPLETED
+	var walletHistory struct {
+		Status      string              `bson:"status"`
+		EmployeeId  *primitive.ObjectID `bson:"employee_id"`
+		CompletedAt *time.Time          `bson:"completed_at"`
+	}
+	err := db.Collection(config.WALLETHISTORY).FindOne(ctx, bson.M{"_id": walletHistoryId}).Decode(&walletHistory)
+	if err != nil {
+		t.Fatalf("FindOne(wallet_history): %v", err)
+	}
+	if walletHistory.Status != status {
+		t.Errorf("wallet_history status: %v != %v", walletHistory.Status, status)
+	}
+	if completed != (walletHistory.EmployeeId != nil) || completed != (walletHistory.CompletedAt != nil) {
+		t.Errorf("wallet_history employee_id/completed_at not in line with status %v", status)
+	}
+	var approval models.CashierApproval
+	err = db.Collection(config.CASHIER_APPROVALS).FindOne(ctx, bson.M{"_id": approvalId}).Decode(&approval)
+	if err != nil {
+		t.Fatalf("FindOne(cashier_approval): %v", err)
+	}
+	approvalStatus := config.STATUS_PENDING_APPROVAL
+	if completed {
+		approvalStatus = config.STATUS_APPROVED
+	}
+	if approval.Status != approvalStatus {
+		t.Errorf("approval status: %v != %v", approval.Status, approvalStatus)
+	}
+	var shift models.CashierShift
+	err = db.Collection(config.CASHIER_SHIFTS).FindOne(ctx, bson.M{"_id": shiftId}).Decode(&shift)
+	if err != nil {
+		t.Fatalf("FindOne(cashier_shift): %v", err)
+	}
+	if shift.Withdrawals != withdrawals {
+		t.Errorf("shift withdrawals: %v != %v", shift.Withdrawals, withdrawals)
+	}
+	works, err := db.Collection(config.CASHIERWORKS).CountDocuments(ctx, bson.M{"approval_id": approvalId})
+	if err != nil {
+		t.Fatalf("CountDocuments(cashier_works): %v", err)
+	}
+	if completed != (works == 1) || works > 1 {
+		t.Errorf("cashier_works for approval: %v", works)
+	}
+}
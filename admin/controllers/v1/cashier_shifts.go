@@ -0,0 +1,433 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/helpers"
+	"github.com/devzatruk/bizhubBackend/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errNoOpenShift = errors.New("Open a cashier shift first.")
+
+// cashier-in acyk smenasy, deposit/withdraw su smena yazylyar.
+func findOpenShift(ctx context.Context, employeeObjId primitive.ObjectID) (*models.CashierShift, error) {
+	var shift models.CashierShift
+	err := config.MI.DB.Collection(config.CASHIER_SHIFTS).FindOne(ctx, bson.M{
+		"employee_id": employeeObjId,
+		"status":      config.STATUS_OPEN,
+	}).Decode(&shift)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errNoOpenShift
+		}
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// deposit/withdraw smena jemlerine yazylyar. Smena acyk bolmaly (tassyklanan operasiyada hem):
+// barlag we yazgy bir write, sonun ucin CloseCashierShift() bilen yarysmayar.
+func addShiftWork(ctx context.Context, shiftId primitive.ObjectID, intent string, amount float64) error {
+	updateResult, err := config.MI.DB.Collection(config.CASHIER_SHIFTS).UpdateOne(ctx,
+		bson.M{
+			"_id":    shiftId,
+			"status": config.STATUS_OPEN,
+		},
+		shiftTotalsUpdate(intent, amount, 1))
+	if err != nil {
+		return err
+	}
+	if updateResult.MatchedCount == 0 {
+		return errNoOpenShift
+	}
+	return nil
+}
+
+// addShiftWork()-dan sonky adim yalnys bolsa. Filter-de status yok: smena arada yapylan bolup biler.
+func removeShiftWork(ctx context.Context, shiftId primitive.ObjectID, intent string, amount float64) error {
+	_, err := config.MI.DB.Collection(config.CASHIER_SHIFTS).UpdateOne(ctx,
+		bson.M{"_id": shiftId},
+		shiftTotalsUpdate(intent, -amount, -1))
+	return err
+}
+
+func shiftTotalsUpdate(intent string, amount float64, count int64) bson.M {
+	field := "deposits"
+	if intent == config.INTENT_WITHDRAW {
+		field = "withdrawals"
+	}
+	return bson.M{
+		"$inc": bson.M{
+			field:            amount,
+			field + "_count": count,
+		},
+	}
+}
+
+// acyk smenada kassada bolmaly pul.
+func setExpectedCash(shift *models.CashierShift) {
+	shift.Deposits = roundCash(shift.Deposits)
+	shift.Withdrawals = roundCash(shift.Withdrawals)
+	expected := roundCash(shift.OpeningCash + shift.Deposits - shift.Withdrawals)
+	shift.ExpectedCash = &expected
+}
+
+func roundCash(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func OpenCashierShift(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.OpenCashierShift")
+	var employeeObjId primitive.ObjectID
+	err := helpers.GetCurrentEmployee(c, &employeeObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentEmployee()", err, config.AUTH_REQUIRED))
+	}
+	var payload struct {
+		OpeningCash *float64 `json:"opening_cash"`
+	}
+	err = c.BodyParser(&payload)
+	if err != nil {
+		return c.JSON(errRes("BodyParser()", err, config.CANT_DECODE))
+	}
+	if payload.OpeningCash == nil || *payload.OpeningCash < 0 {
+		return c.JSON(errRes("OpeningCash", errors.New("Opening cash must be provided."), config.BODY_NOT_PROVIDED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	shift := models.CashierShift{
+		Id:          primitive.NewObjectID(),
+		EmployeeId:  employeeObjId,
+		Status:      config.STATUS_OPEN,
+		OpeningCash: roundCash(*payload.OpeningCash),
+		OpenedAt:    time.Now(),
+	}
+	// upsert: bir cashier-de bir wagtda dine bir acyk smena bolup bilyar.
+	updateResult, err := config.MI.DB.Collection(config.CASHIER_SHIFTS).UpdateOne(ctx,
+		bson.M{
+			"employee_id": employeeObjId,
+			"status":      config.STATUS_OPEN,
+		},
+		bson.M{
+			"$setOnInsert": shift,
+		},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) { // parallel acylan smena, unique index
+			return c.JSON(errRes("ShiftAlreadyOpen", errors.New("Cashier shift is already open."), config.NOT_ALLOWED))
+		}
+		return c.JSON(errRes("UpdateOne(cashier_shift)", err, config.CANT_INSERT))
+	}
+	if updateResult.UpsertedCount == 0 {
+		return c.JSON(errRes("ShiftAlreadyOpen", errors.New("Cashier shift is already open."), config.NOT_ALLOWED))
+	}
+	return c.JSON(models.Response[models.CashierShift]{
+		IsSuccess: true,
+		Result:    shift,
+	})
+}
+
+// acyk smena we su wagta cenli hasaplanan pul.
+func GetCurrentCashierShift(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.GetCurrentCashierShift")
+	var employeeObjId primitive.ObjectID
+	err := helpers.GetCurrentEmployee(c, &employeeObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentEmployee()", err, config.AUTH_REQUIRED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	shift, err := findOpenShift(ctx, employeeObjId)
+	if err != nil {
+		if err == errNoOpenShift {
+			return c.JSON(errRes("findOpenShift()", err, config.NOT_FOUND))
+		}
+		return c.JSON(errRes("findOpenShift()", err, config.DBQUERY_ERROR))
+	}
+	setExpectedCash(shift)
+	return c.JSON(models.Response[*models.CashierShift]{
+		IsSuccess: true,
+		Result:    shift,
+	})
+}
+
+func CloseCashierShift(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.CloseCashierShift")
+	var employeeObjId primitive.ObjectID
+	err := helpers.GetCurrentEmployee(c, &employeeObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentEmployee()", err, config.AUTH_REQUIRED))
+	}
+	var payload struct {
+		ClosingCash *float64 `json:"closing_cash"`
+		Note        string   `json:"note"`
+	}
+	err = c.BodyParser(&payload)
+	if err != nil {
+		return c.JSON(errRes("BodyParser()", err, config.CANT_DECODE))
+	}
+	if payload.ClosingCash == nil || *payload.ClosingCash < 0 {
+		return c.JSON(errRes("ClosingCash", errors.New("Closing cash must be provided."), config.BODY_NOT_PROVIDED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// tassyklanmaga garasyan operasiyalar bu smena yazylyar: olar ilki cozulmeli.
+	openShift, err := findOpenShift(ctx, employeeObjId)
+	if err != nil {
+		if err == errNoOpenShift {
+			return c.JSON(errRes("findOpenShift()", err, config.NOT_FOUND))
+		}
+		return c.JSON(errRes("findOpenShift()", err, config.DBQUERY_ERROR))
+	}
+	pending, err := config.MI.DB.Collection(config.CASHIER_APPROVALS).CountDocuments(ctx, bson.M{
+		"shift_id": openShift.Id,
+		"status":   config.STATUS_PENDING_APPROVAL,
+	})
+	if err != nil {
+		return c.JSON(errRes("CountDocuments(cashier_approvals)", err, config.DBQUERY_ERROR))
+	}
+	if pending > 0 {
+		return c.JSON(errRes("PendingApprovals", errors.New("Shift has operations waiting for approval."), config.NOT_ALLOWED))
+	}
+	closingCash := roundCash(*payload.ClosingCash)
+	var note *string
+	if len(payload.Note) > 0 {
+		note = &payload.Note
+	}
+	// bir write: deposit/withdraw smena jemlerini status open bolsa artdyryar, sonun ucin
+	// smena yapylandan son gelenler ona yazylmayar we jemler yapylan wagtdaky bilen gabat gelyar.
+	var shift models.CashierShift
+	err = config.MI.DB.Collection(config.CASHIER_SHIFTS).FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":    openShift.Id,
+			"status": config.STATUS_OPEN,
+		},
+		bson.A{
+			bson.M{
+				"$set": bson.M{
+					"status":            config.STATUS_CLOSED,
+					"closed_at":         time.Now(),
+					"deposits":          bson.M{"$round": bson.A{bson.M{"$ifNull": bson.A{"$deposits", 0}}, 2}},
+					"deposits_count":    bson.M{"$ifNull": bson.A{"$deposits_count", 0}},
+					"withdrawals":       bson.M{"$round": bson.A{bson.M{"$ifNull": bson.A{"$withdrawals", 0}}, 2}},
+					"withdrawals_count": bson.M{"$ifNull": bson.A{"$withdrawals_count", 0}},
+					"closing_cash":      closingCash,
+					"note":              note,
+				},
+			},
+			bson.M{
+				"$set": bson.M{
+					"expected_cash": bson.M{
+						"$round": bson.A{
+							bson.M{"$subtract": bson.A{bson.M{"$add": bson.A{"$opening_cash", "$deposits"}}, "$withdrawals"}},
+							2,
+						},
+					},
+				},
+			},
+			bson.M{
+				"$set": bson.M{
+					"difference": bson.M{"$round": bson.A{bson.M{"$subtract": bson.A{closingCash, "$expected_cash"}}, 2}},
+				},
+			},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&shift)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.JSON(errRes("FindOneAndUpdate(cashier_shift)", errNoOpenShift, config.NOT_FOUND))
+		}
+		return c.JSON(errRes("FindOneAndUpdate(cashier_shift)", err, config.CANT_UPDATE))
+	}
+	return c.JSON(models.Response[models.CashierShift]{
+		IsSuccess: true,
+		Result:    shift,
+	})
+}
+
+// ?employee_id, ?status (open, closed), ?from, ?to (dd-mm-yyyy, opened_at boyunca)
+func cashierShiftsFilter(c *fiber.Ctx) (bson.M, string, error) {
+	filter := bson.M{}
+	if employeeId := c.Query("employee_id"); len(employeeId) > 0 {
+		employeeObjId, err := primitive.ObjectIDFromHex(employeeId)
+		if err != nil {
+			return nil, "Query(employee_id)", err
+		}
+		filter["employee_id"] = employeeObjId
+	}
+	if status := c.Query("status"); len(status) > 0 {
+		filter["status"] = status
+	}
+	openedAt := bson.M{}
+	if from := c.Query("from"); len(from) > 0 {
+		fromDate, err := helpers.StringToDate(from)
+		if err != nil {
+			return nil, "Query(from)", err
+		}
+		openedAt["$gte"] = fromDate
+	}
+	if to := c.Query("to"); len(to) > 0 {
+		toDate, err := helpers.StringToDate(to)
+		if err != nil {
+			return nil, "Query(to)", err
+		}
+		openedAt["$lt"] = toDate.AddDate(0, 0, 1)
+	}
+	if len(openedAt) > 0 {
+		filter["opened_at"] = openedAt
+	}
+	return filter, "", nil
+}
+
+func findCashierShifts(ctx context.Context, filter bson.M, skip int64, limit int64) ([]models.CashierShift, error) {
+	aggregationArray := bson.A{
+		bson.M{
+			"$match": filter,
+		},
+		bson.M{
+			"$sort": bson.M{"opened_at": -1},
+		},
+	}
+	if limit > 0 {
+		aggregationArray = append(aggregationArray,
+			bson.M{"$skip": skip},
+			bson.M{"$limit": limit})
+	}
+	aggregationArray = append(aggregationArray,
+		bson.M{
+			"$lookup": bson.M{
+				"from":         config.EMPLOYEES,
+				"localField":   "employee_id",
+				"foreignField": "_id",
+				"as":           "employee",
+				"pipeline": bson.A{
+					bson.M{
+						"$project": bson.M{
+							"full_name": 1,
+						},
+					},
+				},
+			},
+		},
+		bson.M{
+			"$addFields": bson.M{
+				"employee_name": bson.M{"$first": "$employee.full_name"},
+			},
+		},
+		bson.M{
+			"$project": bson.M{
+				"employee": 0,
+			},
+		})
+	cursor, err := config.MI.DB.Collection(config.CASHIER_SHIFTS).Aggregate(ctx, aggregationArray)
+	if err != nil {
+		return nil, err
+	}
+	shifts := make([]models.CashierShift, 0)
+	if err = cursor.All(ctx, &shifts); err != nil {
+		return nil, err
+	}
+	return shifts, nil
+}
+
+func GetCashierShifts(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.GetCashierShifts")
+	pageIndex, err := strconv.Atoi(c.Query("page", "0"))
+	if err != nil {
+		return c.JSON(errRes("Query(page)", err, config.QUERY_NOT_PROVIDED))
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || limit <= 0 {
+		return c.JSON(errRes("Query(limit)", err, config.QUERY_NOT_PROVIDED))
+	}
+	filter, fn, err := cashierShiftsFilter(c)
+	if err != nil {
+		return c.JSON(errRes(fn, err, config.QUERY_NOT_PROVIDED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	shifts, err := findCashierShifts(ctx, filter, int64(pageIndex*limit), int64(limit))
+	if err != nil {
+		return c.JSON(errRes("findCashierShifts()", err, config.DBQUERY_ERROR))
+	}
+	return c.JSON(models.Response[[]models.CashierShift]{
+		IsSuccess: true,
+		Result:    shifts,
+	})
+}
+
+// GetCashierShifts() filter-leri bilen, sahypasyz, CSV edip.
+func ExportCashierShifts(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.ExportCashierShifts")
+	filter, fn, err := cashierShiftsFilter(c)
+	if err != nil {
+		return c.JSON(errRes(fn, err, config.QUERY_NOT_PROVIDED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	shifts, err := findCashierShifts(ctx, filter, 0, 0)
+	if err != nil {
+		return c.JSON(errRes("findCashierShifts()", err, config.DBQUERY_ERROR))
+	}
+	formatAmount := func(amount *float64) string {
+		if amount == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*amount, 'f', 2, 64)
+	}
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.Write([]string{
+		"shift_id", "employee_id", "employee_name", "status", "opened_at", "closed_at",
+		"opening_cash", "deposits", "deposits_count", "withdrawals", "withdrawals_count",
+		"expected_cash", "closing_cash", "difference", "note",
+	})
+	for _, shift := range shifts {
+		note := ""
+		if shift.Note != nil {
+			note = *shift.Note
+		}
+		writer.Write([]string{
+			shift.Id.Hex(),
+			shift.EmployeeId.Hex(),
+			shift.EmployeeName,
+			shift.Status,
+			formatTime(&shift.OpenedAt),
+			formatTime(shift.ClosedAt),
+			formatAmount(&shift.OpeningCash),
+			formatAmount(&shift.Deposits),
+			strconv.FormatInt(shift.DepositsCount, 10),
+			formatAmount(&shift.Withdrawals),
+			strconv.FormatInt(shift.WithdrawalsCount, 10),
+			formatAmount(shift.ExpectedCash),
+			formatAmount(shift.ClosingCash),
+			formatAmount(shift.Difference),
+			note,
+		})
+	}
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return c.JSON(errRes("csv.Writer", err, config.SERVER_ERROR))
+	}
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Attachment(fmt.Sprintf("cashier_shifts_%v.csv", time.Now().Format("2006-01-02")))
+	return c.Send(buffer.Bytes())
+}
@@ -0,0 +1,272 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/helpers"
+	"github.com/devzatruk/bizhubBackend/models"
+	"github.com/devzatruk/bizhubBackend/packageservice"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type couponPayload struct {
+	Code           string     `json:"code"`
+	DiscountType   string     `json:"discount_type"`
+	Value          *float64   `json:"value"`
+	ValidFrom      *time.Time `json:"valid_from"`
+	ValidTo        *time.Time `json:"valid_to"`
+	MaxRedemptions *int64     `json:"max_redemptions"`
+	MaxPerSeller   *int64     `json:"max_per_seller"`
+	PackageTypes   []string   `json:"package_types"`
+	SellerTypes    []string   `json:"seller_types"`
+	Status         string     `json:"status"`
+	Note           *string    `json:"note"`
+}
+
+func validateCoupon(coupon *models.Coupon) error {
+	if len(coupon.Code) < 3 {
+		return errors.New("Coupon code must be at least 3 characters.")
+	}
+	if !helpers.SliceContains(config.DISCOUNT_TYPES, coupon.DiscountType) {
+		return errors.New("Invalid discount type.")
+	}
+	if coupon.Value <= 0 || (coupon.DiscountType == config.DISCOUNT_PERCENT && coupon.Value > 100) {
+		return errors.New("Invalid discount value.")
+	}
+	if !coupon.ValidTo.After(coupon.ValidFrom) {
+		return errors.New("valid_to must be after valid_from.")
+	}
+	if coupon.MaxRedemptions < 0 || coupon.MaxPerSeller < 0 {
+		return errors.New("Limits can't be negative.")
+	}
+	for _, packageType := range coupon.PackageTypes {
+		if !helpers.SliceContains(config.PACKAGE_TYPES, packageType) {
+			return errors.New("Invalid package type.")
+		}
+	}
+	if !helpers.SliceContains([]string{config.STATUS_ACTIVE, config.STATUS_BLOCKED}, coupon.Status) {
+		return errors.New("Invalid coupon status.")
+	}
+	return nil
+}
+
+func CreateCoupon(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.CreateCoupon")
+	var employeeObjId primitive.ObjectID
+	err := helpers.GetCurrentEmployee(c, &employeeObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentEmployee()", err, config.AUTH_REQUIRED))
+	}
+	var payload couponPayload
+	err = c.BodyParser(&payload)
+	if err != nil {
+		return c.JSON(errRes("BodyParser()", err, config.CANT_DECODE))
+	}
+	if payload.Value == nil || payload.ValidTo == nil {
+		return c.JSON(errRes("NoValue", errors.New("value and valid_to must be provided."), config.BODY_NOT_PROVIDED))
+	}
+	now := time.Now()
+	coupon := models.Coupon{
+		Id:           primitive.NewObjectID(),
+		Code:         packageservice.NormalizeCouponCode(payload.Code),
+		DiscountType: payload.DiscountType,
+		Value:        *payload.Value,
+		ValidFrom:    now,
+		ValidTo:      *payload.ValidTo,
+		PackageTypes: payload.PackageTypes,
+		SellerTypes:  payload.SellerTypes,
+		Status:       config.STATUS_ACTIVE,
+		CreatedBy:    &employeeObjId,
+		CreatedAt:    now,
+	}
+	if payload.ValidFrom != nil {
+		coupon.ValidFrom = *payload.ValidFrom
+	}
+	if payload.MaxRedemptions != nil {
+		coupon.MaxRedemptions = *payload.MaxRedemptions
+	}
+	if payload.MaxPerSeller != nil {
+		coupon.MaxPerSeller = *payload.MaxPerSeller
+	}
+	if payload.Note != nil {
+		coupon.Note = *payload.Note
+	}
+	if coupon.PackageTypes == nil {
+		coupon.PackageTypes = []string{}
+	}
+	if coupon.SellerTypes == nil {
+		coupon.SellerTypes = []string{}
+	}
+	err = validateCoupon(&coupon)
+	if err != nil {
+		return c.JSON(errRes("validateCoupon()", err, config.NOT_ALLOWED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// upsert: sol code bilen coupon bar bolsa taze doredilmeyar.
+	updateResult, err := config.MI.DB.Collection(config.COUPONS).UpdateOne(ctx,
+		bson.M{"code": coupon.Code},
+		bson.M{"$setOnInsert": coupon},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return c.JSON(errRes("UpdateOne(coupon)", err, config.CANT_INSERT))
+	}
+	if updateResult.UpsertedCount == 0 {
+		return c.JSON(errRes("CodeExists", errors.New("Coupon with this code already exists."), config.NOT_ALLOWED))
+	}
+	return c.JSON(models.Response[models.Coupon]{
+		IsSuccess: true,
+		Result:    coupon,
+	})
+}
+
+// ?status=active&code=NEW: sahypaly sanaw, taze doredilenler birinji.
+func GetCoupons(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.GetCoupons")
+	pageIndex, err := strconv.Atoi(c.Query("page", "0"))
+	if err != nil {
+		return c.JSON(errRes("Query(page)", err, config.QUERY_NOT_PROVIDED))
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || limit <= 0 {
+		return c.JSON(errRes("Query(limit)", err, config.QUERY_NOT_PROVIDED))
+	}
+	filter := bson.M{}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	if code := c.Query("code"); code != "" {
+		filter["code"] = packageservice.NormalizeCouponCode(code)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cursor, err := config.MI.DB.Collection(config.COUPONS).Find(ctx, filter, options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetSkip(int64(pageIndex*limit)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return c.JSON(errRes("Find(coupons)", err, config.DBQUERY_ERROR))
+	}
+	coupons := []models.Coupon{}
+	err = cursor.All(ctx, &coupons)
+	if err != nil {
+		return c.JSON(errRes("cursor.All()", err, config.CANT_DECODE))
+	}
+	return c.JSON(models.Response[[]models.Coupon]{
+		IsSuccess: true,
+		Result:    coupons,
+	})
+}
+
+// code, discount we onki redemptions uytgemeyar, dine status, wagt we limitler.
+func UpdateCoupon(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.UpdateCoupon")
+	couponObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	var payload couponPayload
+	err = c.BodyParser(&payload)
+	if err != nil {
+		return c.JSON(errRes("BodyParser()", err, config.CANT_DECODE))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var coupon models.Coupon
+	err = config.MI.DB.Collection(config.COUPONS).FindOne(ctx, bson.M{"_id": couponObjId}).Decode(&coupon)
+	if err != nil {
+		return c.JSON(errRes("FindOne(coupon)", err, config.NOT_FOUND))
+	}
+	if payload.Status != "" {
+		coupon.Status = payload.Status
+	}
+	if payload.ValidFrom != nil {
+		coupon.ValidFrom = *payload.ValidFrom
+	}
+	if payload.ValidTo != nil {
+		coupon.ValidTo = *payload.ValidTo
+	}
+	if payload.MaxRedemptions != nil {
+		coupon.MaxRedemptions = *payload.MaxRedemptions
+	}
+	if payload.MaxPerSeller != nil {
+		coupon.MaxPerSeller = *payload.MaxPerSeller
+	}
+	if payload.Note != nil {
+		coupon.Note = *payload.Note
+	}
+	err = validateCoupon(&coupon)
+	if err != nil {
+		return c.JSON(errRes("validateCoupon()", err, config.NOT_ALLOWED))
+	}
+	now := time.Now()
+	coupon.UpdatedAt = &now
+	_, err = config.MI.DB.Collection(config.COUPONS).UpdateOne(ctx, bson.M{"_id": couponObjId}, bson.M{
+		"$set": bson.M{
+			"status":          coupon.Status,
+			"valid_from":      coupon.ValidFrom,
+			"valid_to":        coupon.ValidTo,
+			"max_redemptions": coupon.MaxRedemptions,
+			"max_per_seller":  coupon.MaxPerSeller,
+			"note":            coupon.Note,
+			"updated_at":      now,
+		},
+	})
+	if err != nil {
+		return c.JSON(errRes("UpdateOne(coupon)", err, config.CANT_UPDATE))
+	}
+	return c.JSON(models.Response[models.Coupon]{
+		IsSuccess: true,
+		Result:    coupon,
+	})
+}
+
+// ?seller_id=...: coupon-y kim, haysy package ucin we name arzanladys bilen ulandy.
+func GetCouponRedemptions(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.GetCouponRedemptions")
+	couponObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	pageIndex, err := strconv.Atoi(c.Query("page", "0"))
+	if err != nil {
+		return c.JSON(errRes("Query(page)", err, config.QUERY_NOT_PROVIDED))
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || limit <= 0 {
+		return c.JSON(errRes("Query(limit)", err, config.QUERY_NOT_PROVIDED))
+	}
+	filter := bson.M{"coupon_id": couponObjId}
+	if sellerId := c.Query("seller_id"); sellerId != "" {
+		sellerObjId, err := primitive.ObjectIDFromHex(sellerId)
+		if err != nil {
+			return c.JSON(errRes("Query(seller_id)", err, config.QUERY_NOT_PROVIDED))
+		}
+		filter["seller_id"] = sellerObjId
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cursor, err := config.MI.DB.Collection(config.COUPON_REDEMPTIONS).Find(ctx, filter, options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetSkip(int64(pageIndex*limit)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return c.JSON(errRes("Find(coupon_redemptions)", err, config.DBQUERY_ERROR))
+	}
+	redemptions := []models.CouponRedemption{}
+	err = cursor.All(ctx, &redemptions)
+	if err != nil {
+		return c.JSON(errRes("cursor.All()", err, config.CANT_DECODE))
+	}
+	return c.JSON(models.Response[[]models.CouponRedemption]{
+		IsSuccess: true,
+		Result:    redemptions,
+	})
+}
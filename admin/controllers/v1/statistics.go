@@ -318,6 +318,8 @@ func RunStatistic(c *fiber.Ctx) error {
 		config.StatisticsService.Writer.MoneyDeposited(body.Payload["amount"].(float64))
 	case "money_withdrew":
 		config.StatisticsService.Writer.MoneyWithdrew(body.Payload["amount"].(float64))
+	case "money_discounted":
+		config.StatisticsService.Writer.MoneyDiscounted(body.Payload["amount"].(float64))
 	case "new_expense":
 		log.Log("ay isledimow :)")
 		var exp statisticsservice.StatisticExpense
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/devzatruk/bizhubBackend/config"
+	ojocronlisteners "github.com/devzatruk/bizhubBackend/config/ojocron_listeners"
 	"github.com/devzatruk/bizhubBackend/helpers"
 	"github.com/devzatruk/bizhubBackend/models"
 	notificationmanager "github.com/devzatruk/bizhubBackend/notification_manager"
@@ -423,17 +424,36 @@ func GetTaskDetail(c *fiber.Ctx) error {
 					"path": "$seller",
 				},
 			},
+			bson.M{
+				"$lookup": bson.M{
+					"from":         "attributes",
+					"localField":   "variant_attrs",
+					"foreignField": "_id",
+					"as":           "variant_attrs",
+					"pipeline": bson.A{
+						bson.M{
+							"$project": bson.M{
+								"name":        "$name.en",
+								"is_number":   1,
+								"units_array": 1,
+							},
+						},
+					},
+				},
+			},
 			bson.M{
 				"$project": bson.M{
-					"heading":      1,
-					"more_details": 1,
-					"price":        1,
-					"discount":     1,
-					"images":       1,
-					"brand":        1,
-					"category":     1,
-					"attrs":        1,
-					"seller":       1,
+					"heading":       1,
+					"more_details":  1,
+					"price":         1,
+					"discount":      1,
+					"images":        1,
+					"brand":         1,
+					"category":      1,
+					"attrs":         1,
+					"seller":        1,
+					"variant_attrs": 1,
+					"variants":      1,
 				},
 			}}
 	case config.TASK_PROFILE:
@@ -526,6 +546,7 @@ func ConfirmTask(c *fiber.Ctx) error {
 		return c.JSON(errRes("Decode(Task)", err, config.CANT_DECODE))
 	}
 	transaction_manager := ojoTr.NewTransaction(&ctx, config.MI.DB, 3)
+	var cronJobs []*ojocronservice.OjoCronJobModel
 	switch dbTask.Type {
 	case config.TASK_POST:
 		var postData struct {
@@ -741,32 +762,23 @@ func ConfirmTask(c *fiber.Ctx) error {
 			}
 			return c.JSON(errRes("Decode(finishTime)", err, config.CANT_DECODE))
 		}
-		var batch []interface{}
 		// create a cron job that activates auction at started_at
 
 		// create a cron job for when auction is finished
+		// group=auction_id, anti-sniping uzaldanda run_at-i uytgetmek ucin gerek
 		modelF := ojocronservice.NewOjoCronJobModel()
 		modelF.ListenerName(config.AUCTION_FINISHED)
+		modelF.Group(dbTask.TargetId)
 		modelF.Payload(map[string]interface{}{"auction_id": dbTask.TargetId})
 		modelF.RunAt(auctionTimes.FinishedAt)
-		batch = append(batch, modelF)
+		cronJobs = append(cronJobs, modelF)
 		// create a cron job for when auction is to be deleted automatically
 		modelR := ojocronservice.NewOjoCronJobModel()
 		modelR.ListenerName(config.AUCTION_REMOVED)
+		modelR.Group(dbTask.TargetId)
 		modelR.Payload(map[string]interface{}{"auction_id": dbTask.TargetId})
-		modelR.RunAt(auctionTimes.FinishedAt.Add(time.Hour * 24))
-
-		batch = append(batch, modelR)
-		tr_cronJobsColl := transaction_manager.Collection(config.CRON_JOBS)
-		insertMany_model := ojoTr.NewModel().SetManyDocuments(batch)
-		_, err = tr_cronJobsColl.InsertMany(insertMany_model)
-		if err != nil {
-			trErr := transaction_manager.Rollback()
-			if trErr != nil {
-				err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
-			}
-			return c.JSON(errRes("InsertMany(cron_jobs)", err, config.CANT_INSERT))
-		}
+		modelR.RunAt(auctionTimes.FinishedAt.Add(config.AUCTION_REMOVE_AFTER))
+		cronJobs = append(cronJobs, modelR)
 	}
 	service := config.EverydayWorkService.Of(employeeObjId)
 	switch dbTask.Type {
@@ -800,6 +812,25 @@ func ConfirmTask(c *fiber.Ctx) error {
 		}
 		return c.JSON(errRes("Rollback()", err, config.TRANSACTION_FAILED))
 	}
+	// cron job-lar transaction-dan son doredilyar; biri doredilmese auction job-syz galmaz yaly
+	// doredilen job-lar pozulyar we transaction yzyna gaydarylyar.
+	for _, jobModel := range cronJobs {
+		err = config.OjoCronService.NewJob(jobModel)
+		if err != nil {
+			removeErr := config.OjoCronService.RemoveJobsByGroup(dbTask.TargetId)
+			if removeErr != nil {
+				err = fmt.Errorf("Source: %v - RemoveJobsByGroup: %v", err.Error(), removeErr.Error())
+			}
+			trErr := transaction_manager.Rollback()
+			if trErr != nil {
+				err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+			}
+			return c.JSON(errRes("NewJob(cron_jobs)", err, config.CANT_INSERT))
+		}
+	}
+	if dbTask.Type == config.TASK_PRODUCT {
+		ojocronlisteners.ReindexProduct(dbTask.TargetId)
+	}
 	// TODO: yokarda tasks collection-dan task-y pozyas, we asakda hem RemoveTask() edyas. gerekmi?
 	config.CheckerTaskService.RemoveTask(taskId)
 
@@ -0,0 +1,191 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	ojocronlisteners "github.com/devzatruk/bizhubBackend/config/ojocron_listeners"
+	"github.com/devzatruk/bizhubBackend/helpers"
+	"github.com/devzatruk/bizhubBackend/models"
+	"github.com/devzatruk/bizhubBackend/receiptservice"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func GetWalletReconciliations(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.GetWalletReconciliations")
+	pageIndex, err := strconv.Atoi(c.Query("page", "0"))
+	if err != nil {
+		return c.JSON(errRes("Query(page)", err, config.QUERY_NOT_PROVIDED))
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil {
+		return c.JSON(errRes("Query(limit)", err, config.QUERY_NOT_PROVIDED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	reportsColl := config.MI.DB.Collection(config.WALLET_RECONCILIATIONS)
+	cursor, err := reportsColl.Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.M{"started_at": -1}).
+		SetSkip(int64(pageIndex*limit)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return c.JSON(errRes("Find()", err, config.DBQUERY_ERROR))
+	}
+	reports := make([]models.WalletReconciliation, 0)
+	err = cursor.All(ctx, &reports)
+	if err != nil {
+		return c.JSON(errRes("cursor.All()", err, config.CANT_DECODE))
+	}
+	return c.JSON(models.Response[[]models.WalletReconciliation]{
+		IsSuccess: true,
+		Result:    reports,
+	})
+}
+
+// ?reconciliation_id, ?seller_id, ?status (open, fixed, resolved), ?kind (balance, holds, ...)
+func GetWalletDrifts(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.GetWalletDrifts")
+	pageIndex, err := strconv.Atoi(c.Query("page", "0"))
+	if err != nil {
+		return c.JSON(errRes("Query(page)", err, config.QUERY_NOT_PROVIDED))
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil {
+		return c.JSON(errRes("Query(limit)", err, config.QUERY_NOT_PROVIDED))
+	}
+	filter := bson.M{}
+	if reconciliationId := c.Query("reconciliation_id"); len(reconciliationId) > 0 {
+		reconciliationObjId, err := primitive.ObjectIDFromHex(reconciliationId)
+		if err != nil {
+			return c.JSON(errRes("Query(reconciliation_id)", err, config.QUERY_NOT_PROVIDED))
+		}
+		filter["reconciliation_id"] = reconciliationObjId
+	}
+	if sellerId := c.Query("seller_id"); len(sellerId) > 0 {
+		sellerObjId, err := primitive.ObjectIDFromHex(sellerId)
+		if err != nil {
+			return c.JSON(errRes("Query(seller_id)", err, config.QUERY_NOT_PROVIDED))
+		}
+		filter["seller_id"] = sellerObjId
+	}
+	if status := c.Query("status"); len(status) > 0 {
+		filter["status"] = status
+	}
+	if kind := c.Query("kind"); len(kind) > 0 {
+		filter["kinds"] = kind
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	driftsColl := config.MI.DB.Collection(config.WALLET_DRIFTS)
+	cursor, err := driftsColl.Find(ctx, filter, options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetSkip(int64(pageIndex*limit)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return c.JSON(errRes("Find()", err, config.DBQUERY_ERROR))
+	}
+	drifts := make([]models.WalletDrift, 0)
+	err = cursor.All(ctx, &drifts)
+	if err != nil {
+		return c.JSON(errRes("cursor.All()", err, config.CANT_DECODE))
+	}
+	return c.JSON(models.Response[[]models.WalletDrift]{
+		IsSuccess: true,
+		Result:    drifts,
+	})
+}
+
+// reconciliation-y garasman el bilen isletmek ucin.
+func RunWalletReconciliation(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.RunWalletReconciliation")
+	var employeeObjId primitive.ObjectID
+	err := helpers.GetCurrentEmployee(c, &employeeObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentEmployee()", err, config.AUTH_REQUIRED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	report, err := ojocronlisteners.ReconcileWallets(ctx, &employeeObjId)
+	if err != nil {
+		return c.JSON(errRes("ReconcileWallets()", err, config.DBQUERY_ERROR))
+	}
+	return c.JSON(models.Response[*models.WalletReconciliation]{
+		IsSuccess: true,
+		Result:    report,
+	})
+}
+
+// drift-i correction entry bilen duzedyar, note hokmany (audit ucin).
+func FixWalletDrift(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.FixWalletDrift")
+	driftObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	var employeeObjId primitive.ObjectID
+	err = helpers.GetCurrentEmployee(c, &employeeObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentEmployee()", err, config.AUTH_REQUIRED))
+	}
+	var payload struct {
+		Note string `json:"note"`
+	}
+	err = c.BodyParser(&payload)
+	if err != nil {
+		return c.JSON(errRes("BodyParser()", err, config.CANT_DECODE))
+	}
+	if len(payload.Note) == 0 {
+		return c.JSON(errRes("Note", errors.New("Note is required."), config.BODY_NOT_PROVIDED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	drift, err := ojocronlisteners.FixWalletDrift(ctx, driftObjId, employeeObjId, payload.Note)
+	if err != nil {
+		if err == ojocronlisteners.ErrDriftNotOpen {
+			return c.JSON(errRes("FixWalletDrift()", err, config.NOT_FOUND))
+		}
+		return c.JSON(errRes("FixWalletDrift()", err, config.CANT_UPDATE))
+	}
+	return c.JSON(models.Response[*models.WalletDrift]{
+		IsSuccess: true,
+		Result:    drift,
+	})
+}
+
+// cashier deposit/withdraw-dan son seller-e kwitansiya cap edip beryar: ?format=pdf|html&culture=ru
+func GetWalletReceipt(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Admin.GetWalletReceipt")
+	culture := helpers.GetCultureFromQuery(c)
+	historyObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	format := c.Query("format", receiptservice.FormatHTML)
+	if format != receiptservice.FormatHTML && format != receiptservice.FormatPDF {
+		return c.JSON(errRes("Query(format)", errors.New("Format must be html or pdf."), config.QUERY_NOT_PROVIDED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	view, err := config.ReceiptService.Build(ctx, historyObjId, nil, culture.Lang)
+	if err != nil {
+		if err == receiptservice.ErrHistoryNotFound {
+			return c.JSON(errRes("Build()", err, config.NOT_FOUND))
+		}
+		return c.JSON(errRes("Build()", err, config.DBQUERY_ERROR))
+	}
+	contentType, body, err := config.ReceiptService.Render(view, format, culture.Lang)
+	if err != nil {
+		return c.JSON(errRes("Render()", err, config.SERVER_ERROR))
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="receipt-%v.%v"`,
+		receiptservice.FormatNumber(view.Receipt.Number), format))
+	return c.Send(body)
+}
@@ -15,4 +15,9 @@ func SetupAdminAuctionRoutes(router fiber.Router) {
 	auctions.Post("/", controllers.CreateNewAuction)
 	auctions.Get("/", controllers.GetAuctions)
 	auctions.Get("/:id", controllers.GetAuctionDetail)
+	auctions.Put("/:id", controllers.UpdateAuction)
+	auctions.Put("/:id/schedule", controllers.RescheduleAuction)
+	auctions.Post("/:id/cancel", controllers.CancelAuction)
+	auctions.Get("/:id/bids", controllers.GetAuctionBids)
+	auctions.Get("/:id/refunds", controllers.GetAuctionRefunds)
 }
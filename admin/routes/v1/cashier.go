@@ -17,4 +17,24 @@ func SetupAdminCashierRoutes(router fiber.Router) {
 	cashier.Post("/deposit", controllers.Deposit)
 	cashier.Get("/find", controllers.FindSeller)
 	cashier.Get("/completed_tasks", controllers.CompletedTasks)
+	cashier.Get("/shift", controllers.GetCurrentCashierShift)
+	cashier.Post("/shift/open", controllers.OpenCashierShift)
+	cashier.Post("/shift/close", controllers.CloseCashierShift)
+	cashier.Get("/receipt/:id", controllers.GetWalletReceipt)
+	cashier.Get("/approvals", controllers.GetMyCashierApprovals)
+
+	shifts := router.Group("/cashier_shifts",
+		middlewares.DeSerializeEmployee,
+		middlewares.AllowRoles([]string{config.ADMIN, config.OWNER, config.EMPLOYEES_MANAGER}),
+	)
+	shifts.Get("/", controllers.GetCashierShifts)
+	shifts.Get("/export", controllers.ExportCashierShifts)
+
+	approvals := router.Group("/cashier_approvals",
+		middlewares.DeSerializeEmployee,
+		middlewares.AllowRoles(config.CASHIER_APPROVER_ROLES),
+	)
+	approvals.Get("/", controllers.GetCashierApprovals)
+	approvals.Post("/:id/approve", controllers.ApproveCashierApproval)
+	approvals.Post("/:id/reject", controllers.RejectCashierApproval)
 }
@@ -0,0 +1,18 @@
+package v1
+
+import (
+	controllers "github.com/devzatruk/bizhubBackend/admin/controllers/v1"
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/middlewares"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupAdminCouponRoutes(router fiber.Router) {
+	coupons := router.Group("/coupons",
+		middlewares.DeSerializeEmployee,
+		middlewares.AllowRoles([]string{config.ADMIN, config.OWNER}))
+	coupons.Get("/", controllers.GetCoupons)
+	coupons.Post("/", controllers.CreateCoupon)
+	coupons.Put("/:id", controllers.UpdateCoupon)
+	coupons.Get("/:id/redemptions", controllers.GetCouponRedemptions)
+}
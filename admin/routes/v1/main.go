@@ -21,4 +21,6 @@ func SetupRoutes(api fiber.Router) {
 	SetupAdminCategoriesRoutes(v1)
 	SetupAdminAttributesRoutes(v1)
 	SetupAdminBrandsRoutes(v1)
+	SetupAdminWalletRoutes(v1)
+	SetupAdminCouponRoutes(v1)
 }
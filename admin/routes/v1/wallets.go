@@ -0,0 +1,19 @@
+package v1
+
+import (
+	controllers "github.com/devzatruk/bizhubBackend/admin/controllers/v1"
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/middlewares"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupAdminWalletRoutes(router fiber.Router) {
+	wallets := router.Group("/wallets",
+		middlewares.DeSerializeEmployee,
+		middlewares.AllowRoles([]string{config.ADMIN, config.OWNER}),
+	)
+	wallets.Get("/reconciliations", controllers.GetWalletReconciliations)
+	wallets.Post("/reconciliations", controllers.RunWalletReconciliation)
+	wallets.Get("/drifts", controllers.GetWalletDrifts)
+	wallets.Post("/drifts/:id/fix", controllers.FixWalletDrift)
+}
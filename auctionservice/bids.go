@@ -0,0 +1,92 @@
+package auctionservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	BidAccepted           = "accepted"
+	BidRejectedLowBalance = "rejected_low_balance"
+	BidRejected           = "rejected"
+	BidOutbid             = "outbid"
+	BidRefunded           = "refunded"
+)
+
+var (
+	CollAuctionBids = "auction_bids"
+)
+
+// auction_bids collection-a yazyar. Her bid synanysygy bir dokument, onun sonky
+// yagdayy `result`-da, ahli uytgesmeler bolsa `history[]`-de saklanyar.
+type AuctionServiceBidLedger struct {
+	service *AuctionService
+}
+
+// taze bid synanysygyny yazyar, reason dine ret edilen bid-ler ucin.
+func (l *AuctionServiceBidLedger) Record(ctx context.Context, auctionId, sellerId primitive.ObjectID, amount float64, isProxy bool, result string, reason *string) error {
+	now := time.Now()
+	bid := models.AuctionBid{
+		AuctionId: auctionId,
+		SellerId:  sellerId,
+		Amount:    amount,
+		IsProxy:   isProxy,
+		Result:    result,
+		Reason:    reason,
+		History: []models.AuctionBidHistory{
+			{
+				Result:    result,
+				Amount:    amount,
+				Note:      reason,
+				CreatedAt: now,
+			},
+		},
+		CreatedAt: now,
+	}
+	_, err := l.service.db.Collection(CollAuctionBids).InsertOne(ctx, bid)
+	return err
+}
+
+// winners[]-dan cykarylan seller-in sonky kabul edilen bid-ini `outbid` edyar,
+// saklanan pul balance-a gaydyp berlendigi ucin refunded_amount hem yazylyar.
+func (l *AuctionServiceBidLedger) MarkOutbid(ctx context.Context, auctionId, sellerId primitive.ObjectID, refunded float64) error {
+	return l.release(ctx, auctionId, sellerId, BidOutbid, refunded, nil)
+}
+
+// auction yatyrylanda ya-da sertler yerine yetirilmande saklanan pul gaytarylanda.
+func (l *AuctionServiceBidLedger) MarkRefunded(ctx context.Context, auctionId, sellerId primitive.ObjectID, refunded float64, note *string) error {
+	return l.release(ctx, auctionId, sellerId, BidRefunded, refunded, note)
+}
+
+func (l *AuctionServiceBidLedger) release(ctx context.Context, auctionId, sellerId primitive.ObjectID, result string, refunded float64, note *string) error {
+	now := time.Now()
+	opts := options.FindOneAndUpdate().SetSort(bson.M{"created_at": -1})
+	err := l.service.db.Collection(CollAuctionBids).FindOneAndUpdate(ctx,
+		bson.M{
+			"auction_id": auctionId,
+			"seller_id":  sellerId,
+			"result":     BidAccepted,
+		},
+		bson.M{
+			"$set": bson.M{
+				"result":          result,
+				"refunded_amount": refunded,
+				"refunded_at":     now,
+				"updated_at":      now,
+			},
+			"$push": bson.M{
+				"history": models.AuctionBidHistory{
+					Result:    result,
+					Amount:    refunded,
+					Note:      note,
+					CreatedAt: now,
+				},
+			},
+		}, opts).Err()
+	return err
+}
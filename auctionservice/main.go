@@ -0,0 +1,129 @@
+package auctionservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devzatruk/bizhubBackend/models"
+	"github.com/devzatruk/bizhubBackend/ws"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	CollAuctions = "auctions"
+	CollSellers  = "sellers"
+)
+
+type AuctionService struct {
+	db       *mongo.Database
+	Realtime *AuctionServiceRealtimeAnnouncer
+	Bids     *AuctionServiceBidLedger
+}
+
+func NewAuctionService() *AuctionService {
+	return &AuctionService{}
+}
+
+func (s *AuctionService) Init(db *mongo.Database, ws *ws.OjoWS) {
+	s.db = db
+	s.Realtime = &AuctionServiceRealtimeAnnouncer{
+		ws:      ws,
+		service: s,
+	}
+	s.Bids = &AuctionServiceBidLedger{
+		service: s,
+	}
+}
+
+// her auction ucin bir room: `auction-{{auction_id}}`
+func RoomName(auctionId primitive.ObjectID) string {
+	return fmt.Sprintf("auction-%v", auctionId.Hex())
+}
+
+// auction-in participants sanyny we winners[]-ny seller maglumatlary bilen gaytaryar.
+func (s *AuctionService) Winners(ctx context.Context, auctionId primitive.ObjectID) (int64, []models.AuctionDetailWinner, error) {
+	cursor, err := s.db.Collection(CollAuctions).Aggregate(ctx, bson.A{
+		bson.M{
+			"$match": bson.M{
+				"_id": auctionId,
+			},
+		},
+		bson.M{
+			"$project": bson.M{
+				"participants": 1,
+				"winners":      1,
+			},
+		},
+		bson.M{
+			"$unwind": bson.M{
+				"path":                       "$winners",
+				"includeArrayIndex":          "index",
+				"preserveNullAndEmptyArrays": true,
+			},
+		},
+		bson.M{
+			"$lookup": bson.M{
+				"from":         CollSellers,
+				"localField":   "winners.seller_id",
+				"foreignField": "_id",
+				"as":           "winners.seller",
+				"pipeline": bson.A{
+					bson.M{
+						"$project": bson.M{
+							"_id":  1,
+							"name": 1,
+							"logo": 1,
+							"type": 1,
+						},
+					},
+				},
+			},
+		},
+		bson.M{
+			"$unwind": bson.M{
+				"path":                       "$winners.seller",
+				"preserveNullAndEmptyArrays": true,
+			},
+		},
+		bson.M{
+			"$group": bson.M{
+				"_id": "$_id",
+				"participants": bson.M{
+					"$first": "$participants",
+				},
+				"winners": bson.M{
+					"$push": bson.M{
+						"$cond": bson.A{
+							bson.M{"$ifNull": bson.A{"$winners.seller_id", false}},
+							bson.M{"$mergeObjects": bson.A{"$winners", bson.M{"index": "$index"}}},
+							"$$REMOVE",
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	defer cursor.Close(ctx)
+	var result struct {
+		Participants int64                        `bson:"participants"`
+		Winners      []models.AuctionDetailWinner `bson:"winners"`
+	}
+	if cursor.Next(ctx) {
+		err = cursor.Decode(&result)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return 0, nil, err
+	}
+	if result.Winners == nil {
+		result.Winners = []models.AuctionDetailWinner{}
+	}
+	return result.Participants, result.Winners, nil
+}
@@ -0,0 +1,103 @@
+package auctionservice
+
+import (
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/models"
+	"github.com/devzatruk/bizhubBackend/ws"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EventBidPlaced           = "bid-placed"
+	EventOutbid              = "outbid"
+	EventMinimalBidChanged   = "minimal-bid-changed"
+	EventParticipantsChanged = "participants-changed"
+	EventAuctionFinished     = "auction-finished"
+	EventFinishedAtChanged   = "finished-at-changed"
+	EventAuctionCancelled    = "auction-cancelled"
+)
+
+type AuctionBidPlaced struct {
+	AuctionId primitive.ObjectID `json:"auction_id"`
+	SellerId  primitive.ObjectID `json:"seller_id"`
+	Sum       float64            `json:"sum"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type AuctionOutbid struct {
+	AuctionId primitive.ObjectID `json:"auction_id"`
+	SellerId  primitive.ObjectID `json:"seller_id"`
+	LastBid   float64            `json:"last_bid"`
+}
+
+type AuctionMinimalBidChanged struct {
+	AuctionId  primitive.ObjectID `json:"auction_id"`
+	MinimalBid float64            `json:"minimal_bid"`
+}
+
+type AuctionParticipantsChanged struct {
+	AuctionId    primitive.ObjectID           `json:"auction_id"`
+	Participants int64                        `json:"participants"`
+	Winners      []models.AuctionDetailWinner `json:"winners"`
+}
+
+type AuctionFinishedAtChanged struct {
+	AuctionId  primitive.ObjectID `json:"auction_id"`
+	FinishedAt time.Time          `json:"finished_at"`
+}
+
+type AuctionCancelled struct {
+	AuctionId primitive.ObjectID `json:"auction_id"`
+}
+
+type AuctionFinished struct {
+	AuctionId primitive.ObjectID           `json:"auction_id"`
+	Winners   []models.AuctionDetailWinner `json:"winners"`
+}
+
+type AuctionServiceRealtimeAnnouncer struct {
+	ws      *ws.OjoWS
+	service *AuctionService
+}
+
+func (a *AuctionServiceRealtimeAnnouncer) emit(auctionId primitive.ObjectID, event string, payload any) {
+	if a == nil {
+		return
+	}
+	a.ws.In(RoomName(auctionId)).Emit(event, payload)
+}
+
+func (a *AuctionServiceRealtimeAnnouncer) BidPlaced(e AuctionBidPlaced) {
+	a.emit(e.AuctionId, EventBidPlaced, e)
+}
+
+func (a *AuctionServiceRealtimeAnnouncer) Outbid(e AuctionOutbid) {
+	a.emit(e.AuctionId, EventOutbid, e)
+}
+
+func (a *AuctionServiceRealtimeAnnouncer) MinimalBidChanged(e AuctionMinimalBidChanged) {
+	a.emit(e.AuctionId, EventMinimalBidChanged, e)
+}
+
+func (a *AuctionServiceRealtimeAnnouncer) ParticipantsChanged(e AuctionParticipantsChanged) {
+	if e.Winners == nil {
+		e.Winners = []models.AuctionDetailWinner{}
+	}
+	a.emit(e.AuctionId, EventParticipantsChanged, e)
+}
+
+func (a *AuctionServiceRealtimeAnnouncer) Finished(e AuctionFinished) {
+	if e.Winners == nil {
+		e.Winners = []models.AuctionDetailWinner{}
+	}
+	a.emit(e.AuctionId, EventAuctionFinished, e)
+}
+
+func (a *AuctionServiceRealtimeAnnouncer) FinishedAtChanged(e AuctionFinishedAtChanged) {
+	a.emit(e.AuctionId, EventFinishedAtChanged, e)
+}
+
+func (a *AuctionServiceRealtimeAnnouncer) Cancelled(e AuctionCancelled) {
+	a.emit(e.AuctionId, EventAuctionCancelled, e)
+}
@@ -0,0 +1,102 @@
+package cashierservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/models"
+	ojoTr "github.com/devzatruk/bizhubBackend/transaction_manager"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrApprovalNotPending = errors.New("Cashier approval not found or already decided.")
+
+// tassyklanmadyk cashier operasiyasyny rejected ya-da expired edyar. Deposit-de wallet
+// uytgemandi, withdraw request bolsa yzyna waiting bolyar, onun wagty eyyam gecen bolsa
+// expired edilip pul balance-a gaytarylyar. RejectCashierApproval we cashier_approval_expired ulanyar.
+func ReleaseCashierApproval(ctx context.Context, approvalId primitive.ObjectID, status string,
+	checkerId *primitive.ObjectID, reason *string) (*models.CashierApproval, error) {
+	var approval models.CashierApproval
+	filter := bson.M{
+		"_id":    approvalId,
+		"status": config.STATUS_PENDING_APPROVAL,
+	}
+	err := config.MI.DB.Collection(config.CASHIER_APPROVALS).FindOne(ctx, filter).Decode(&approval)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrApprovalNotPending
+		}
+		return nil, fmt.Errorf("FindOne(cashier_approval): %v", err)
+	}
+	now := time.Now()
+	transaction_manager := ojoTr.NewTransaction(&ctx, config.MI.DB, 3)
+	tr_approvalsColl := transaction_manager.Collection(config.CASHIER_APPROVALS)
+	_, err = tr_approvalsColl.UpdateOne(ojoTr.NewModel().
+		SetFilter(filter).
+		SetUpdate(bson.M{
+			"$set": bson.M{
+				"status":     status,
+				"checker_id": checkerId,
+				"reason":     reason,
+				"decided_at": now,
+			},
+		}).
+		SetRollbackUpdate(bson.M{
+			"$set": bson.M{
+				"status":     config.STATUS_PENDING_APPROVAL,
+				"checker_id": nil,
+				"reason":     nil,
+				"decided_at": nil,
+			},
+		}))
+	if err != nil {
+		return nil, fmt.Errorf("UpdateOne(cashier_approval): %v", rollbackWith(transaction_manager, err))
+	}
+	approval.Status = status
+	approval.CheckerId = checkerId
+	approval.Reason = reason
+	approval.DecidedAt = &now
+	if approval.Intent != config.INTENT_WITHDRAW || approval.WalletHistoryId == nil {
+		return &approval, nil
+	}
+	tr_whistoryColl := transaction_manager.Collection(config.WALLETHISTORY)
+	_, err = tr_whistoryColl.UpdateOne(ojoTr.NewModel().
+		SetFilter(bson.M{
+			"_id":    *approval.WalletHistoryId,
+			"status": config.STATUS_PENDING_APPROVAL,
+		}).
+		SetUpdate(bson.M{
+			"$set": bson.M{"status": config.STATUS_WAITING},
+		}).
+		SetRollbackUpdate(bson.M{
+			"$set": bson.M{"status": config.STATUS_PENDING_APPROVAL},
+		}))
+	if err != nil {
+		return nil, fmt.Errorf("UpdateOne(wallet_history): %v", rollbackWith(transaction_manager, err))
+	}
+	// withdraw request-in oz wagty gecen bolsa onun cancel_withdraw_action job-y eyyam gecipdir.
+	var history models.MyWalletHistory
+	err = config.MI.DB.Collection(config.WALLETHISTORY).FindOne(ctx, bson.M{"_id": *approval.WalletHistoryId}).Decode(&history)
+	if err != nil {
+		return &approval, nil
+	}
+	if now.Before(history.CreatedAt.Add(config.WithdrawExpiresAfter())) {
+		return &approval, nil
+	}
+	log := cashierLogger.Group("ReleaseCashierApproval()")
+	expired, postResult, err := CancelWithdrawRequest(ctx, history.Id, config.STATUS_EXPIRED)
+	if err != nil {
+		log.Errorf("CancelWithdrawRequest(%v): %v", history.Id.Hex(), err)
+		return &approval, nil
+	}
+	err = NotifyWithdrawExpired(ctx, expired, postResult)
+	if err != nil {
+		log.Errorf("NotifySellers(): %v", err)
+	}
+	return &approval, nil
+}
@@ -0,0 +1,21 @@
+package cashierservice
+
+import (
+	"fmt"
+
+	"github.com/devzatruk/bizhubBackend/ojologger"
+	ojoTr "github.com/devzatruk/bizhubBackend/transaction_manager"
+)
+
+// cashier tassyklamalary we withdraw request-lerini yzyna gaytarmak. Admin/mobile controller-leri
+// we cashier_approval_expired, cancel_withdraw_action job-lary sunu ulanyar.
+
+var cashierLogger = ojologger.LoggerService.Logger("CashierService")
+
+func rollbackWith(transaction_manager *ojoTr.TransactionManager, err error) error {
+	trErr := transaction_manager.Rollback()
+	if trErr != nil {
+		return fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+	}
+	return err
+}
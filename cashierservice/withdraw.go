@@ -0,0 +1,90 @@
+package cashierservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/ledger"
+	"github.com/devzatruk/bizhubBackend/models"
+	notificationmanager "github.com/devzatruk/bizhubBackend/notification_manager"
+	ojoTr "github.com/devzatruk/bizhubBackend/transaction_manager"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrWithdrawNotWaiting = errors.New("Withdraw request not found or not waiting.")
+
+// waiting withdraw request-i status (cancelled ya-da expired) edyar we saklanan pul
+// ledger arkaly balance-a gaytarylyar. CancelWithdraw we cancel_withdraw_action sunu ulanyar.
+// ojocron job-y pozmak caller-in isi.
+func CancelWithdrawRequest(ctx context.Context, transactionId primitive.ObjectID, status string) (*models.MyWalletHistory, *ledger.PostResult, error) {
+	filter := bson.M{
+		"_id":    transactionId,
+		"intent": config.INTENT_WITHDRAW,
+		"status": config.STATUS_WAITING,
+	}
+	var history models.MyWalletHistory
+	err := config.MI.DB.Collection(config.WALLETHISTORY).FindOne(ctx, filter).Decode(&history)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, ErrWithdrawNotWaiting
+		}
+		return nil, nil, fmt.Errorf("FindOne(wallet_history): %v", err)
+	}
+	// status uytgemezden on: yogsa opening_balance-da withdraw_pending yetmez.
+	err = config.Ledger.OpenWallets(ctx, history.SellerId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Ledger.OpenWallets(): %v", err)
+	}
+	transaction_manager := ojoTr.NewTransaction(&ctx, config.MI.DB, 3)
+	whistoryColl := transaction_manager.Collection(config.WALLETHISTORY)
+	now := time.Now()
+	// filter-de status: cashier su wagt toleyan bolsa update bolmaz.
+	_, err = whistoryColl.UpdateOne(ojoTr.NewModel().
+		SetFilter(filter).
+		SetUpdate(bson.M{
+			"$set": bson.M{
+				"status":       status,
+				"completed_at": now,
+			},
+		}).
+		SetRollbackUpdate(bson.M{
+			"$set": bson.M{
+				"status":       config.STATUS_WAITING,
+				"completed_at": nil,
+			},
+		}))
+	if err != nil {
+		return nil, nil, fmt.Errorf("UpdateOne(wallet_history): %v", rollbackWith(transaction_manager, err))
+	}
+	postResult, err := config.Ledger.Post(ctx, ledger.WithdrawCancel(history.SellerId, history.Amount).
+		WithRef(config.WALLETHISTORY, transactionId).
+		WithNote(status))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Ledger.Post(withdraw_cancel): %v", rollbackWith(transaction_manager, err))
+	}
+	// request indi waiting dal: cashier Withdraw() hem kabul etmeyar, code galsa-da zyyany yok.
+	err = config.CodeService.Revoke(ctx, transactionId)
+	if err != nil {
+		cashierLogger.Group("CancelWithdrawRequest()").Errorf("CodeService.Revoke(%v): %v", transactionId.Hex(), err)
+	}
+	history.Status = status
+	history.CompletedAt = &now
+	return &history, postResult, nil
+}
+
+// seller-e withdraw request-in wagty gecip pulun balance-a gaytarylandygy barada.
+func NotifyWithdrawExpired(ctx context.Context, history *models.MyWalletHistory, postResult *ledger.PostResult) error {
+	return config.NotificationManager.NotifySellers(ctx, []primitive.ObjectID{history.SellerId},
+		notificationmanager.EnvTranslation("WithdrawExpiredTitle"),
+		notificationmanager.EnvTranslationf("WithdrawExpired", history.Amount, postResult.NewBalance(history.SellerId)),
+		map[string]string{
+			"type": "wallet",
+			"link": os.Getenv("WalletDeepLink"),
+		})
+}
@@ -0,0 +1,283 @@
+package codeservice
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	CollCodes         = "wallet_codes"
+	CollWalletHistory = "wallet_history"
+)
+
+const (
+	StatusActive   = "active"
+	StatusUsed     = "used"
+	StatusRevoked  = "revoked"
+	StatusLocked   = "locked"                           // synanysyklar gutardy
+	codeAlphabet   = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // O/0, I/1 garysmasyn
+	codeLength     = 6
+	defaultTries   = 5
+	defaultTimeout = time.Hour * 24
+	hashVersion    = 1 // HMAC-SHA256; 0 (field yok): acarsyz sha256
+)
+
+var (
+	ErrCodeNotFound       = errors.New("code: not found")
+	ErrCodeExpired        = errors.New("code: expired")
+	ErrCodeUsed           = errors.New("code: already used")
+	ErrTooManyAttempts    = errors.New("code: too many attempts")
+	ErrCodeNotVerified    = errors.New("code: not verified")
+	ErrCodeAlreadyPending = errors.New("code: wallet_history already has an active code")
+	ErrNoSecret           = errors.New("code: secret not set")
+)
+
+// wallet_history entry-e bagly bir gezeklik code. Code ozi saklanmayar, dine HMAC-y.
+type Code struct {
+	Id              primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	WalletHistoryId primitive.ObjectID `json:"wallet_history_id" bson:"wallet_history_id"`
+	SellerId        primitive.ObjectID `json:"seller_id" bson:"seller_id"`
+	Intent          string             `json:"intent" bson:"intent"`
+	Hash            string             `json:"-" bson:"hash"`
+	HashVersion     int                `json:"-" bson:"hash_version"`
+	Status          string             `json:"status" bson:"status"`
+	Attempts        int64              `json:"attempts" bson:"attempts"`
+	MaxAttempts     int64              `json:"max_attempts" bson:"max_attempts"`
+	ExpiresAt       time.Time          `json:"expires_at" bson:"expires_at"`
+	UsedAt          *time.Time         `json:"used_at" bson:"used_at"`
+	CreatedAt       time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// cashier withdraw code-lary: crypto/rand bilen doredilyar, wagty we
+// synanysyk sany cakli, cashier Code() endpoint-inde ulanylanson gecersiz.
+// Code 6 harp (~2^30 variant), sonun ucin hash acarsyz bolsa offline saylap bolyar: HMAC.
+type CodeService struct {
+	db          *mongo.Database
+	secret      []byte
+	maxAttempts int64
+}
+
+func NewCodeService() *CodeService {
+	return &CodeService{maxAttempts: defaultTries}
+}
+
+func (s *CodeService) Init(db *mongo.Database, secret string) {
+	s.db = db
+	s.secret = []byte(secret)
+}
+
+func (s *CodeService) SetMaxAttempts(maxAttempts int64) *CodeService {
+	s.maxAttempts = maxAttempts
+	return s
+}
+
+func (s *CodeService) coll() *mongo.Collection {
+	return s.db.Collection(CollCodes)
+}
+
+func generate() (string, error) {
+	var builder strings.Builder
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for i := 0; i < codeLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteByte(codeAlphabet[n.Int64()])
+	}
+	return builder.String(), nil
+}
+
+func (s *CodeService) hash(code string) (string, error) {
+	if len(s.secret) == 0 {
+		return "", ErrNoSecret
+	}
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(strings.ToUpper(strings.TrimSpace(code))))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// wallet_history entry ucin taze code. validFor <= 0 bolsa 24 sagat.
+// Gaytarylan code dine seller-e gorkezilyar, bazada dine hash-y bar.
+func (s *CodeService) Issue(ctx context.Context, walletHistoryId primitive.ObjectID, sellerId primitive.ObjectID,
+	intent string, validFor time.Duration) (string, error) {
+	if validFor <= 0 {
+		validFor = defaultTimeout
+	}
+	count, err := s.coll().CountDocuments(ctx, bson.M{
+		"wallet_history_id": walletHistoryId,
+		"status":            StatusActive,
+	})
+	if err != nil {
+		return "", err
+	}
+	if count > 0 {
+		return "", ErrCodeAlreadyPending
+	}
+	code, err := generate()
+	if err != nil {
+		return "", err
+	}
+	codeHash, err := s.hash(code)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	_, err = s.coll().InsertOne(ctx, Code{
+		WalletHistoryId: walletHistoryId,
+		SellerId:        sellerId,
+		Intent:          intent,
+		Hash:            codeHash,
+		HashVersion:     hashVersion,
+		Status:          StatusActive,
+		MaxAttempts:     s.maxAttempts,
+		ExpiresAt:       now.Add(validFor),
+		CreatedAt:       now,
+	})
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// seller-in code-yny barlayar we dogry bolsa ulanylan edyar (ikinji gezek gecmeyar).
+// Yalnys code seller-in ahli active code-larynyn synanysyklaryny artdyryar, MaxAttempts-a
+// yetenler locked bolyar: code-y saylap tapmak mumkin dal.
+func (s *CodeService) Verify(ctx context.Context, sellerId primitive.ObjectID, intent string, code string) (*Code, error) {
+	codeHash, err := s.hash(code)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var found Code
+	err = s.coll().FindOne(ctx, bson.M{
+		"seller_id": sellerId,
+		"intent":    intent,
+		"hash":      codeHash,
+	}, options.FindOne().SetSort(bson.M{"created_at": -1})).Decode(&found)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if err == mongo.ErrNoDocuments || found.Status == StatusRevoked {
+		err = s.failAttempt(ctx, sellerId, intent)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrCodeNotFound
+	}
+	switch {
+	case found.Status == StatusUsed:
+		return nil, ErrCodeUsed
+	case found.Status == StatusLocked || found.Attempts >= found.MaxAttempts:
+		return nil, ErrTooManyAttempts
+	case !now.Before(found.ExpiresAt):
+		return nil, ErrCodeExpired
+	}
+	// filter-de status we attempts: parallel Verify-lardan dine biri gecyar.
+	updateResult, err := s.coll().UpdateOne(ctx, bson.M{
+		"_id":      found.Id,
+		"status":   StatusActive,
+		"attempts": bson.M{"$lt": found.MaxAttempts},
+	}, bson.M{
+		"$set": bson.M{
+			"status":  StatusUsed,
+			"used_at": now,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if updateResult.ModifiedCount == 0 {
+		return nil, ErrCodeUsed
+	}
+	found.Status = StatusUsed
+	found.UsedAt = &now
+	return &found, nil
+}
+
+func (s *CodeService) failAttempt(ctx context.Context, sellerId primitive.ObjectID, intent string) error {
+	filter := bson.M{
+		"seller_id": sellerId,
+		"intent":    intent,
+		"status":    StatusActive,
+	}
+	_, err := s.coll().UpdateMany(ctx, filter, bson.M{"$inc": bson.M{"attempts": 1}})
+	if err != nil {
+		return err
+	}
+	_, err = s.coll().UpdateMany(ctx, bson.M{
+		"seller_id": sellerId,
+		"intent":    intent,
+		"status":    StatusActive,
+		"$expr":     bson.M{"$gte": bson.A{"$attempts", "$max_attempts"}},
+	}, bson.M{"$set": bson.M{"status": StatusLocked}})
+	return err
+}
+
+// seller code-y yitirse: wallet_history-nin active code-lary gecersiz bolyar we expiresAt-a
+// cenli taze code doredilyar.
+func (s *CodeService) Reissue(ctx context.Context, walletHistoryId primitive.ObjectID, sellerId primitive.ObjectID,
+	intent string, expiresAt time.Time) (string, error) {
+	validFor := time.Until(expiresAt)
+	if validFor <= 0 {
+		return "", ErrCodeExpired
+	}
+	err := s.Revoke(ctx, walletHistoryId)
+	if err != nil {
+		return "", err
+	}
+	return s.Issue(ctx, walletHistoryId, sellerId, intent, validFor)
+}
+
+// cashier wallet_history entry-ni tamamlamazdan on code Verify() bilen ulanylan bolmaly.
+func (s *CodeService) EnsureVerified(ctx context.Context, walletHistoryId primitive.ObjectID) error {
+	count, err := s.coll().CountDocuments(ctx, bson.M{
+		"wallet_history_id": walletHistoryId,
+		"status":            StatusUsed,
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrCodeNotVerified
+	}
+	return nil
+}
+
+// wallet_history entry cancel/expired bolanda onun active code-lary gecersiz.
+func (s *CodeService) Revoke(ctx context.Context, walletHistoryId primitive.ObjectID) error {
+	_, err := s.coll().UpdateMany(ctx, bson.M{
+		"wallet_history_id": walletHistoryId,
+		"status":            StatusActive,
+	}, bson.M{"$set": bson.M{"status": StatusRevoked}})
+	return err
+}
+
+// HMAC-dan onki active code-lar (acarsyz sha256) we wallet_history.code-da acyk saklanan code-lar
+// gecersiz bolyar. Waiting withdraw-yn seller-i taze code-y Reissue() bilen alyar. Gaytadan
+// caglanyp bilner: uytgedilen document galmasa hic zat etmeyar.
+func (s *CodeService) RevokeLegacyCodes(ctx context.Context) error {
+	_, err := s.coll().UpdateMany(ctx, bson.M{
+		"status":       StatusActive,
+		"hash_version": bson.M{"$ne": hashVersion},
+	}, bson.M{"$set": bson.M{"status": StatusRevoked}})
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Collection(CollWalletHistory).UpdateMany(ctx, bson.M{
+		"code": bson.M{"$ne": nil},
+	}, bson.M{"$unset": bson.M{"code": ""}})
+	return err
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/auctionservice"
+)
+
+const (
+	AUCTION_REMOVE_AFTER = time.Hour * 24 // auction gutarandan son, su wagtdan son pozulyar
+
+	// auction rules error codes
+	AUCTION_BID_BELOW_INITIAL       = "AUCTION_BID_BELOW_INITIAL"       // ilkinji bid initial_minimal_bid-den pes
+	AUCTION_BID_INCREMENT_TOO_SMALL = "AUCTION_BID_INCREMENT_TOO_SMALL" // minimal_bid + increment-den pes
+	AUCTION_SELLER_TYPE_NOT_ALLOWED = "AUCTION_SELLER_TYPE_NOT_ALLOWED" // seller type rules.seller_types-da yok
+	AUCTION_SELLER_LIMIT_REACHED    = "AUCTION_SELLER_LIMIT_REACHED"    // seller-in active auction-lary rules.max_active_per_seller-e yetdi
+)
+
+var (
+	AuctionService = auctionservice.NewAuctionService()
+
+	AUCTION_SELLER_TYPES = []string{SELLER_TYPE_REGULAR, SELLER_TYPE_MANUFACTURER}
+)
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	CASHIER_APPROVALS                      = "cashier_approvals"
+	CASHIER_APPROVAL_EXPIRED               = "cashier_approval_expired"
+	CASHIER_APPROVAL_THRESHOLD_DEFAULT     = float64(5000)
+	CASHIER_APPROVAL_EXPIRES_AFTER_DEFAULT = time.Hour * 2
+	STATUS_PENDING_APPROVAL                = "pending_approval" // cashier operasiyasy manager-e garasyar
+	STATUS_APPROVED                        = "approved"
+)
+
+// cashier deposit/withdraw-y su rollardan biri tassyklamaly (maker-checker).
+var CASHIER_APPROVER_ROLES = []string{ADMIN, OWNER, EMPLOYEES_MANAGER}
+
+// su mukdardan uly deposit/withdraw pending_approval bolyar.
+// .env-de CASHIER_APPROVAL_THRESHOLD = "5000", 0: ahli operasiyalar tassyklanmaly.
+func CashierApprovalThreshold() float64 {
+	threshold, err := strconv.ParseFloat(os.Getenv("CASHIER_APPROVAL_THRESHOLD"), 64)
+	if err != nil || threshold < 0 {
+		return CASHIER_APPROVAL_THRESHOLD_DEFAULT
+	}
+	return threshold
+}
+
+// tassyklanmadyk operasiya su wagtdan son expired bolyar.
+func CashierApprovalExpiresAfter() time.Duration {
+	expiresAfter, err := time.ParseDuration(os.Getenv("CASHIER_APPROVAL_EXPIRES_AFTER"))
+	if err != nil || expiresAfter <= 0 {
+		return CASHIER_APPROVAL_EXPIRES_AFTER_DEFAULT
+	}
+	return expiresAfter
+}
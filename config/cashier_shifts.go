@@ -0,0 +1,21 @@
+package config
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// bir cashier-de bir wagtda dine bir acyk smena: OpenCashierShift() upsert-i parallel gelse hem.
+func EnsureCashierShiftIndexes(ctx context.Context) error {
+	_, err := MI.DB.Collection(CASHIER_SHIFTS).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "employee_id", Value: 1}},
+		Options: options.Index().
+			SetName("employee_id_open_unique").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"status": STATUS_OPEN}),
+	})
+	return err
+}
@@ -0,0 +1,11 @@
+package config
+
+import "github.com/devzatruk/bizhubBackend/codeservice"
+
+// withdraw code-lary ucin yalnys synanysyk cagi.
+const WALLET_CODE_MAX_ATTEMPTS = 5
+
+// .env-de WALLET_CODE_SECRET: withdraw code-larynyn HMAC acary.
+const WALLET_CODE_SECRET = "WALLET_CODE_SECRET"
+
+var CodeService = codeservice.NewCodeService().SetMaxAttempts(WALLET_CODE_MAX_ATTEMPTS)
@@ -55,6 +55,7 @@ const (
 	FEEDBACKS           = "feedbacks"
 	EVERYDAYWORK        = "everyday_work"
 	CASHIERWORKS        = "cashier_works"
+	CASHIER_SHIFTS      = "cashier_shifts"
 	TRANSFERS           = "transfers"
 	PACKAGEHISTORY      = "package_history"
 	BRANDS              = "brands"
@@ -67,6 +68,8 @@ const (
 	WALLETHISTORY       = "wallet_history"
 	PACKAGES            = "packages"
 	AUCTIONS            = "auctions"
+	AUCTION_PROXY_BIDS  = "auction_proxy_bids"
+	AUCTION_BIDS        = "auction_bids"
 	CRON_JOBS           = "cron_jobs"
 	TASKS               = "tasks"
 	// variables
@@ -76,6 +79,9 @@ const (
 	MIN_PWD_ENTROPY  = float64(50)
 	ACCT_EXPIREDIN   = "ACCESS_TOKEN_EXPIRED_IN"
 	REFT_EXPIREDIN   = "REFRESH_TOKEN_EXPIRED_IN"
+	// auction bids
+	AUCTION_MIN_BID_STEP     = float64(1) // yenmek ucin minimal_bid-den azyndan su mocberde kop bolmaly
+	AUCTION_MAX_PROXY_ROUNDS = 50         // awtomatik bid-lerin bir zynjyrynda in kop aylaw
 	// token keys
 	ACCT_PRIVATE_KEY = "ACCESS_TOKEN_PRIVATE_KEY"
 	ACCT_PUBLIC_KEY  = "ACCESS_TOKEN_PUBLIC_KEY"
@@ -102,6 +108,7 @@ const (
 	INTENT_WITHDRAW = "withdraw"
 	INTENT_PAYMENT  = "payment"
 	INTENT_DEPOSIT  = "deposit"
+	INTENT_REFUND   = "refund"
 	// statuses
 	STATUS_WAITING   = "waiting"
 	STATUS_RECEIVED  = "received"
@@ -116,6 +123,8 @@ const (
 	STATUS_ACTIVE    = "active"
 	STATUS_CLOSED    = "closed"
 	STATUS_BLOCKED   = "blocked"
+	STATUS_HIDDEN    = "hidden" // package gutaranda basic-den artyk product-lar
+	STATUS_OPEN      = "open"   // cashier smenasy
 	// package payment actions
 	PACKAGE_PAY    = "pay"
 	PACKAGE_CHANGE = "changed"
@@ -146,7 +155,9 @@ const (
 	AUCTION_FINISHED       = "auction_finished"
 	AUCTION_REMOVED        = "auction_removed"
 	ADD_DISCOUNT           = "add_discount"
-	REMOVE_DISCOUNT        = "remove_discount"
+	REMOVE_DISCOUNT        = "remove_discount" // kone job-lar, DISCOUNT_REMOVED bilen birmenzes islenyar
+	DISCOUNT_STARTED       = "discount_started"
+	DISCOUNT_REMOVED       = "discount_removed"
 	CANCEL_WITHDRAW_ACTION = "cancel_withdraw_action"
 	// discount types
 	DISCOUNT_PERCENT = "percent"
@@ -155,6 +166,9 @@ const (
 	DURATION_HOUR  = "hour"
 	DURATION_DAY   = "day"
 	DURATION_MONTH = "month"
+	// discount window: in uzyn dowamy we in uzak start (gun)
+	DISCOUNT_MAX_DAYS       = 60
+	DISCOUNT_MAX_START_DAYS = 60
 
 	// employee job names, roles
 	ADMIN             = "admin"
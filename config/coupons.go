@@ -0,0 +1,31 @@
+package config
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	COUPONS            = "coupons"
+	COUPON_REDEMPTIONS = "coupon_redemptions"
+)
+
+// max_per_seller: seller-in her ulanysy oz nomeri (n) bilen, parallel tolegler sol bir n-i alyp bilmeyar.
+// n dine max_per_seller bar bolsa yazylyar.
+func EnsureCouponIndexes(ctx context.Context) error {
+	_, err := MI.DB.Collection(COUPON_REDEMPTIONS).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "coupon_id", Value: 1},
+			{Key: "seller_id", Value: 1},
+			{Key: "n", Value: 1},
+		},
+		Options: options.Index().
+			SetName("coupon_id_seller_id_n_unique").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"n": bson.M{"$gt": 0}}),
+	})
+	return err
+}
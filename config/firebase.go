@@ -21,7 +21,12 @@ func SetupFirebase() *firebase.App {
 		panic(fmt.Sprintf("firebase current path error : %v", err))
 	}
 
-	opt := option.WithCredentialsFile(path.Join(currentPath, "config", "firebase-config.json"))
+	credentialsFile := path.Join(currentPath, "config", "firebase-config.json")
+	// go test paket papkasynda isleyar, sonun ucin file yoly env-den hem berlip bilner
+	if envFile := os.Getenv("FIREBASE_CREDENTIALS"); len(envFile) > 0 {
+		credentialsFile = envFile
+	}
+	opt := option.WithCredentialsFile(credentialsFile)
 	app, err := firebase.NewApp(context.Background(), nil, opt)
 
 	if err != nil {
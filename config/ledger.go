@@ -0,0 +1,38 @@
+package config
+
+import (
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/ledger"
+)
+
+const (
+	// wallet reconciliation: ojocron listener ady we job group-y
+	WALLET_RECONCILIATION       = "wallet_reconciliation"
+	WALLET_RECONCILIATION_EVERY = time.Hour * 24
+	WALLET_RECONCILIATION_HOUR  = 3 // gijan 03:00-da, bid-ler az wagty
+	WALLET_RECONCILIATIONS      = "wallet_reconciliations"
+	WALLET_DRIFTS               = "wallet_drifts"
+	INTENT_CORRECTION           = "correction"
+	// reconciliation statuslary
+	RECONCILIATION_RUNNING   = "running"
+	RECONCILIATION_COMPLETED = "completed"
+	RECONCILIATION_FAILED    = "failed"
+	// drift statuslary
+	DRIFT_OPEN     = "open"
+	DRIFT_FIXED    = "fixed"
+	DRIFT_RESOLVED = "resolved" // fix wagtynda tapawut eyyam yok eken
+	// drift gornusleri
+	DRIFT_BALANCE  = "balance"  // wallets.balance != wallet_history-den hasaplanan
+	DRIFT_HOLDS    = "holds"    // wallets.in_auction != acyk auction-laryn winners[]
+	DRIFT_LEDGER   = "ledger"   // wallets.balance, in_auction ya-da berilmedik withdraw-lar != ledger journal
+	DRIFT_DEPOSITS = "deposits" // deposit wallet_history != cashier_works
+	DRIFT_PAYOUTS  = "payouts"  // tamamlanan withdraw wallet_history != cashier_works
+)
+
+var (
+	Ledger = ledger.NewLedger()
+
+	// dine su gornusler correction bilen duzedilip bilner, beylekiler dine report.
+	FIXABLE_DRIFTS = []string{DRIFT_BALANCE, DRIFT_HOLDS}
+)
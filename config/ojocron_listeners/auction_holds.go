@@ -0,0 +1,113 @@
+package ojocronlisteners
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/ledger"
+	"github.com/devzatruk/bizhubBackend/models"
+	ojoTr "github.com/devzatruk/bizhubBackend/transaction_manager"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// auction ucin saklanan pullary (wallets.in_auction[]) winner-lere gaytaryar,
+// her gaytarma wallet_history-a refund bolup yazylyar, balance bolsa ledger-de
+// bir auction_release entry bilen (in sonky adim) gaytarylyar.
+// Rollback filter-i hem seller_id, sonun ucin in_auction.auction_id filter-e goyulmady.
+// Yalnys bolsa Rollback() caller-in isi.
+func ReleaseAuctionHolds(ctx context.Context, transaction_manager *ojoTr.TransactionManager, auctionId primitive.ObjectID,
+	heading models.Translation, winners []models.AuctionDetailNewWinner, note models.Translation) error {
+	walletsColl := config.MI.DB.Collection(config.WALLETS)
+	tr_walletsColl := transaction_manager.Collection(config.WALLETS)
+	tr_walletHistoryColl := transaction_manager.Collection(config.WALLETHISTORY)
+	tr_sellersColl := transaction_manager.Collection(config.SELLERS)
+	now := time.Now()
+	holds := make([]ledger.Hold, 0, len(winners))
+	sellerIds := make([]primitive.ObjectID, 0, len(winners))
+	for _, winner := range winners {
+		sellerIds = append(sellerIds, winner.SellerId)
+	}
+	// in_auction[] pull edilmezden on, yogsa opening_balance-da held yetmez.
+	err := config.Ledger.OpenWallets(ctx, sellerIds...)
+	if err != nil {
+		return fmt.Errorf("Ledger.OpenWallets(): %v", err)
+	}
+	for _, winner := range winners {
+		holds = append(holds, ledger.Hold{SellerId: winner.SellerId, Amount: winner.LastBid})
+		var wallet models.SellerWallet
+		err := walletsColl.FindOne(ctx, bson.M{"seller_id": winner.SellerId}).Decode(&wallet)
+		if err != nil {
+			return fmt.Errorf("FindOne(wallet): %v", err)
+		}
+		update_model_wallet := ojoTr.NewModel().
+			SetFilter(bson.M{"seller_id": winner.SellerId}).
+			SetUpdate(bson.M{
+				"$pull": bson.M{
+					"in_auction": bson.M{"auction_id": auctionId},
+				},
+			}).
+			SetRollbackUpdate(bson.M{
+				"$push": bson.M{
+					"in_auction": fiber.Map{
+						"auction_id": auctionId,
+						"amount":     winner.LastBid,
+						"name":       heading,
+					},
+				},
+			})
+		_, err = tr_walletsColl.UpdateOne(update_model_wallet)
+		if err != nil {
+			return fmt.Errorf("UpdateOne(wallet): %v", err)
+		}
+		wh := models.MyWalletHistory{
+			SellerId:    winner.SellerId,
+			WalletId:    wallet.Id,
+			OldBalance:  wallet.Balance,
+			Amount:      winner.LastBid,
+			Intent:      config.INTENT_REFUND,
+			Note:        &note,
+			Code:        nil,
+			Status:      config.STATUS_COMPLETED,
+			CompletedAt: &now,
+			CreatedAt:   now,
+			EmployeeId:  nil,
+		}
+		insertResult, err := tr_walletHistoryColl.InsertOne(ojoTr.NewModel().SetDocument(wh))
+		if err != nil {
+			return fmt.Errorf("InsertOne(wallet_history): %v", err)
+		}
+		_, err = tr_sellersColl.UpdateOne(ojoTr.NewModel().
+			SetFilter(bson.M{"_id": winner.SellerId}).
+			SetUpdate(bson.M{
+				"$push": bson.M{
+					"transfers": bson.M{
+						"$each":     bson.A{insertResult.InsertedID.(primitive.ObjectID)},
+						"$slice":    2,
+						"$position": 0,
+					},
+				},
+			}).
+			SetRollbackUpdate(bson.M{
+				"$pull": bson.M{
+					"transfers": insertResult.InsertedID.(primitive.ObjectID),
+				},
+			}))
+		if err != nil {
+			return fmt.Errorf("UpdateOne(seller.transfers[]): %v", err)
+		}
+	}
+	if len(holds) == 0 {
+		return nil
+	}
+	_, err = config.Ledger.Post(ctx, ledger.AuctionRelease(holds...).
+		WithRef(config.AUCTIONS, auctionId).
+		WithNote(note.En))
+	if err != nil {
+		return fmt.Errorf("Ledger.Post(auction_release): %v", err)
+	}
+	return nil
+}
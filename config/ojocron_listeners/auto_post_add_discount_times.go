@@ -2,13 +2,13 @@ package ojocronlisteners
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"math"
 	"os"
 	"time"
 
 	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/helpers"
 	"github.com/devzatruk/bizhubBackend/models"
 	"github.com/devzatruk/bizhubBackend/ojocronservice"
 	"github.com/devzatruk/bizhubBackend/ojologger"
@@ -91,28 +91,23 @@ func getProductForDiscount(id primitive.ObjectID) (*ProductForDiscount, error) {
 }
 
 func ScheduleAutoPostAddDiscountTimes(duration int64, durationType string, productObjId primitive.ObjectID) error {
-	// fmt.Printf("\n*******discount-schedule*******\n")
 	now := time.Now()
-
-	var endDate time.Time
-
-	duration_ := time.Duration(duration)
-	day_ := time.Hour * 24
-	month_ := day_ * 30
-
-	if durationType == "hour" {
-		endDate = now.Add(time.Hour * duration_)
-	} else if durationType == "day" {
-		endDate = now.Add(day_ * duration_)
-	} else if durationType == "month" {
-		endDate = now.Add(month_ * duration_)
-	} else {
-		return errors.New("invalid duration type")
+	endDate, err := helpers.DiscountEndDate(now, duration, durationType)
+	if err != nil {
+		return err
 	}
 	max_last_day := now.Add(time.Hour * 24 * 60) // 2 aydan kop discount edip bilenoklar!!!
 	if endDate.After(max_last_day) {
 		endDate = max_last_day
 	}
+	return scheduleDiscountJobs(productObjId, primitive.NilObjectID, endDate)
+}
+
+// endDate-a cenli auto post-lar (her 2 gun, -3h, -1h) we endDate-de discount_removed job.
+// discountId job-lary sol discount-a baglayar: discount calysylsa kone job-lar hic zat etmeyar.
+func scheduleDiscountJobs(productObjId primitive.ObjectID, discountId primitive.ObjectID, endDate time.Time) error {
+	// fmt.Printf("\n*******discount-schedule*******\n")
+	now := time.Now()
 	// start
 
 	times := []DiscountTime{}
@@ -137,10 +132,7 @@ func ScheduleAutoPostAddDiscountTimes(duration int64, durationType string, produ
 	// fmt.Printf("\ntimes: %v\n", times)
 	// fmt.Printf("\ntimes length: %v\n", len(times))
 
-	if len(times) == 0 {
-		return errors.New("gygyrmaly wagt yogey")
-	}
-
+	// gygyrmaly wagt yok bolsa hem discount_removed job goyulmaly
 	product, err := getProductForDiscount(productObjId)
 	// fmt.Printf("\nproduct: %v\n", product)
 	if err != nil {
@@ -195,15 +187,19 @@ func ScheduleAutoPostAddDiscountTimes(duration int64, durationType string, produ
 		cronModel.Group(productObjId)
 		cronModel.Payload(payload)
 		cronModel.RunAt(discountTime.Time)
-		config.OjoCronService.NewJob(cronModel)
+		err = config.OjoCronService.NewJob(cronModel)
+		if err != nil {
+			return err
+		}
 		// fmt.Printf("\ndiscount time-i schedule edildi\n")
 	}
 
 	removeJobModel := ojocronservice.NewOjoCronJobModel()
 	removeJobModel.Group(productObjId)
-	removeJobModel.ListenerName(config.REMOVE_DISCOUNT)
+	removeJobModel.ListenerName(config.DISCOUNT_REMOVED)
 	removeJobModel.Payload(map[string]interface{}{
-		"product_id": productObjId,
+		"product_id":  productObjId,
+		"discount_id": discountId,
 		"headings": map[string]any{
 			"en": fmt.Sprintf(os.Getenv("AnnounceEnRem"), product.Seller.Name,
 				product.Discount, product.Name.En),
@@ -216,10 +212,10 @@ func ScheduleAutoPostAddDiscountTimes(duration int64, durationType string, produ
 		},
 	})
 	removeJobModel.RunAt(endDate)
-	config.OjoCronService.NewJob(removeJobModel)
+	err = config.OjoCronService.NewJob(removeJobModel)
 
 	// fmt.Printf("\n*******discount-schedule*******\n")
-	return nil
+	return err
 
 }
 
@@ -14,16 +14,15 @@ import (
 )
 
 type ProductPayload struct {
-	ProductId primitive.ObjectID `mapstructure:"product_id"`
-	Headings  models.Translation `mapstructure:"headings"`
+	ProductId  primitive.ObjectID `mapstructure:"product_id"`
+	Headings   models.Translation `mapstructure:"headings"`
+	DiscountId primitive.ObjectID `mapstructure:"discount_id"` // kone job-larda yok
 }
 
 func AutoPostDiscountRemoved(job *ojocronservice.OjoCronJob) {
 	logger := ojologger.LoggerService.Logger("AddOjoCronListeners()")
 	log := logger.Group("autoPostDiscountRemoved()")
 
-	// ilki cron_jobs-dan bar bolsa ayyr
-
 	var payload ProductPayload
 
 	err := mapstructure.Decode(job.Payload, &payload)
@@ -35,16 +34,14 @@ func AutoPostDiscountRemoved(job *ojocronservice.OjoCronJob) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	err = config.OjoCronService.RemoveJobsByGroup(job.Group)
-	if err != nil {
-		log.Errorf("Couldn't delete from cron jobs...")
-		job.Failed()
-		return
-	}
 	productsColl := config.MI.DB.Collection(config.PRODUCTS)
 
 	// indi products collection-da product-y tapyp, discount = 0 etmeli, discountDetails =nil etmeli
-	_, err = productsColl.UpdateOne(ctx, bson.M{"_id": payload.ProductId}, bson.M{
+	filter := bson.M{"_id": payload.ProductId}
+	if payload.DiscountId != primitive.NilObjectID {
+		filter["discount_data._id"] = payload.DiscountId
+	}
+	updateResult, err := productsColl.UpdateOne(ctx, filter, bson.M{
 		"$set": bson.M{
 			"discount":      0,
 			"discount_data": nil,
@@ -55,6 +52,19 @@ func AutoPostDiscountRemoved(job *ojocronservice.OjoCronJob) {
 		log.Log("AutoPost update product failed.")
 		return
 	}
+	if updateResult.MatchedCount == 0 {
+		// discount eyyam calysyldy ya-da ayryldy: taze discount-yn job-lary galmaly
+		log.Logf("Discount %v of product %v not active, skipped.", payload.DiscountId.Hex(), payload.ProductId.Hex())
+		job.Finish()
+		return
+	}
+	// dine auto post-lar: garasyan discount_started job galmaly
+	err = config.OjoCronService.RemoveJobsByGroupListeners(job.Group, config.ADD_DISCOUNT)
+	if err != nil {
+		log.Errorf("Couldn't delete from cron jobs...")
+		job.Failed()
+		return
+	}
 
 	log.Logf("Product discount removed...")
 
@@ -0,0 +1,32 @@
+package ojocronlisteners
+
+import (
+	"context"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/cashierservice"
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/ojocronservice"
+	"github.com/devzatruk/bizhubBackend/ojologger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func HandleCashierApprovalExpired(job *ojocronservice.OjoCronJob) {
+	log := ojologger.LoggerService.Logger("AddOjoCronListeners()").Group("HandleCashierApprovalExpired()")
+	approvalId := job.Payload["approval_id"].(primitive.ObjectID)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	approval, err := cashierservice.ReleaseCashierApproval(ctx, approvalId, config.STATUS_EXPIRED, nil, nil)
+	if err != nil {
+		if err == cashierservice.ErrApprovalNotPending {
+			// eyyam tassyklandy ya-da ret edildi.
+			job.Finish()
+			return
+		}
+		log.Errorf("ReleaseCashierApproval(%v): %v", approvalId.Hex(), err)
+		job.Failed()
+		return
+	}
+	job.Finish()
+	log.Logf("Cashier approval %v (%v %v) expired.", approvalId.Hex(), approval.Intent, approval.Amount)
+}
@@ -0,0 +1,221 @@
+package ojocronlisteners
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/helpers"
+	"github.com/devzatruk/bizhubBackend/models"
+	"github.com/devzatruk/bizhubBackend/ojocronservice"
+	"github.com/devzatruk/bizhubBackend/ojologger"
+	"github.com/mitchellh/mapstructure"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	errDiscountReplaced = errors.New("Scheduled discount replaced.")
+	errDiscountPrice    = errors.New("Discount not valid for product price.")
+)
+
+// SetProductDiscount-dan: starts_at gelen bolsa discount hazir ulanylyar, bolmasa scheduled_discount-da
+// garasyar we discount_started job ony basladyar. Onki job-lar taze job-lar goyulandan son pozulyar:
+// yalnys bolsa product onki discount-y we onun job-lary bilen galyar.
+func ScheduleProductDiscount(ctx context.Context, productId primitive.ObjectID, data models.DiscountData) error {
+	data.Id = primitive.NewObjectID()
+	if !data.StartsAt.After(time.Now()) {
+		return replaceProductDiscount(ctx, productId, data)
+	}
+	// hazirki discount-yn job-lary (kone discount-larda remove_discount hem) galyar: ol taze discount
+	// baslamanka gutarsa oz job-y bilen gutaryar, gutarmasa activateProductDiscount() olary pozyar.
+	// dine onki scheduled discount-yn discount_started job-y calysylyar.
+	oldJobIds, err := config.OjoCronService.JobIdsByGroup(productId, config.DISCOUNT_STARTED)
+	if err != nil {
+		return fmt.Errorf("JobIdsByGroup(): %v", err)
+	}
+	productsColl := config.MI.DB.Collection(config.PRODUCTS)
+	var old struct {
+		ScheduledDiscount *models.DiscountData `bson:"scheduled_discount"`
+	}
+	err = productsColl.FindOneAndUpdate(ctx, bson.M{"_id": productId}, bson.M{
+		"$set": bson.M{
+			"scheduled_discount": data,
+		},
+	}, options.FindOneAndUpdate().SetProjection(bson.M{"scheduled_discount": 1})).Decode(&old)
+	if err != nil {
+		return fmt.Errorf("FindOneAndUpdate(scheduled_discount): %v", err)
+	}
+	err = config.OjoCronService.NewJob(ojocronservice.NewOjoCronJobModel().
+		ListenerName(config.DISCOUNT_STARTED).
+		Group(productId).
+		Payload(map[string]interface{}{
+			"product_id":  productId,
+			"discount_id": data.Id,
+		}).
+		RunAt(*data.StartsAt))
+	if err != nil {
+		// job-syz scheduled_discount hic hacan baslamaz: onkisi (onun job-y entek bar) gaytarylyar.
+		restore := bson.M{"$unset": bson.M{"scheduled_discount": ""}}
+		if old.ScheduledDiscount != nil {
+			restore = bson.M{"$set": bson.M{"scheduled_discount": old.ScheduledDiscount}}
+		}
+		productsColl.UpdateOne(ctx, bson.M{"_id": productId}, restore)
+		return fmt.Errorf("NewJob(discount_started): %v", err)
+	}
+	// galsa-da zyyany yok: HandleDiscountStarted scheduled_discount._id-ni barlayar.
+	config.OjoCronService.RemoveJobsByIds(oldJobIds)
+	return nil
+}
+
+// discount hazir ulanylyar. Onki discount-yn (we scheduled discount-yn) job-lary taze discount-yn
+// job-lary goyulandan son pozulyar; yalnys bolsa product onki discount-laryna gaytarylyar.
+func replaceProductDiscount(ctx context.Context, productId primitive.ObjectID, data models.DiscountData) error {
+	oldJobIds, err := config.OjoCronService.JobIdsByGroup(productId)
+	if err != nil {
+		return fmt.Errorf("JobIdsByGroup(): %v", err)
+	}
+	productsColl := config.MI.DB.Collection(config.PRODUCTS)
+	var old struct {
+		Discount          float64              `bson:"discount"`
+		DiscountData      *models.DiscountData `bson:"discount_data"`
+		ScheduledDiscount *models.DiscountData `bson:"scheduled_discount"`
+	}
+	err = productsColl.FindOne(ctx, bson.M{"_id": productId}, options.FindOne().SetProjection(bson.M{
+		"discount":           1,
+		"discount_data":      1,
+		"scheduled_discount": 1,
+	})).Decode(&old)
+	if err != nil {
+		return fmt.Errorf("FindOne(product): %v", err)
+	}
+	err = activateProductDiscount(ctx, productId, data, false)
+	if err != nil {
+		restore := bson.M{
+			"$set": bson.M{
+				"discount":      old.Discount,
+				"discount_data": old.DiscountData,
+			},
+		}
+		if old.ScheduledDiscount != nil {
+			restore["$set"].(bson.M)["scheduled_discount"] = old.ScheduledDiscount
+		}
+		_, restoreErr := productsColl.UpdateOne(ctx, bson.M{"_id": productId}, restore)
+		if restoreErr != nil {
+			err = fmt.Errorf("%w - Restore: %v", err, restoreErr)
+		}
+		jobErr := config.OjoCronService.RemoveJobsByGroupExcept(productId, oldJobIds)
+		if jobErr != nil {
+			err = fmt.Errorf("%w - RemoveJobsByGroupExcept: %v", err, jobErr)
+		}
+		return err
+	}
+	// discount eyyam ulanyldy: kone job-lar pozulmasa-da netije yalnys dal.
+	err = config.OjoCronService.RemoveJobsByIds(oldJobIds)
+	if err != nil {
+		ojologger.LoggerService.Logger("AddOjoCronListeners()").Group("ScheduleProductDiscount()").Errorf("RemoveJobsByIds(%v): %v", productId.Hex(), err)
+	}
+	return nil
+}
+
+// discount product-yn su wagtky bahasyna gora hasaplanyp ulanylyar, scheduled_discount ayrylyar.
+// scheduled=true bolsa dine scheduled_discount entek sol discount bolsa ulanylyar.
+func activateProductDiscount(ctx context.Context, productId primitive.ObjectID, data models.DiscountData, scheduled bool) error {
+	productsColl := config.MI.DB.Collection(config.PRODUCTS)
+	filter := bson.M{"_id": productId}
+	if scheduled {
+		filter["scheduled_discount._id"] = data.Id
+	}
+	var product struct {
+		Price float64 `bson:"price"`
+	}
+	err := productsColl.FindOne(ctx, filter, options.FindOne().SetProjection(bson.M{"price": 1})).Decode(&product)
+	if err == mongo.ErrNoDocuments && scheduled {
+		return errDiscountReplaced
+	}
+	if err != nil {
+		return fmt.Errorf("FindOne(product): %v", err)
+	}
+	err = helpers.ApplyDiscountPrice(&data, product.Price)
+	if err != nil {
+		if scheduled {
+			productsColl.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"scheduled_discount": ""}})
+		}
+		return fmt.Errorf("%w %v", errDiscountPrice, err)
+	}
+	updateResult, err := productsColl.UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{
+			"discount":      data.Percent,
+			"discount_data": data,
+		},
+		"$unset": bson.M{
+			"scheduled_discount": "",
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("UpdateOne(discount): %v", err)
+	}
+	if scheduled {
+		if updateResult.MatchedCount == 0 {
+			return errDiscountReplaced
+		}
+		// onki discount-yn auto post-lary we discount_removed job-y taze discount-a degmesin
+		err = config.OjoCronService.RemoveJobsByGroupListeners(productId, config.ADD_DISCOUNT, config.DISCOUNT_REMOVED, config.REMOVE_DISCOUNT)
+		if err != nil {
+			return fmt.Errorf("RemoveJobsByGroupListeners(): %v", err)
+		}
+	}
+	return scheduleDiscountJobs(productId, data.Id, *data.EndsAt)
+}
+
+func HandleDiscountStarted(job *ojocronservice.OjoCronJob) {
+	log := ojologger.LoggerService.Logger("AddOjoCronListeners()").Group("HandleDiscountStarted()")
+	var payload ProductPayload
+	err := mapstructure.Decode(job.Payload, &payload)
+	if err != nil {
+		log.Error(err)
+		job.Failed()
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var product struct {
+		ScheduledDiscount *models.DiscountData `bson:"scheduled_discount"`
+	}
+	err = config.MI.DB.Collection(config.PRODUCTS).FindOne(ctx, bson.M{
+		"_id":                    payload.ProductId,
+		"scheduled_discount._id": payload.DiscountId,
+	}).Decode(&product)
+	if err == mongo.ErrNoDocuments || (err == nil && product.ScheduledDiscount == nil) {
+		// discount calysyldy ya-da yatyryldy
+		job.Finish()
+		return
+	}
+	if err != nil {
+		log.Errorf("FindOne(product %v): %v", payload.ProductId.Hex(), err)
+		job.Failed()
+		return
+	}
+	err = activateProductDiscount(ctx, payload.ProductId, *product.ScheduledDiscount, true)
+	if err == errDiscountReplaced {
+		job.Finish()
+		return
+	}
+	if errors.Is(err, errDiscountPrice) {
+		// product bahasy uytgedi: discount ulanylmady, gaytalamagyn peydasy yok
+		log.Errorf("Product %v: %v", payload.ProductId.Hex(), err)
+		job.Finish()
+		return
+	}
+	if err != nil {
+		log.Errorf("activateProductDiscount(%v): %v", payload.ProductId.Hex(), err)
+		job.Failed()
+		return
+	}
+	job.Finish()
+	log.Logf("Product %v discount %v started.", payload.ProductId.Hex(), payload.DiscountId.Hex())
+}
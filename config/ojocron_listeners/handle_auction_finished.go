@@ -6,7 +6,9 @@ import (
 	"os"
 	"time"
 
+	"github.com/devzatruk/bizhubBackend/auctionservice"
 	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/ledger"
 	"github.com/devzatruk/bizhubBackend/models"
 	notificationmanager "github.com/devzatruk/bizhubBackend/notification_manager"
 	"github.com/devzatruk/bizhubBackend/ojocronservice"
@@ -37,8 +39,23 @@ func HandleAuctionFinished(job *ojocronservice.OjoCronJob) {
 	// ???
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	// begin transaction
 	now := time.Now()
+	var auctionTimes struct {
+		FinishedAt time.Time `bson:"finished_at"`
+	}
+	err := config.MI.DB.Collection(config.AUCTIONS).FindOne(ctx, bson.M{"_id": auctionId}).Decode(&auctionTimes)
+	if err != nil {
+		log.Errorf("FindOne(auction): %v - %v", err, config.NOT_FOUND)
+		job.Failed()
+		return
+	}
+	if auctionTimes.FinishedAt.After(now) {
+		// anti-sniping bilen uzaldyldy, job finished_at gecenden son tazeden isledilyar.
+		log.Logf("Auction %v extended until %v, retrying later.", auctionId, auctionTimes.FinishedAt)
+		job.Retry()
+		return
+	}
+	// begin transaction
 	transaction_manager := ojoTr.NewTransaction(&ctx, config.MI.DB, 3)
 	tr_auctionsColl := transaction_manager.Collection(config.AUCTIONS)
 	update_model_auc := ojoTr.NewModel().
@@ -76,6 +93,29 @@ func HandleAuctionFinished(job *ojocronservice.OjoCronJob) {
 		job.Failed()
 		return
 	}
+	highestBid := float64(0)
+	if len(oldAuctionData.Winners) > 0 {
+		highestBid = oldAuctionData.Winners[0].LastBid
+	}
+	reserveMet := oldAuctionData.Rules.ReserveMet(highestBid)
+	_, err = tr_auctionsColl.UpdateOne(ojoTr.NewModel().
+		SetFilter(bson.M{"_id": auctionId}).
+		SetUpdate(bson.M{"$set": bson.M{"reserve_met": reserveMet}}).
+		SetRollbackUpdate(bson.M{"$unset": bson.M{"reserve_met": ""}}))
+	if err != nil {
+		trErr := transaction_manager.Rollback()
+		if trErr != nil {
+			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+		}
+		log.Errorf("UpdateOne(auction.reserve_met): %v - %v", err, config.CANT_UPDATE)
+		job.Failed()
+		return
+	}
+	if !reserveMet {
+		// reserve_price-a yetilmedi: hic kim yenmeyar, ahli saklanan pullar gaytarylyar.
+		handleAuctionReserveNotMet(ctx, job, transaction_manager, auctionId, oldAuctionData)
+		return
+	}
 	// var winnerModel models.AuctionDetailNewWinner
 	tr_walletsColl := transaction_manager.Collection(config.WALLETS)
 	tr_walletHistoryColl := transaction_manager.Collection(config.WALLETHISTORY)
@@ -86,9 +126,22 @@ func HandleAuctionFinished(job *ojocronservice.OjoCronJob) {
 		Tr: os.Getenv("AuctionFinishedTr"),
 		Ru: os.Getenv("AuctionFinishedRu"),
 	}
-	var winnerIdsForNotificationService = make([]primitive.ObjectID, 0)
+	// ledger wallet-leri in_auction[] pull edilmezden on acylyar, yogsa opening_balance-da held yetmez.
+	winnerIds := make([]primitive.ObjectID, 0, len(oldAuctionData.Winners))
+	for _, winner := range oldAuctionData.Winners {
+		winnerIds = append(winnerIds, winner.SellerId)
+	}
+	err = config.Ledger.OpenWallets(ctx, winnerIds...)
+	if err != nil {
+		trErr := transaction_manager.Rollback()
+		if trErr != nil {
+			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+		}
+		log.Errorf("Ledger.OpenWallets(): %v - %v", err, config.CANT_UPDATE)
+		job.Failed()
+		return
+	}
 	for _, winner := range oldAuctionData.Winners {
-		winnerIdsForNotificationService = append(winnerIdsForNotificationService, winner.SellerId)
 		update_model_wallet := ojoTr.NewModel().
 			SetFilter(bson.M{"seller_id": winner.SellerId}).
 			SetUpdate(bson.M{"$pull": bson.M{"in_auction": bson.M{"auction_id": auctionId}}}).
@@ -180,16 +233,74 @@ func HandleAuctionFinished(job *ojocronservice.OjoCronJob) {
 			return
 		}
 	}
-	if len(winnerIdsForNotificationService) > 0 {
-		// SendNotificationToWinners(winnerIdsForNotificationService)
-		config.NotificationManager.AddNotificationEvent(&notificationmanager.NotificationEvent{
-			Title:       "Buşluk! Auksionda yeňiji bolduňyz!",
-			Description: fmt.Sprintf("Auksion: %v", oldAuctionData.Heading.Tm),
-			ClientType: notificationmanager.NotificationEventClientType{
-				Sellers: true,
-			},
-			ClientIds: winnerIdsForNotificationService,
-		})
+	// saklanan pullar platforma gecyar: ledger-de auction_capture, in sonky adim.
+	if len(oldAuctionData.Winners) > 0 {
+		holds := make([]ledger.Hold, 0, len(oldAuctionData.Winners))
+		for _, winner := range oldAuctionData.Winners {
+			holds = append(holds, ledger.Hold{SellerId: winner.SellerId, Amount: winner.LastBid})
+		}
+		_, err = config.Ledger.Post(ctx, ledger.AuctionCapture(holds...).
+			WithRef(config.AUCTIONS, auctionId).
+			WithNote(walletHistoryNote.En))
+		if err != nil {
+			trErr := transaction_manager.Rollback()
+			if trErr != nil {
+				err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+			}
+			log.Errorf("Ledger.Post(auction_capture): %v - %v", err, config.CANT_INSERT)
+			job.Failed()
+			return
+		}
+	}
+	_, finishedWinners, err := config.AuctionService.Winners(ctx, auctionId)
+	if err != nil {
+		log.Errorf("Winners(): %v", err)
+	}
+	config.AuctionService.Realtime.Finished(auctionservice.AuctionFinished{
+		AuctionId: auctionId,
+		Winners:   finishedWinners,
+	})
+	// her winner-e oz bahasy bilen
+	for _, winner := range oldAuctionData.Winners {
+		err = config.NotificationManager.NotifyAuctionSellers(ctx, auctionId, []primitive.ObjectID{winner.SellerId}, "AuctionWonTitle",
+			notificationmanager.AuctionTranslation("AuctionWon", oldAuctionData.Heading, winner.LastBid))
+		if err != nil {
+			log.Errorf("NotifyAuctionSellers(won): %v", err)
+		}
+	}
+	job.Finish()
+}
+
+func handleAuctionReserveNotMet(ctx context.Context, job *ojocronservice.OjoCronJob, transaction_manager *ojoTr.TransactionManager,
+	auctionId primitive.ObjectID, auction models.NewAuction) {
+	log := ojologger.LoggerService.Logger("AddOjoCronListeners()").Group("handleAuctionReserveNotMet()")
+	refundNote := notificationmanager.AuctionTranslation("AuctionReserveNotMetRefund", auction.Heading)
+	err := ReleaseAuctionHolds(ctx, transaction_manager, auctionId, auction.Heading, auction.Winners, refundNote)
+	if err != nil {
+		trErr := transaction_manager.Rollback()
+		if trErr != nil {
+			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+		}
+		log.Errorf("ReleaseAuctionHolds(): %v - %v", err, config.CANT_UPDATE)
+		job.Failed()
+		return
+	}
+	note := refundNote.En
+	sellerIds := make([]primitive.ObjectID, 0, len(auction.Winners))
+	for _, winner := range auction.Winners {
+		sellerIds = append(sellerIds, winner.SellerId)
+		err = config.AuctionService.Bids.MarkRefunded(ctx, auctionId, winner.SellerId, winner.LastBid, &note)
+		if err != nil {
+			log.Errorf("Bids.MarkRefunded(): %v", err)
+		}
+	}
+	config.AuctionService.Realtime.Finished(auctionservice.AuctionFinished{
+		AuctionId: auctionId,
+		Winners:   []models.AuctionDetailWinner{},
+	})
+	err = config.NotificationManager.NotifyAuctionSellers(ctx, auctionId, sellerIds, "AuctionReserveNotMetTitle", refundNote)
+	if err != nil {
+		log.Errorf("NotifyAuctionSellers(reserve not met): %v", err)
 	}
 	job.Finish()
 }
@@ -5,10 +5,18 @@ import (
 )
 
 func AddOjoCronListeners() {
-	config.OjoCronService.On("discount_removed", AutoPostDiscountRemoved)
-	config.OjoCronService.On("add_discount", AutoPostAddDiscount)
+	config.OjoCronService.On(config.DISCOUNT_REMOVED, AutoPostDiscountRemoved)
+	config.OjoCronService.On(config.REMOVE_DISCOUNT, AutoPostDiscountRemoved)
+	config.OjoCronService.On(config.ADD_DISCOUNT, AutoPostAddDiscount)
+	config.OjoCronService.On(config.DISCOUNT_STARTED, HandleDiscountStarted)
 	config.OjoCronService.On("auction_finished", HandleAuctionFinished)
 	config.OjoCronService.On("auction_removed", HandleAuctionRemoved)
 	config.OjoCronService.On(config.PERMISSION_STARTED, HandlePermissionStarted)
 	config.OjoCronService.On(config.PERMISSION_ENDED, HandlePermissionEnded)
+	config.OjoCronService.On(config.WALLET_RECONCILIATION, HandleWalletReconciliation)
+	config.OjoCronService.On(config.PACKAGE_LIFECYCLE, HandlePackageLifecycle)
+	config.OjoCronService.On(config.PACKAGE_AUTO_RENEW, HandlePackageAutoRenew)
+	config.OjoCronService.On(config.CANCEL_WITHDRAW_ACTION, HandleCancelWithdraw)
+	config.OjoCronService.On(config.CASHIER_APPROVAL_EXPIRED, HandleCashierApprovalExpired)
+	config.OjoCronService.On(config.SEARCH_REINDEX, HandleSearchReindex)
 }
@@ -0,0 +1,186 @@
+package ojocronlisteners
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/models"
+	notificationmanager "github.com/devzatruk/bizhubBackend/notification_manager"
+	"github.com/devzatruk/bizhubBackend/ojocronservice"
+	"github.com/devzatruk/bizhubBackend/ojologger"
+	"github.com/devzatruk/bizhubBackend/packageservice"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type sellerForRenewal struct {
+	Id      primitive.ObjectID          `bson:"_id"`
+	Package models.SellerCurrentPackage `bson:"package"`
+}
+
+func SchedulePackageAutoRenew() error {
+	return scheduleRecurringJob(config.PACKAGE_AUTO_RENEW, time.Now().Add(config.PACKAGE_AUTO_RENEW_EVERY))
+}
+
+func HandlePackageAutoRenew(job *ojocronservice.OjoCronJob) {
+	log := ojologger.LoggerService.Logger("AddOjoCronListeners()").Group("HandlePackageAutoRenew()")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	// ilki toleg: tolenen package-lerin reminder-leri indi gerek dal.
+	err := renewPackages(ctx)
+	if err != nil {
+		log.Errorf("renewPackages(): %v", err)
+	}
+	err = sendPackageReminders(ctx)
+	if err != nil {
+		log.Errorf("sendPackageReminders(): %v", err)
+	}
+	job.Finish()
+	err = SchedulePackageAutoRenew()
+	if err != nil {
+		log.Errorf("SchedulePackageAutoRenew(): %v", err)
+	}
+}
+
+func findSellersForRenewal(ctx context.Context, filter bson.M) ([]sellerForRenewal, error) {
+	cursor, err := config.MI.DB.Collection(config.SELLERS).Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("Find(sellers): %v", err)
+	}
+	var sellers []sellerForRenewal
+	if err = cursor.All(ctx, &sellers); err != nil {
+		return nil, fmt.Errorf("cursor.All(sellers): %v", err)
+	}
+	return sellers, nil
+}
+
+func renewPackages(ctx context.Context) error {
+	log := ojologger.LoggerService.Logger("AddOjoCronListeners()").Group("renewPackages()")
+	now := time.Now()
+	sellers, err := findSellersForRenewal(ctx, bson.M{
+		"status":                  bson.M{"$in": bson.A{config.SELLER_STATUS_PUBLISHED, config.SELLER_STATUS_NOTPAID}},
+		"package.auto_renew":      true,
+		"package.to":              bson.M{"$lte": now.Add(config.PACKAGE_AUTO_RENEW_BEFORE)},
+		"package.renew_failed_at": nil,
+		"$or": bson.A{
+			bson.M{"package.renew_next_at": nil},
+			bson.M{"package.renew_next_at": bson.M{"$lte": now}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	for _, seller := range sellers {
+		// wallet_history we package_history note-lary seller-in oz dilinde.
+		lang, err := config.NotificationManager.SellerLang(ctx, seller.Id)
+		if err != nil {
+			log.Errorf("SellerLang(%v): %v", seller.Id.Hex(), err)
+			lang = notificationmanager.DefaultLang
+		}
+		payment, err := packageservice.PayPackage(ctx, seller.Id, models.PayPayload{
+			PackageType: seller.Package.Type,
+			Action:      config.PACKAGE_PAY,
+		}, lang)
+		if err == nil {
+			err = config.NotificationManager.NotifySellers(ctx, []primitive.ObjectID{seller.Id},
+				notificationmanager.EnvTranslation("PackageAutoRenewedTitle"),
+				notificationmanager.EnvTranslationf("PackageAutoRenewed", payment.Type, payment.ExpiresAt.Format("02.01.2006"), payment.Price),
+				packageNotificationData())
+			if err != nil {
+				log.Errorf("NotifySellers(renewed): %v", err)
+			}
+			continue
+		}
+		log.Errorf("PayPackage(%v): %v", seller.Id.Hex(), err)
+		if !packageservice.IsBalanceNotEnough(err) {
+			// DB, ledger we s.m.: synanysyk sanalmayar, indiki job-da gaytadan.
+			continue
+		}
+		set := bson.M{"package.renew_next_at": now.Add(config.PACKAGE_AUTO_RENEW_RETRY_AFTER)}
+		gaveUp := seller.Package.RenewAttempts+1 >= config.PACKAGE_AUTO_RENEW_RETRIES
+		if gaveUp {
+			set["package.renew_failed_at"] = now
+		}
+		_, err = config.MI.DB.Collection(config.SELLERS).UpdateOne(ctx, bson.M{"_id": seller.Id}, bson.M{
+			"$inc": bson.M{"package.renew_attempts": 1},
+			"$set": set,
+		})
+		if err != nil {
+			log.Errorf("UpdateOne(seller.package.renew_attempts): %v", err)
+			continue
+		}
+		if gaveUp {
+			err = config.NotificationManager.NotifySellers(ctx, []primitive.ObjectID{seller.Id},
+				notificationmanager.EnvTranslation("PackageAutoRenewFailedTitle"),
+				notificationmanager.EnvTranslationf("PackageAutoRenewFailed", seller.Package.Type),
+				packageNotificationData())
+			if err != nil {
+				log.Errorf("NotifySellers(failed): %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// PACKAGE_REMINDER_DAYS-yn her biri ucin bir gezek: 7 gun galanda, 3 gun galanda, 1 gun galanda.
+func sendPackageReminders(ctx context.Context) error {
+	log := ojologger.LoggerService.Logger("AddOjoCronListeners()").Group("sendPackageReminders()")
+	now := time.Now()
+	sellersColl := config.MI.DB.Collection(config.SELLERS)
+	for i, days := range config.PACKAGE_REMINDER_DAYS {
+		from := now
+		if i+1 < len(config.PACKAGE_REMINDER_DAYS) {
+			from = now.Add(time.Duration(config.PACKAGE_REMINDER_DAYS[i+1]) * time.Hour * 24)
+		}
+		sellers, err := findSellersForRenewal(ctx, bson.M{
+			"status": config.SELLER_STATUS_PUBLISHED,
+			"package.to": bson.M{
+				"$gt":  from,
+				"$lte": now.Add(time.Duration(days) * time.Hour * 24),
+			},
+			"package.reminders_sent": bson.M{"$ne": days},
+		})
+		if err != nil {
+			return err
+		}
+		for _, seller := range sellers {
+			// bir reminder iki gezek gitmesin: ilki bellik, son push.
+			updateResult, err := sellersColl.UpdateOne(ctx,
+				bson.M{
+					"_id":                    seller.Id,
+					"package.reminders_sent": bson.M{"$ne": days},
+				},
+				bson.M{
+					"$addToSet": bson.M{"package.reminders_sent": days},
+				})
+			if err != nil {
+				log.Errorf("UpdateOne(seller.package.reminders_sent): %v", err)
+				continue
+			}
+			if updateResult.ModifiedCount == 0 {
+				continue
+			}
+			description := notificationmanager.EnvTranslationf("PackageReminder", seller.Package.Type, days)
+			if seller.Package.AutoRenew && seller.Package.RenewFailedAt == nil {
+				description = notificationmanager.EnvTranslationf("PackageAutoRenewReminder", seller.Package.Type, days)
+			}
+			err = config.NotificationManager.NotifySellers(ctx, []primitive.ObjectID{seller.Id},
+				notificationmanager.EnvTranslation("PackageReminderTitle"), description, packageNotificationData())
+			if err != nil {
+				log.Errorf("NotifySellers(reminder): %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// package notification-lary wallet ekranyna acyar (WalletDeepLink withdraw notification-lary bilen umumy).
+func packageNotificationData() map[string]string {
+	return map[string]string{
+		"type": "package",
+		"link": os.Getenv("WalletDeepLink"),
+	}
+}
@@ -0,0 +1,180 @@
+package ojocronlisteners
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/models"
+	"github.com/devzatruk/bizhubBackend/ojocronservice"
+	"github.com/devzatruk/bizhubBackend/ojologger"
+	"github.com/devzatruk/bizhubBackend/packageservice"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// package lifecycle: active -> (package.to gecdi) expired, grace_until -> (grace gecdi) seller notpaid,
+// basic package-den artyk product-lar hidden. package.to tazeden gelejekde bolsa hemmesi yzyna gaytarylyar.
+func SchedulePackageLifecycle() error {
+	return scheduleRecurringJob(config.PACKAGE_LIFECYCLE, time.Now().Add(config.PACKAGE_LIFECYCLE_EVERY))
+}
+
+func HandlePackageLifecycle(job *ojocronservice.OjoCronJob) {
+	log := ojologger.LoggerService.Logger("AddOjoCronListeners()").Group("HandlePackageLifecycle()")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	err := RunPackageLifecycle(ctx)
+	if err != nil {
+		log.Errorf("RunPackageLifecycle(): %v", err)
+	}
+	job.Finish()
+	err = SchedulePackageLifecycle()
+	if err != nil {
+		log.Errorf("SchedulePackageLifecycle(): %v", err)
+	}
+}
+
+// ahli adimlar idempotent: birnace gezek islese hem netije uytgemeyar.
+func RunPackageLifecycle(ctx context.Context) error {
+	now := time.Now()
+	sellersColl := config.MI.DB.Collection(config.SELLERS)
+	// 1. package.to gecdi: grace period baslayar, garasylyan downgrade (pending_type) su wagt gecyar.
+	_, err := sellersColl.UpdateMany(ctx,
+		bson.M{
+			"status":         config.SELLER_STATUS_PUBLISHED,
+			"package.to":     bson.M{"$lte": now},
+			"package.status": bson.M{"$ne": config.STATUS_EXPIRED},
+		},
+		bson.A{
+			bson.M{
+				"$set": bson.M{
+					"package.status": config.STATUS_EXPIRED,
+					"package.grace_until": bson.M{
+						"$add": bson.A{"$package.to", config.PACKAGE_GRACE_PERIOD.Milliseconds()},
+					},
+					"package.type": bson.M{
+						"$ifNull": bson.A{"$package.pending_type", "$package.type"},
+					},
+				},
+			},
+			bson.M{
+				"$unset": "package.pending_type",
+			},
+		})
+	if err != nil {
+		return fmt.Errorf("UpdateMany(sellers.package.status): %v", err)
+	}
+	// 2. grace period gecdi: seller notpaid, artyk product-lar gizlenyar.
+	sellerIds, err := findSellerIds(ctx, bson.M{
+		"status":              config.SELLER_STATUS_PUBLISHED,
+		"package.status":      config.STATUS_EXPIRED,
+		"package.grace_until": bson.M{"$lte": now},
+	})
+	if err != nil {
+		return err
+	}
+	for _, sellerId := range sellerIds {
+		err = markSellerNotPaid(ctx, sellerId)
+		if err != nil {
+			return err
+		}
+	}
+	// 3. package.to tazeden gelejekde (PayForPackage ya-da admin extend): yzyna gaytarylyar.
+	sellerIds, err = findSellerIds(ctx, bson.M{
+		"package.to": bson.M{"$gt": now},
+		"$or": bson.A{
+			bson.M{"status": config.SELLER_STATUS_NOTPAID},
+			bson.M{"package.status": config.STATUS_EXPIRED},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	for _, sellerId := range sellerIds {
+		err = packageservice.RestoreSellerPackage(ctx, sellerId)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findSellerIds(ctx context.Context, filter bson.M) ([]primitive.ObjectID, error) {
+	cursor, err := config.MI.DB.Collection(config.SELLERS).Find(ctx, filter,
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("Find(sellers): %v", err)
+	}
+	var sellers []struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &sellers); err != nil {
+		return nil, fmt.Errorf("cursor.All(sellers): %v", err)
+	}
+	sellerIds := make([]primitive.ObjectID, 0, len(sellers))
+	for _, seller := range sellers {
+		sellerIds = append(sellerIds, seller.Id)
+	}
+	return sellerIds, nil
+}
+
+func markSellerNotPaid(ctx context.Context, sellerId primitive.ObjectID) error {
+	var basic models.PackageWithoutName
+	err := config.MI.DB.Collection(config.PACKAGES).FindOne(ctx, bson.M{"type": config.PACKAGE_TYPE_BASIC}).Decode(&basic)
+	if err != nil {
+		return fmt.Errorf("FindOne(package.basic): %v", err)
+	}
+	// in taze max_products product galyar, galanlary hidden.
+	productsColl := config.MI.DB.Collection(config.PRODUCTS)
+	cursor, err := productsColl.Find(ctx,
+		bson.M{
+			"seller_id": sellerId,
+			"status":    config.STATUS_PUBLISHED,
+		},
+		options.Find().
+			SetSort(bson.M{"created_at": -1}).
+			SetSkip(basic.MaxProducts).
+			SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return fmt.Errorf("Find(products): %v", err)
+	}
+	var products []struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &products); err != nil {
+		return fmt.Errorf("cursor.All(products): %v", err)
+	}
+	if len(products) > 0 {
+		productIds := make([]primitive.ObjectID, 0, len(products))
+		for _, product := range products {
+			productIds = append(productIds, product.Id)
+		}
+		_, err = productsColl.UpdateMany(ctx,
+			bson.M{
+				"_id":    bson.M{"$in": productIds},
+				"status": config.STATUS_PUBLISHED,
+			},
+			bson.M{
+				"$set": bson.M{"status": config.STATUS_HIDDEN},
+			})
+		if err != nil {
+			return fmt.Errorf("UpdateMany(products.hidden): %v", err)
+		}
+	}
+	// product-lar gizlenenden son: yalnys bolsa indiki gezek tazeden synanysylyar.
+	_, err = config.MI.DB.Collection(config.SELLERS).UpdateOne(ctx,
+		bson.M{
+			"_id":            sellerId,
+			"status":         config.SELLER_STATUS_PUBLISHED,
+			"package.status": config.STATUS_EXPIRED,
+		},
+		bson.M{
+			"$set": bson.M{"status": config.SELLER_STATUS_NOTPAID},
+		})
+	if err != nil {
+		return fmt.Errorf("UpdateOne(seller.notpaid): %v", err)
+	}
+	return nil
+}
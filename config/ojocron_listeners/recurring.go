@@ -0,0 +1,23 @@
+package ojocronlisteners
+
+import (
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/ojocronservice"
+)
+
+// gaytalanyan job-lar (reconciliation, package lifecycle) her gezek oz-ozuni tazeden goyyar.
+// group listener ady: onki goyulan job pozulyar, sonun ucin bir wagtda dine bir job bolyar.
+func scheduleRecurringJob(listenerName string, runAt time.Time) error {
+	err := config.OjoCronService.RemoveJobsByGroup(listenerName)
+	if err != nil {
+		return err
+	}
+	jobModel := ojocronservice.NewOjoCronJobModel().
+		ListenerName(listenerName).
+		Payload(map[string]interface{}{}).
+		RunAt(runAt).
+		Group(listenerName)
+	return config.OjoCronService.NewJob(jobModel)
+}
@@ -0,0 +1,78 @@
+package ojocronlisteners
+
+import (
+	"context"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/ojocronservice"
+	"github.com/devzatruk/bizhubBackend/ojologger"
+	"github.com/devzatruk/bizhubBackend/searchservice"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func ScheduleSearchReindex() error {
+	return scheduleRecurringJob(config.SEARCH_REINDEX, time.Now().Add(config.SEARCH_REINDEX_EVERY))
+}
+
+func HandleSearchReindex(job *ojocronservice.OjoCronJob) {
+	log := ojologger.LoggerService.Logger("AddOjoCronListeners()").Group("HandleSearchReindex()")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	stale := searchservice.StaleFilter(config.SEARCH_REINDEX_AFTER)
+	products, err := config.SearchService.IndexProducts(ctx, stale, config.SEARCH_REINDEX_LIMIT)
+	if err != nil {
+		log.Errorf("IndexProducts(): %v", err)
+	}
+	sellers, err := config.SearchService.IndexSellers(ctx, stale, config.SEARCH_REINDEX_LIMIT)
+	if err != nil {
+		log.Errorf("IndexSellers(): %v", err)
+	}
+	if products+sellers > 0 {
+		log.Logf("Search reindex: %v products, %v sellers.", products, sellers)
+	}
+	err = config.SearchService.RefreshSuggestions(ctx)
+	if err != nil {
+		log.Errorf("RefreshSuggestions(): %v", err)
+	}
+	job.Finish()
+	err = ScheduleSearchReindex()
+	if err != nil {
+		log.Errorf("ScheduleSearchReindex(): %v", err)
+	}
+}
+
+// product publish/edit-den son: indiki job-a garasman gozlegde we suggest-de tazelenip gorunsin.
+func ReindexProduct(productId primitive.ObjectID) {
+	go func() {
+		log := ojologger.LoggerService.Logger("AddOjoCronListeners()").Group("ReindexProduct()")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, err := config.SearchService.IndexProducts(ctx, bson.M{"_id": productId}, 1)
+		if err != nil {
+			log.Errorf("IndexProducts(%v): %v", productId.Hex(), err)
+		}
+		err = config.SearchService.RefreshProductSuggestion(ctx, productId)
+		if err != nil {
+			log.Errorf("RefreshProductSuggestion(%v): %v", productId.Hex(), err)
+		}
+	}()
+}
+
+func ReindexSeller(sellerId primitive.ObjectID) {
+	go func() {
+		log := ojologger.LoggerService.Logger("AddOjoCronListeners()").Group("ReindexSeller()")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, err := config.SearchService.IndexSellers(ctx, bson.M{"_id": sellerId}, 1)
+		if err != nil {
+			log.Errorf("IndexSellers(%v): %v", sellerId.Hex(), err)
+		}
+		// seller ady product meta-synda hem bar.
+		_, err = config.SearchService.IndexProducts(ctx, bson.M{"seller_id": sellerId}, 0)
+		if err != nil {
+			log.Errorf("IndexProducts(seller %v): %v", sellerId.Hex(), err)
+		}
+	}()
+}
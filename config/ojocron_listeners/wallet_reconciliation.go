@@ -0,0 +1,597 @@
+package ojocronlisteners
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/helpers"
+	"github.com/devzatruk/bizhubBackend/ledger"
+	"github.com/devzatruk/bizhubBackend/models"
+	"github.com/devzatruk/bizhubBackend/ojocronservice"
+	"github.com/devzatruk/bizhubBackend/ojologger"
+	ojoTr "github.com/devzatruk/bizhubBackend/transaction_manager"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrDriftNotOpen = errors.New("Drift not found or already handled.")
+
+// wallet-in wallet_history, cashier_works we acyk auction-lar boyunca bolmaly yagdayy.
+type expectedWallet struct {
+	Balance         float64
+	Holds           []models.WalletDriftHold
+	WithdrawPending float64 // entek berilmedik withdraw-lar (waiting, pending_approval)
+	Deposits        models.WalletDriftTotals
+	Payouts         models.WalletDriftTotals
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// indiki reconciliation job-y: WALLET_RECONCILIATION_HOUR-da, onki job-lar pozulyar.
+func ScheduleWalletReconciliation() error {
+	now := time.Now()
+	y, m, d := now.Date()
+	runAt := time.Date(y, m, d, config.WALLET_RECONCILIATION_HOUR, 0, 0, 0, now.Location())
+	for !runAt.After(now) {
+		runAt = runAt.Add(config.WALLET_RECONCILIATION_EVERY)
+	}
+	return scheduleRecurringJob(config.WALLET_RECONCILIATION, runAt)
+}
+
+func HandleWalletReconciliation(job *ojocronservice.OjoCronJob) {
+	log := ojologger.LoggerService.Logger("AddOjoCronListeners()").Group("HandleWalletReconciliation()")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	report, err := ReconcileWallets(ctx, nil)
+	if err != nil {
+		log.Errorf("ReconcileWallets(): %v", err)
+	} else {
+		log.Logf("Wallet reconciliation %v: %v wallets, %v drifts.", report.Id.Hex(), report.WalletsChecked, report.Drifts)
+	}
+	// yalnys bolsa hem indiki gun tazeden barlanyar.
+	job.Finish()
+	err = ScheduleWalletReconciliation()
+	if err != nil {
+		log.Errorf("ScheduleWalletReconciliation(): %v", err)
+	}
+}
+
+// ahli wallet-leri barlayar, tapawutlary wallet_drifts-a yazyar.
+// triggeredBy admin tarapyndan el bilen isledilende employee id, ojocron ucin nil.
+func ReconcileWallets(ctx context.Context, triggeredBy *primitive.ObjectID) (*models.WalletReconciliation, error) {
+	reportsColl := config.MI.DB.Collection(config.WALLET_RECONCILIATIONS)
+	report := models.WalletReconciliation{
+		Id:          primitive.NewObjectID(),
+		Status:      config.RECONCILIATION_RUNNING,
+		TriggeredBy: triggeredBy,
+		StartedAt:   time.Now(),
+	}
+	_, err := reportsColl.InsertOne(ctx, report)
+	if err != nil {
+		return nil, fmt.Errorf("InsertOne(wallet_reconciliation): %v", err)
+	}
+	drifts, checked, err := findWalletDrifts(ctx, report.Id)
+	if err == nil && len(drifts) > 0 {
+		documents := make([]interface{}, 0, len(drifts))
+		for _, drift := range drifts {
+			documents = append(documents, drift)
+		}
+		_, err = config.MI.DB.Collection(config.WALLET_DRIFTS).InsertMany(ctx, documents)
+		if err != nil {
+			err = fmt.Errorf("InsertMany(wallet_drifts): %v", err)
+		}
+	}
+	now := time.Now()
+	report.FinishedAt = &now
+	report.WalletsChecked = checked
+	report.Drifts = int64(len(drifts))
+	report.Status = config.RECONCILIATION_COMPLETED
+	if err != nil {
+		errMessage := err.Error()
+		report.Status = config.RECONCILIATION_FAILED
+		report.Error = &errMessage
+	}
+	_, updateErr := reportsColl.UpdateOne(ctx, bson.M{"_id": report.Id}, bson.M{
+		"$set": bson.M{
+			"status":          report.Status,
+			"wallets_checked": report.WalletsChecked,
+			"drifts":          report.Drifts,
+			"error":           report.Error,
+			"finished_at":     report.FinishedAt,
+		},
+	})
+	if err != nil {
+		return &report, err
+	}
+	if updateErr != nil {
+		return &report, fmt.Errorf("UpdateOne(wallet_reconciliation): %v", updateErr)
+	}
+	return &report, nil
+}
+
+func findWalletDrifts(ctx context.Context, reconciliationId primitive.ObjectID) ([]models.WalletDrift, int64, error) {
+	wallets, err := findWallets(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	expected, err := expectedWallets(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	suspects := []primitive.ObjectID{}
+	for _, wallet := range wallets {
+		drift, err := compareWallet(ctx, wallet, expected[wallet.SellerId])
+		if err != nil {
+			return nil, 0, err
+		}
+		if drift != nil {
+			suspects = append(suspects, wallet.SellerId)
+		}
+	}
+	drifts := []models.WalletDrift{}
+	if len(suspects) == 0 {
+		return drifts, int64(len(wallets)), nil
+	}
+	// barlag wagtynda gecen bid/withdraw yalnys drift gorkezmesin: tapawutly wallet-ler tazeden okalyar.
+	recheckedWallets, err := findWallets(ctx, suspects)
+	if err != nil {
+		return nil, 0, err
+	}
+	expected, err = expectedWallets(ctx, suspects)
+	if err != nil {
+		return nil, 0, err
+	}
+	now := time.Now()
+	for _, wallet := range recheckedWallets {
+		drift, err := compareWallet(ctx, wallet, expected[wallet.SellerId])
+		if err != nil {
+			return nil, 0, err
+		}
+		if drift == nil {
+			continue
+		}
+		drift.Id = primitive.NewObjectID()
+		drift.ReconciliationId = reconciliationId
+		drift.Status = config.DRIFT_OPEN
+		drift.CreatedAt = now
+		drifts = append(drifts, *drift)
+	}
+	return drifts, int64(len(wallets)), nil
+}
+
+// sellerIds nil bolsa ahli wallet-ler.
+func findWallets(ctx context.Context, sellerIds []primitive.ObjectID) ([]models.SellerWallet, error) {
+	filter := bson.M{}
+	if sellerIds != nil {
+		filter["seller_id"] = bson.M{"$in": sellerIds}
+	}
+	cursor, err := config.MI.DB.Collection(config.WALLETS).Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("Find(wallets): %v", err)
+	}
+	var wallets []models.SellerWallet
+	err = cursor.All(ctx, &wallets)
+	if err != nil {
+		return nil, fmt.Errorf("cursor.All(wallets): %v", err)
+	}
+	return wallets, nil
+}
+
+// sellerIds nil bolsa ahli seller-ler ucin.
+func expectedWallets(ctx context.Context, sellerIds []primitive.ObjectID) (map[primitive.ObjectID]*expectedWallet, error) {
+	match := bson.M{}
+	if sellerIds != nil {
+		match["seller_id"] = bson.M{"$in": sellerIds}
+	}
+	expected := map[primitive.ObjectID]*expectedWallet{}
+	of := func(sellerId primitive.ObjectID) *expectedWallet {
+		e, ok := expected[sellerId]
+		if !ok {
+			e = &expectedWallet{Holds: []models.WalletDriftHold{}}
+			expected[sellerId] = e
+		}
+		return e
+	}
+
+	cursor, err := config.MI.DB.Collection(config.WALLETHISTORY).Aggregate(ctx, bson.A{
+		bson.M{"$match": match},
+		bson.M{
+			"$group": bson.M{
+				"_id": bson.M{
+					"seller_id": "$seller_id",
+					"intent":    "$intent",
+					"status":    "$status",
+				},
+				"amount": bson.M{"$sum": "$amount"},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Aggregate(wallet_history): %v", err)
+	}
+	var histories []struct {
+		Id struct {
+			SellerId primitive.ObjectID `bson:"seller_id"`
+			Intent   string             `bson:"intent"`
+			Status   string             `bson:"status"`
+		} `bson:"_id"`
+		Amount float64 `bson:"amount"`
+	}
+	if err = cursor.All(ctx, &histories); err != nil {
+		return nil, fmt.Errorf("cursor.All(wallet_history): %v", err)
+	}
+	for _, history := range histories {
+		e := of(history.Id.SellerId)
+		completed := history.Id.Status == config.STATUS_COMPLETED
+		switch history.Id.Intent {
+		case config.INTENT_DEPOSIT:
+			if completed {
+				e.Balance += history.Amount
+				e.Deposits.History += history.Amount
+			}
+		case config.INTENT_WITHDRAW:
+			// waiting/pending_approval: pul wallet-den cykdy, entek berilmedi. cancelled/expired: yzyna gaytaryldy.
+			pending := history.Id.Status == config.STATUS_WAITING || history.Id.Status == config.STATUS_PENDING_APPROVAL
+			if completed || pending {
+				e.Balance -= history.Amount
+			}
+			if pending {
+				e.WithdrawPending += history.Amount
+			}
+			if completed {
+				e.Payouts.History += history.Amount
+			}
+		case config.INTENT_PAYMENT:
+			if completed {
+				e.Balance -= history.Amount
+			}
+		case config.INTENT_CORRECTION:
+			if completed {
+				e.Balance += history.Amount
+			}
+			// INTENT_REFUND auction hold-y gaytarylanda yazylyar, hold-yn ozi history-de yok,
+			// sonun ucin ol asakdaky acyk auction hold-lary arkaly eyyam hasaplanyar.
+		}
+	}
+
+	cursor, err = config.MI.DB.Collection(config.CASHIERWORKS).Aggregate(ctx, bson.A{
+		bson.M{"$match": match},
+		bson.M{
+			"$group": bson.M{
+				"_id": bson.M{
+					"seller_id": "$seller_id",
+					"intent":    "$intent",
+				},
+				"amount": bson.M{"$sum": "$amount"},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Aggregate(cashier_works): %v", err)
+	}
+	var works []struct {
+		Id struct {
+			SellerId primitive.ObjectID `bson:"seller_id"`
+			Intent   string             `bson:"intent"`
+		} `bson:"_id"`
+		Amount float64 `bson:"amount"`
+	}
+	if err = cursor.All(ctx, &works); err != nil {
+		return nil, fmt.Errorf("cursor.All(cashier_works): %v", err)
+	}
+	for _, work := range works {
+		e := of(work.Id.SellerId)
+		switch work.Id.Intent {
+		case config.INTENT_DEPOSIT:
+			e.Deposits.Cashier += work.Amount
+		case config.INTENT_WITHDRAW:
+			e.Payouts.Cashier += work.Amount
+		}
+	}
+
+	winnersMatch := bson.M{}
+	if sellerIds != nil {
+		winnersMatch["winners.seller_id"] = bson.M{"$in": sellerIds}
+	}
+	cursor, err = config.MI.DB.Collection(config.AUCTIONS).Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"is_finished": false}},
+		bson.M{"$unwind": "$winners"},
+		bson.M{"$match": winnersMatch},
+		bson.M{
+			"$project": bson.M{
+				"seller_id": "$winners.seller_id",
+				"amount":    "$winners.last_bid",
+				"name":      "$heading",
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Aggregate(auctions): %v", err)
+	}
+	var holds []struct {
+		AuctionId primitive.ObjectID `bson:"_id"`
+		SellerId  primitive.ObjectID `bson:"seller_id"`
+		Amount    float64            `bson:"amount"`
+		Name      models.Translation `bson:"name"`
+	}
+	if err = cursor.All(ctx, &holds); err != nil {
+		return nil, fmt.Errorf("cursor.All(auctions): %v", err)
+	}
+	for _, hold := range holds {
+		e := of(hold.SellerId)
+		e.Balance -= hold.Amount
+		e.Holds = append(e.Holds, models.WalletDriftHold{
+			AuctionId: hold.AuctionId,
+			Amount:    hold.Amount,
+			Name:      hold.Name,
+		})
+	}
+	return expected, nil
+}
+
+// tapawut yok bolsa nil gaytaryar.
+func compareWallet(ctx context.Context, wallet models.SellerWallet, expected *expectedWallet) (*models.WalletDrift, error) {
+	if expected == nil {
+		expected = &expectedWallet{Holds: []models.WalletDriftHold{}}
+	}
+	drift := &models.WalletDrift{
+		SellerId:        wallet.SellerId,
+		WalletId:        wallet.Id,
+		Kinds:           []string{},
+		Balance:         wallet.Balance,
+		ExpectedBalance: roundCents(expected.Balance),
+		Holds:           make([]models.WalletDriftHold, 0, len(wallet.InAuction)),
+		ExpectedHolds:   expected.Holds,
+		WithdrawPending: roundCents(expected.WithdrawPending),
+		Deposits:        expected.Deposits,
+		Payouts:         expected.Payouts,
+	}
+	if roundCents(wallet.Balance) != drift.ExpectedBalance {
+		drift.Kinds = append(drift.Kinds, config.DRIFT_BALANCE)
+	}
+	actualHolds := map[primitive.ObjectID]float64{}
+	var held float64
+	for _, inAuction := range wallet.InAuction {
+		actualHolds[inAuction.AuctionId] += float64(inAuction.Amount)
+		held += float64(inAuction.Amount)
+		drift.Holds = append(drift.Holds, models.WalletDriftHold{
+			AuctionId: inAuction.AuctionId,
+			Amount:    float64(inAuction.Amount),
+			Name:      inAuction.Name,
+		})
+	}
+	holdsMatch := len(wallet.InAuction) == len(expected.Holds)
+	for _, hold := range expected.Holds {
+		amount, ok := actualHolds[hold.AuctionId]
+		if !ok || roundCents(amount) != roundCents(hold.Amount) {
+			holdsMatch = false
+		}
+	}
+	if !holdsMatch {
+		drift.Kinds = append(drift.Kinds, config.DRIFT_HOLDS)
+	}
+	if wallet.LedgerOpenedAt != nil {
+		// ledger-in uc hasaby: available = wallets.balance, held = in_auction[] jemi,
+		// withdraw_pending = berilmedik withdraw wallet_history-leri.
+		ledgerMatches := true
+		for _, account := range []struct {
+			name   string
+			actual float64
+			target **float64
+		}{
+			{ledger.SellerAvailable(wallet.SellerId), wallet.Balance, &drift.LedgerBalance},
+			{ledger.SellerHeld(wallet.SellerId), held, &drift.LedgerHeld},
+			{ledger.SellerWithdrawPending(wallet.SellerId), expected.WithdrawPending, &drift.LedgerPending},
+		} {
+			journaled, err := config.Ledger.Balance(ctx, account.name)
+			if err != nil {
+				return nil, fmt.Errorf("Ledger.Balance(%v): %v", account.name, err)
+			}
+			*account.target = &journaled
+			if journaled != roundCents(account.actual) {
+				ledgerMatches = false
+			}
+		}
+		if !ledgerMatches {
+			drift.Kinds = append(drift.Kinds, config.DRIFT_LEDGER)
+		}
+	}
+	if roundCents(expected.Deposits.History) != roundCents(expected.Deposits.Cashier) {
+		drift.Kinds = append(drift.Kinds, config.DRIFT_DEPOSITS)
+	}
+	if roundCents(expected.Payouts.History) != roundCents(expected.Payouts.Cashier) {
+		drift.Kinds = append(drift.Kinds, config.DRIFT_PAYOUTS)
+	}
+	if len(drift.Kinds) == 0 {
+		return nil, nil
+	}
+	return drift, nil
+}
+
+// acyk drift-i duzedyar: wallet hazirki wagtda tazeden hasaplanyar, balance tapawudy
+// ledger-e correction entry we wallet_history-a correction bolup yazylyar (employee_id bilen),
+// in_auction[] acyk auction-laryn winners[]-ine gora goyulyar.
+// ledger/deposits/payouts tapawutlary dine report, olar duzedilmeyar.
+func FixWalletDrift(ctx context.Context, driftId primitive.ObjectID, employeeId primitive.ObjectID, note string) (*models.WalletDrift, error) {
+	driftsColl := config.MI.DB.Collection(config.WALLET_DRIFTS)
+	var drift models.WalletDrift
+	err := driftsColl.FindOne(ctx, bson.M{"_id": driftId, "status": config.DRIFT_OPEN}).Decode(&drift)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrDriftNotOpen
+	}
+	if err != nil {
+		return nil, fmt.Errorf("FindOne(drift): %v", err)
+	}
+	wallets, err := findWallets(ctx, []primitive.ObjectID{drift.SellerId})
+	if err != nil {
+		return nil, err
+	}
+	if len(wallets) == 0 {
+		return nil, errors.New("Wallet not found.")
+	}
+	wallet := wallets[0]
+	expected, err := expectedWallets(ctx, []primitive.ObjectID{drift.SellerId})
+	if err != nil {
+		return nil, err
+	}
+	current, err := compareWallet(ctx, wallet, expected[drift.SellerId])
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	fixable := false
+	if current != nil {
+		for _, kind := range current.Kinds {
+			fixable = fixable || helpers.SliceContains(config.FIXABLE_DRIFTS, kind)
+		}
+	}
+	if !fixable {
+		_, err = driftsColl.UpdateOne(ctx, bson.M{"_id": driftId, "status": config.DRIFT_OPEN}, bson.M{
+			"$set": bson.M{
+				"status":   config.DRIFT_RESOLVED,
+				"fixed_at": now,
+				"fixed_by": employeeId,
+				"fix_note": note,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("UpdateOne(drift): %v", err)
+		}
+		drift.Status = config.DRIFT_RESOLVED
+		drift.FixedAt = &now
+		drift.FixedBy = &employeeId
+		drift.FixNote = &note
+		return &drift, nil
+	}
+	var actualHeld, expectedHeld float64
+	for _, hold := range current.Holds {
+		actualHeld += hold.Amount
+	}
+	for _, hold := range current.ExpectedHolds {
+		expectedHeld += hold.Amount
+	}
+	availableDelta := roundCents(current.ExpectedBalance - wallet.Balance)
+	heldDelta := roundCents(expectedHeld - actualHeld)
+	// in_auction[] duzedilmezden on: correction tapawudy opening_balance-a girmesin.
+	err = config.Ledger.OpenWallets(ctx, drift.SellerId)
+	if err != nil {
+		return nil, fmt.Errorf("Ledger.OpenWallets(): %v", err)
+	}
+
+	transaction_manager := ojoTr.NewTransaction(&ctx, config.MI.DB, 3)
+	entry := ledger.Correction(drift.SellerId, availableDelta, heldDelta).
+		WithRef(config.WALLET_DRIFTS, driftId).
+		WithNote(note)
+	var historyId *primitive.ObjectID
+	if availableDelta != 0 {
+		correctionNote := models.Translation{Tm: note, Ru: note, En: note, Tr: note}
+		wh := models.MyWalletHistory{
+			SellerId:    drift.SellerId,
+			WalletId:    wallet.Id,
+			OldBalance:  wallet.Balance,
+			Amount:      availableDelta,
+			Intent:      config.INTENT_CORRECTION,
+			Note:        &correctionNote,
+			Code:        nil,
+			Status:      config.STATUS_COMPLETED,
+			CompletedAt: &now,
+			CreatedAt:   now,
+			EmployeeId:  &employeeId,
+		}
+		insertResult, err := transaction_manager.Collection(config.WALLETHISTORY).InsertOne(ojoTr.NewModel().SetDocument(wh))
+		if err != nil {
+			return nil, rollbackWith(transaction_manager, fmt.Errorf("InsertOne(wallet_history): %v", err))
+		}
+		insertedId := insertResult.InsertedID.(primitive.ObjectID)
+		historyId = &insertedId
+		_, err = transaction_manager.Collection(config.SELLERS).UpdateOne(ojoTr.NewModel().
+			SetFilter(bson.M{"_id": drift.SellerId}).
+			SetUpdate(bson.M{
+				"$push": bson.M{
+					"transfers": bson.M{
+						"$each":     bson.A{insertedId},
+						"$slice":    2,
+						"$position": 0,
+					},
+				},
+			}).
+			SetRollbackUpdate(bson.M{
+				"$pull": bson.M{
+					"transfers": insertedId,
+				},
+			}))
+		if err != nil {
+			return nil, rollbackWith(transaction_manager, fmt.Errorf("UpdateOne(seller.transfers[]): %v", err))
+		}
+	}
+	if helpers.SliceContains(current.Kinds, config.DRIFT_HOLDS) {
+		_, err = transaction_manager.Collection(config.WALLETS).UpdateOne(ojoTr.NewModel().
+			SetFilter(bson.M{"_id": wallet.Id}).
+			SetUpdate(bson.M{"$set": bson.M{"in_auction": current.ExpectedHolds}}).
+			SetRollbackUpdate(bson.M{"$set": bson.M{"in_auction": wallet.InAuction}}))
+		if err != nil {
+			return nil, rollbackWith(transaction_manager, fmt.Errorf("UpdateOne(wallet.in_auction): %v", err))
+		}
+	}
+	_, err = transaction_manager.Collection(config.WALLET_DRIFTS).UpdateOne(ojoTr.NewModel().
+		SetFilter(bson.M{"_id": driftId, "status": config.DRIFT_OPEN}).
+		SetUpdate(bson.M{
+			"$set": bson.M{
+				"status":                config.DRIFT_FIXED,
+				"fixed_at":              now,
+				"fixed_by":              employeeId,
+				"fix_note":              note,
+				"correction_history_id": historyId,
+			},
+		}).
+		SetRollbackUpdate(bson.M{
+			"$set": bson.M{
+				"status":                config.DRIFT_OPEN,
+				"fixed_at":              nil,
+				"fixed_by":              nil,
+				"fix_note":              nil,
+				"correction_history_id": nil,
+			},
+		}))
+	if err != nil {
+		return nil, rollbackWith(transaction_manager, fmt.Errorf("UpdateOne(drift): %v", err))
+	}
+	// ledger in sonky adim: yalnys bolsa entry yazylmaz, ojoTr yzyna gaytaryar.
+	var entryId *primitive.ObjectID
+	if len(entry.Lines) > 0 {
+		postResult, err := config.Ledger.Post(ctx, entry)
+		if err != nil {
+			return nil, rollbackWith(transaction_manager, fmt.Errorf("Ledger.Post(correction): %v", err))
+		}
+		entryId = &postResult.EntryId
+		_, err = driftsColl.UpdateOne(ctx, bson.M{"_id": driftId}, bson.M{
+			"$set": bson.M{"correction_entry_id": entryId},
+		})
+		if err != nil {
+			// correction eyyam yazyldy, dine baglanysyk yitdi: entry ref arkaly tapylyar.
+			ojologger.LoggerService.Logger("AddOjoCronListeners()").Group("FixWalletDrift()").Errorf("UpdateOne(drift.correction_entry_id): %v", err)
+		}
+	}
+	drift.Status = config.DRIFT_FIXED
+	drift.FixedAt = &now
+	drift.FixedBy = &employeeId
+	drift.FixNote = &note
+	drift.CorrectionHistoryId = historyId
+	drift.CorrectionEntryId = entryId
+	return &drift, nil
+}
+
+func rollbackWith(transaction_manager *ojoTr.TransactionManager, err error) error {
+	trErr := transaction_manager.Rollback()
+	if trErr != nil {
+		return fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+	}
+	return err
+}
@@ -0,0 +1,36 @@
+package ojocronlisteners
+
+import (
+	"context"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/cashierservice"
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/ojocronservice"
+	"github.com/devzatruk/bizhubBackend/ojologger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func HandleCancelWithdraw(job *ojocronservice.OjoCronJob) {
+	log := ojologger.LoggerService.Logger("AddOjoCronListeners()").Group("HandleCancelWithdraw()")
+	transactionId := job.Payload["transaction_id"].(primitive.ObjectID)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	history, postResult, err := cashierservice.CancelWithdrawRequest(ctx, transactionId, config.STATUS_EXPIRED)
+	if err != nil {
+		if err == cashierservice.ErrWithdrawNotWaiting {
+			// eyyam tolendi ya-da seller cancel etdi.
+			job.Finish()
+			return
+		}
+		log.Errorf("CancelWithdrawRequest(%v): %v", transactionId.Hex(), err)
+		job.Failed()
+		return
+	}
+	job.Finish()
+	log.Logf("Withdraw %v expired, %v returned to balance.", transactionId.Hex(), history.Amount)
+	err = cashierservice.NotifyWithdrawExpired(ctx, history, postResult)
+	if err != nil {
+		log.Errorf("NotifySellers(): %v", err)
+	}
+}
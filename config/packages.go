@@ -0,0 +1,27 @@
+package config
+
+import "time"
+
+const (
+	// package lifecycle: ojocron listener ady we job group-y
+	PACKAGE_LIFECYCLE       = "package_lifecycle"
+	PACKAGE_LIFECYCLE_EVERY = time.Hour
+	// package.to gecenden son seller su wagt published galyar, son notpaid bolyar
+	PACKAGE_GRACE_PERIOD = time.Hour * 24 * 3
+	// auto renew: package.to-dan PACKAGE_AUTO_RENEW_BEFORE on tolenyar, pul yetmese
+	// PACKAGE_AUTO_RENEW_RETRY_AFTER-dan son tazeden, jemi PACKAGE_AUTO_RENEW_RETRIES gezek.
+	PACKAGE_AUTO_RENEW             = "package_auto_renew"
+	PACKAGE_AUTO_RENEW_EVERY       = time.Hour
+	PACKAGE_AUTO_RENEW_BEFORE      = time.Hour * 24
+	PACKAGE_AUTO_RENEW_RETRY_AFTER = time.Hour * 6
+	PACKAGE_AUTO_RENEW_RETRIES     = 3
+	// package tolegi: renew (sol package), change (package gutaran, credit yok),
+	// upgrade (ulanylmadyk gunler credit), downgrade (package.to-da baslayar)
+	PACKAGE_MODE_RENEW     = "renew"
+	PACKAGE_MODE_CHANGE    = "change"
+	PACKAGE_MODE_UPGRADE   = "upgrade"
+	PACKAGE_MODE_DOWNGRADE = "downgrade"
+)
+
+// package.to-dan su gun on push reminder ugradylyar (ulydan kica).
+var PACKAGE_REMINDER_DAYS = []int64{7, 3, 1}
@@ -0,0 +1,20 @@
+package config
+
+const (
+	// product-da in kop price tier (mysal 1-9, 10-99, 100+)
+	PRICE_TIERS_MAX = 5
+	// satylyan birlik: client oz dilinde gorkezyar
+	SALE_UNIT_PIECE = "piece"
+	SALE_UNIT_PACK  = "pack"
+	SALE_UNIT_BOX   = "box"
+	SALE_UNIT_SET   = "set"
+	SALE_UNIT_PAIR  = "pair"
+	SALE_UNIT_KG    = "kg"
+	SALE_UNIT_TON   = "ton"
+	SALE_UNIT_LITER = "liter"
+	SALE_UNIT_METER = "meter"
+	SALE_UNIT_SQM   = "sq_meter"
+)
+
+var SALE_UNITS = []string{SALE_UNIT_PIECE, SALE_UNIT_PACK, SALE_UNIT_BOX, SALE_UNIT_SET, SALE_UNIT_PAIR,
+	SALE_UNIT_KG, SALE_UNIT_TON, SALE_UNIT_LITER, SALE_UNIT_METER, SALE_UNIT_SQM}
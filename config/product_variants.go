@@ -0,0 +1,10 @@
+package config
+
+const (
+	// variant-y kesgitleyan attribute-lar (mysal renk, olceg)
+	PRODUCT_VARIANT_ATTRS_MAX = 3
+	// bir product-da in kop variant
+	PRODUCT_VARIANTS_MAX = 50
+	// her variant-yn in kop suraty
+	PRODUCT_VARIANT_IMAGES_MAX = 5
+)
@@ -0,0 +1,8 @@
+package config
+
+import "github.com/devzatruk/bizhubBackend/receiptservice"
+
+// .env-de RECEIPT_SECRET: kwitansiya barlag hash-y ucin HMAC acary.
+const RECEIPT_SECRET = "RECEIPT_SECRET"
+
+var ReceiptService = receiptservice.NewReceiptService()
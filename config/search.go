@@ -0,0 +1,26 @@
+package config
+
+import (
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/searchservice"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// search index: product/seller-lerin "search" term-leri yzygiderli tazelenyar,
+	// SEARCH_REINDEX_AFTER-dan kone bolanlary hem (brand/category/city ady uytgan bolup biler).
+	SEARCH_REINDEX       = "search_reindex"
+	SEARCH_REINDEX_EVERY = time.Minute * 10
+	SEARCH_REINDEX_AFTER = time.Hour * 24
+	SEARCH_REINDEX_LIMIT = 5000 // bir job-da in kop dokument
+	// /products/suggest: limit berilmese we in kop
+	SEARCH_SUGGEST_LIMIT     = 10
+	SEARCH_SUGGEST_MAX_LIMIT = 20
+)
+
+// suggest-de dine publish edilen product-lar we seller-ler.
+var SearchService = searchservice.NewSearchService().SetSuggestFilters(
+	bson.M{"status": STATUS_PUBLISHED},
+	bson.M{"status": SELLER_STATUS_PUBLISHED, "type": bson.M{"$ne": SELLER_TYPE_REPORTERBEE}},
+)
@@ -0,0 +1,18 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+const WITHDRAW_EXPIRES_AFTER_DEFAULT = time.Hour * 24
+
+// waiting withdraw request su wagtdan son expired bolyar we pul balance-a gaytarylyar.
+// .env-de WITHDRAW_EXPIRES_AFTER = "48h" yaly uytgedip bolyar.
+func WithdrawExpiresAfter() time.Duration {
+	expiresAfter, err := time.ParseDuration(os.Getenv("WITHDRAW_EXPIRES_AFTER"))
+	if err != nil || expiresAfter <= 0 {
+		return WITHDRAW_EXPIRES_AFTER_DEFAULT
+	}
+	return expiresAfter
+}
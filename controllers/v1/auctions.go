@@ -6,238 +6,635 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/devzatruk/bizhubBackend/auctionservice"
 	"github.com/devzatruk/bizhubBackend/config"
 	"github.com/devzatruk/bizhubBackend/helpers"
+	"github.com/devzatruk/bizhubBackend/ledger"
 	"github.com/devzatruk/bizhubBackend/models"
+	notificationmanager "github.com/devzatruk/bizhubBackend/notification_manager"
+	"github.com/devzatruk/bizhubBackend/ojologger"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 	"golang.org/x/net/context"
 )
 
-// yeterlik seller bolanda tazeden gowy test etmeli!
-// TODO: bulkwrite() yerine transaction etsek???
-func BidAuction(c *fiber.Ctx) error {
-	errRes := helpers.ErrorResponse("BidAuction")
-	auctionObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+var auctionsLogger = ojologger.LoggerService.Logger("Auctions v1")
+
+// placeBid() we proxy bid-ler ulanyar, yalnys bolsa haysy funksiyada we haysy code bilen
+// gaytarmalydygyny bilmek ucin.
+type auctionBidError struct {
+	Fn   string
+	Err  error
+	Code string
+}
+
+func (e *auctionBidError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Fn, e.Err)
+}
+
+func auctionBidErrorResponse(c *fiber.Ctx, errRes helpers.ResponseFunc, err error) error {
+	if bidErr, ok := err.(*auctionBidError); ok {
+		return c.JSON(errRes(bidErr.Fn, bidErr.Err, bidErr.Code))
+	}
+	return c.JSON(errRes("placeBid()", err, config.SERVER_ERROR))
+}
+
+type auctionBidResult struct {
+	Auction    models.BidAuctionFind
+	NewWinner  models.AuctionDetailNewWinner
+	LastWinner *models.AuctionDetailWinner // winners[]-dan cykarylan seller, yok bolsa nil
+	Balance    float64
+}
+
+// bid edyar we synanysygy (kabul edilen ya-da ret edilen) auction_bids ledger-e yazyar.
+func placeBid(ctx context.Context, auctionObjId primitive.ObjectID, sellerObjId primitive.ObjectID, sum float64, isProxy bool) (*auctionBidResult, error) {
+	log := auctionsLogger.Group("placeBid()")
+	result, bidErr := tryBid(ctx, auctionObjId, sellerObjId, sum)
+	bids := config.AuctionService.Bids
+	if bidErr != nil {
+		bidResult := auctionservice.BidRejected
+		if e, ok := bidErr.(*auctionBidError); ok && e.Fn == "BalanceNotEnough" {
+			bidResult = auctionservice.BidRejectedLowBalance
+		}
+		reason := bidErr.Error()
+		err := bids.Record(ctx, auctionObjId, sellerObjId, sum, isProxy, bidResult, &reason)
+		if err != nil {
+			log.Errorf("Bids.Record(%v): %v", bidResult, err)
+		}
+		return nil, bidErr
+	}
+	err := bids.Record(ctx, auctionObjId, sellerObjId, sum, isProxy, auctionservice.BidAccepted, nil)
 	if err != nil {
-		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+		log.Errorf("Bids.Record(%v): %v", auctionservice.BidAccepted, err)
 	}
-	var bidSum struct {
-		Sum float64 `json:"sum"`
+	if result.LastWinner != nil {
+		err = bids.MarkOutbid(ctx, auctionObjId, result.LastWinner.SellerId, result.LastWinner.LastBid)
+		if err != nil {
+			log.Errorf("Bids.MarkOutbid(): %v", err)
+		}
+		err = config.NotificationManager.NotifyAuctionSellers(ctx, auctionObjId, []primitive.ObjectID{result.LastWinner.SellerId}, "AuctionOutbidTitle",
+			notificationmanager.AuctionTranslation("AuctionOutbid", result.Auction.Heading, result.LastWinner.LastBid))
+		if err != nil {
+			log.Errorf("NotifyAuctionSellers(outbid): %v", err)
+		}
 	}
-	err = c.BodyParser(&bidSum)
+	return result, nil
+}
+
+// wallet barlagy, taze winner-in pulunu saklamak, cykarylan winner-e pul gaytarmak we
+// winners[]-i tazelemek bir mongo transaction-da edilyar: hemmesi bolyar ya-da hic biri.
+// Bir wagtda gelen bid-ler WriteConflict alyar, WithTransaction olary tazeden synanysyar.
+// Transaction ucin mongo replica set gerek.
+func tryBid(ctx context.Context, auctionObjId primitive.ObjectID, sellerObjId primitive.ObjectID, sum float64) (*auctionBidResult, error) {
+	session, err := config.MI.Client.StartSession()
 	if err != nil {
-		return c.JSON(errRes("BodyParser()", err, config.CANT_DECODE))
+		return nil, &auctionBidError{"StartSession()", err, config.DBQUERY_ERROR}
 	}
-	var sellerObjId primitive.ObjectID
-	err = helpers.GetCurrentSeller(c, &sellerObjId)
+	defer session.EndSession(ctx)
+	opts := options.Transaction().
+		SetReadConcern(readconcern.Snapshot()).
+		SetWriteConcern(writeconcern.New(writeconcern.WMajority()))
+	result, err := session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return bidInTransaction(sc, auctionObjId, sellerObjId, sum)
+	}, opts)
 	if err != nil {
-		return c.JSON(errRes("GetCurrentSeller()", err, config.AUTH_REQUIRED))
+		if _, ok := err.(*auctionBidError); ok {
+			return nil, err
+		}
+		return nil, &auctionBidError{"WithTransaction()", err, config.CANT_UPDATE}
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	bidResult := result.(*auctionBidResult)
+	announceBid(ctx, bidResult)
+	extendAuctionIfSniped(ctx, bidResult.Auction, bidResult.NewWinner.CreatedAt)
+	return bidResult, nil
+}
+
+// WithTransaction tazeden synanysanda hem caglyar, sonun ucin dine sc bilen okayar/yazyar.
+func bidInTransaction(sc mongo.SessionContext, auctionObjId primitive.ObjectID, sellerObjId primitive.ObjectID, sum float64) (*auctionBidResult, error) {
 	walletsColl := config.MI.DB.Collection(config.WALLETS)
-	walletResult := walletsColl.FindOne(ctx, bson.M{"seller_id": sellerObjId})
-	if err = walletResult.Err(); err != nil {
-		return c.JSON(errRes("FindOne()", err, config.NOT_FOUND))
-	}
 	var sellerWallet models.SellerWallet
-	err = walletResult.Decode(&sellerWallet)
+	err := walletsColl.FindOne(sc, bson.M{"seller_id": sellerObjId}).Decode(&sellerWallet)
 	if err != nil {
-		return c.JSON(errRes("Decode()", err, config.CANT_DECODE))
+		if err == mongo.ErrNoDocuments {
+			return nil, &auctionBidError{"FindOne()", err, config.NOT_FOUND}
+		}
+		return nil, err
 	}
-	if sellerWallet.Balance < float64(bidSum.Sum) || sellerWallet.ClosedAt != nil || sellerWallet.Status != config.SELLER_STATUS_PUBLISHED {
-		return c.JSON(errRes("BalanceNotEnough", errors.New("No enough funds."), config.NOT_ALLOWED))
+	if sellerWallet.Balance < sum || sellerWallet.ClosedAt != nil || sellerWallet.Status != config.SELLER_STATUS_PUBLISHED {
+		return nil, &auctionBidError{"BalanceNotEnough", errors.New("No enough funds."), config.NOT_ALLOWED}
 	}
 	for _, inAuction := range sellerWallet.InAuction {
 		if inAuction.AuctionId == auctionObjId {
-			return c.JSON(errRes("sellerWallet.InAuction", errors.New("Seller already in auction."), config.NOT_ALLOWED))
+			return nil, &auctionBidError{"sellerWallet.InAuction", errors.New("Seller already in auction."), config.NOT_ALLOWED}
 		}
-	} // suna cenli problemsiz gelen bolsa, bid etsin
+	}
 	auctionsColl := config.MI.DB.Collection(config.AUCTIONS)
-	aggregationArray := bson.A{
+	var auction models.BidAuctionFind
+	err = auctionsColl.FindOne(sc,
 		bson.M{
-			"$match": bson.M{
-				"_id": auctionObjId,
-				"$expr": bson.M{
-					"$and": bson.A{
-						bson.M{
-							"$gt": bson.A{"$$NOW", "$started_at"},
-						},
-						bson.M{
-							"$lt": bson.A{"$$NOW", "$finished_at"},
-						},
+			"_id": auctionObjId,
+			"$expr": bson.M{
+				"$and": bson.A{
+					bson.M{
+						"$gt": bson.A{"$$NOW", "$started_at"},
+					},
+					bson.M{
+						"$lt": bson.A{"$$NOW", "$finished_at"},
 					},
 				},
-				"is_finished": false,
 			},
+			"is_finished": false,
 		},
+		options.FindOne().SetProjection(bson.M{
+			"initial_minimal_bid": 1,
+			"minimal_bid":         1,
+			"winners":             1,
+			"participants":        1,
+			"heading":             1,
+			"finished_at":         1,
+			"anti_sniping":        1,
+			"rules":               1,
+		})).Decode(&auction)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, &auctionBidError{"NilObjectID", errors.New("Auction not found."), config.NOT_FOUND}
+		}
+		return nil, err
+	}
+	if auction.MinimalBid >= sum {
+		return nil, &auctionBidError{"Auction.MinimalBid > BidSum", errors.New("Bid sum must be more than minimal bid amount."), config.NOT_ALLOWED}
+	}
+	err = checkAuctionRules(sc, auction, sellerWallet, sum)
+	if err != nil {
+		return nil, err
+	} // suna cenli problemsiz gelen bolsa, bid etsin
+	// ledger wallet-leri in_auction[] uytgemezden on acylyar, yogsa bu bid opening_balance-a-da girer.
+	openSellerIds := []primitive.ObjectID{sellerObjId}
+	if int64(len(auction.Winners)) >= auction.Participants && len(auction.Winners) > 0 {
+		openSellerIds = append(openSellerIds, auction.Winners[len(auction.Winners)-1].SellerId)
+	}
+	err = config.Ledger.OpenWallets(sc, openSellerIds...)
+	if err != nil {
+		return nil, err
+	}
+	// seller can bid!
+	now := time.Now()
+	newWinner := models.AuctionDetailNewWinner{
+		SellerId:  sellerObjId,
+		LastBid:   sum,
+		CreatedAt: now,
+	}
+	// $slice bilen push we pop bir update-de: winners[] hic wagt participants-den kop bolmaz.
+	// minimal_bid filter-de: arada basga bid gecen bolsa update hic zat tapmaz.
+	updateResult, err := auctionsColl.UpdateOne(sc,
 		bson.M{
-			"$project": bson.M{
-				"initial_min_bid": 1,
-				"minimal_bid":     1,
-				"winners":         1,
-				"participants":    1,
-				"heading":         1,
+			"_id":         auction.Id,
+			"minimal_bid": auction.MinimalBid,
+			"is_finished": false,
+		},
+		bson.M{
+			"$set": bson.M{
+				"minimal_bid": sum,
+			},
+			"$push": bson.M{
+				"winners": bson.M{
+					"$each":     bson.A{newWinner},
+					"$position": 0,
+					"$slice":    auction.Participants,
+				},
 			},
+		})
+	if err != nil {
+		return nil, err
+	}
+	if updateResult.MatchedCount == 0 {
+		return nil, &auctionBidError{"UpdateOne(auction)", errors.New("Auction changed, please try again."), config.CANT_UPDATE}
+	}
+	// new winner's wallet must be updated! balance-y ledger uytgedyar, bu yerde dine in_auction.
+	updateResult, err = walletsColl.UpdateOne(sc,
+		bson.M{
+			"seller_id":             sellerObjId,
+			"in_auction.auction_id": bson.M{"$ne": auction.Id},
 		},
+		bson.M{
+			"$push": bson.M{
+				"in_auction": fiber.Map{
+					"auction_id": auction.Id,
+					"amount":     sum,
+					"name":       auction.Heading,
+				},
+			},
+		})
+	if err != nil {
+		return nil, err
+	}
+	if updateResult.MatchedCount == 0 {
+		return nil, &auctionBidError{"sellerWallet.InAuction", errors.New("Seller already in auction."), config.NOT_ALLOWED}
 	}
-	cursor, err := auctionsColl.Aggregate(ctx, aggregationArray)
+	holdResult, err := config.Ledger.Post(sc, ledger.AuctionHold(sellerObjId, sum).WithRef(config.AUCTIONS, auction.Id))
 	if err != nil {
-		return c.JSON(errRes("Aggregate()", err, config.DBQUERY_ERROR))
-	} // so the auction is valid!
-	defer cursor.Close(ctx)
-	var auction models.BidAuctionFind
-	if cursor.Next(ctx) {
-		err = cursor.Decode(&auction)
+		if err == ledger.ErrInsufficientFunds {
+			return nil, &auctionBidError{"BalanceNotEnough", errors.New("No enough funds."), config.NOT_ALLOWED}
+		}
+		return nil, err
+	}
+	result := &auctionBidResult{
+		Auction:   auction,
+		NewWinner: newWinner,
+		Balance:   holdResult.NewBalance(sellerObjId),
+	}
+	// last winner's wallet must be updated if he has to be removed from winners list.
+	if int64(len(auction.Winners)) >= auction.Participants && len(auction.Winners) > 0 {
+		lastWinner := auction.Winners[len(auction.Winners)-1]
+		updateResult, err = walletsColl.UpdateOne(sc,
+			bson.M{
+				"seller_id":             lastWinner.SellerId,
+				"in_auction.auction_id": auction.Id,
+			},
+			bson.M{
+				"$pull": bson.M{
+					"in_auction": bson.M{
+						"auction_id": auction.Id,
+					},
+				},
+			})
 		if err != nil {
-			return c.JSON(errRes("Decode()", err, config.CANT_DECODE))
+			return nil, err
+		}
+		if updateResult.MatchedCount == 0 {
+			return nil, &auctionBidError{"UpdateOne(lastWinner.wallet)", errors.New("Last winner's hold not found."), config.CANT_UPDATE}
 		}
+		_, err = config.Ledger.Post(sc, ledger.AuctionRelease(ledger.Hold{
+			SellerId: lastWinner.SellerId,
+			Amount:   lastWinner.LastBid,
+		}).WithRef(config.AUCTIONS, auction.Id))
+		if err != nil {
+			return nil, err
+		}
+		result.LastWinner = &lastWinner
 	}
-	if err = cursor.Err(); err != nil {
-		return c.JSON(errRes("cursor.Err()", err, config.DBQUERY_ERROR))
+	return result, nil
+}
+
+// admin tarapyndan goyulan auction.rules: increment, seller type we active auction cagi.
+func checkAuctionRules(ctx context.Context, auction models.BidAuctionFind, sellerWallet models.SellerWallet, sum float64) error {
+	if len(auction.Winners) == 0 && sum < auction.InitialMinimalBid {
+		return &auctionBidError{"Auction.InitialMinimalBid > BidSum", fmt.Errorf("First bid must be at least %v.", auction.InitialMinimalBid), config.AUCTION_BID_BELOW_INITIAL}
 	}
-	if auction.Id == primitive.NilObjectID {
-		return c.JSON(errRes("NilObjectID", errors.New("Auction not found."), config.NOT_FOUND))
+	if minBid := nextMinimalBid(auction); len(auction.Winners) > 0 && sum < minBid {
+		return &auctionBidError{"Rules.Increment", fmt.Errorf("Bid sum must be at least %v.", minBid), config.AUCTION_BID_INCREMENT_TOO_SMALL}
 	}
-	now := time.Now()
-	if auction.MinimalBid < bidSum.Sum { // seller can bid!
-		// fetch the last of winners in order to update some collections
-		var lastWinner models.AuctionDetailWinner
-		// update auctionsColl then update walletsColl
-		newWinner := models.AuctionDetailNewWinner{
-			SellerId:  sellerObjId,
-			LastBid:   bidSum.Sum,
-			CreatedAt: now,
+	rules := auction.Rules
+	if rules.MaxActivePerSeller > 0 && int64(len(sellerWallet.InAuction)) >= rules.MaxActivePerSeller {
+		return &auctionBidError{"Rules.MaxActivePerSeller", fmt.Errorf("Seller can be in at most %v auctions at once.", rules.MaxActivePerSeller), config.AUCTION_SELLER_LIMIT_REACHED}
+	}
+	if len(rules.SellerTypes) > 0 {
+		var seller models.Seller
+		err := config.MI.DB.Collection(config.SELLERS).FindOne(ctx, bson.M{"_id": sellerWallet.SellerId},
+			options.FindOne().SetProjection(bson.M{"type": 1})).Decode(&seller)
+		if err != nil {
+			return &auctionBidError{"FindOne(seller)", err, config.NOT_FOUND}
 		}
-		bulkModels := []mongo.WriteModel{
-			mongo.NewUpdateOneModel().
-				SetFilter(bson.M{"_id": auction.Id}).
-				SetUpdate(
-					bson.M{
-						"$set": bson.M{
-							"minimal_bid": bidSum.Sum,
-						},
-						"$push": bson.M{
-							"winners": bson.M{
-								"$each":     bson.A{newWinner},
-								"$position": 0,
-								// "$slice":    2,
-							},
-						},
-					},
-				),
+		if !rules.AllowsSellerType(seller.Type) {
+			return &auctionBidError{"Rules.SellerTypes", errors.New("Seller type not allowed in this auction."), config.AUCTION_SELLER_TYPE_NOT_ALLOWED}
 		}
-		if int64(len(auction.Winners)) == auction.Participants {
-			// fmt.Printf("\nBidAuction: len(auction.Winners[]) == auction.Participants\n")
+	}
+	return nil
+}
 
-			lastWinner = auction.Winners[len(auction.Winners)-1]
-			// fmt.Printf("\nBidAuction: lastWinner: %v\n", lastWinner)
+// anti-sniping: auction-yn sonky minutlarynda edilen bid finished_at-y uzaldyar we
+// auction_finished, auction_removed job-laryny taze wagta geciryar.
+func extendAuctionIfSniped(ctx context.Context, auction models.BidAuctionFind, bidAt time.Time) {
+	log := auctionsLogger.Group("extendAuctionIfSniped()")
+	extension := auction.AntiSniping.ExtensionFor(bidAt, auction.FinishedAt)
+	if extension == 0 {
+		return
+	}
+	newFinishedAt := auction.FinishedAt.Add(time.Duration(extension) * time.Minute)
+	auctionsColl := config.MI.DB.Collection(config.AUCTIONS)
+	// finished_at filter-de: bir wagtda gelen iki bid auction-y iki gezek uzaltmasyn
+	updateResult, err := auctionsColl.UpdateOne(ctx,
+		bson.M{
+			"_id":         auction.Id,
+			"finished_at": auction.FinishedAt,
+			"is_finished": false,
+		},
+		bson.M{
+			"$set": bson.M{
+				"finished_at": newFinishedAt,
+			},
+			"$inc": bson.M{
+				"anti_sniping.extended": extension,
+			},
+		})
+	if err != nil {
+		log.Errorf("UpdateOne(auction.finished_at): %v", err)
+		return
+	}
+	if updateResult.ModifiedCount == 0 {
+		return
+	}
+	err = config.OjoCronService.RescheduleJobsByGroup(auction.Id, config.AUCTION_FINISHED, newFinishedAt)
+	if err != nil {
+		log.Errorf("RescheduleJobsByGroup(%v): %v", config.AUCTION_FINISHED, err)
+	}
+	err = config.OjoCronService.RescheduleJobsByGroup(auction.Id, config.AUCTION_REMOVED, newFinishedAt.Add(config.AUCTION_REMOVE_AFTER))
+	if err != nil {
+		log.Errorf("RescheduleJobsByGroup(%v): %v", config.AUCTION_REMOVED, err)
+	}
+	config.AuctionService.Realtime.FinishedAtChanged(auctionservice.AuctionFinishedAtChanged{
+		AuctionId:  auction.Id,
+		FinishedAt: newFinishedAt,
+	})
+}
 
-			bulkModels = append(bulkModels,
-				mongo.NewUpdateOneModel().
-					SetFilter(bson.M{"_id": auction.Id}).
-					SetUpdate(
-						bson.M{
-							"$pop": bson.M{"winners": 1},
-						},
-					))
-		}
-		// opts := options.BulkWrite().SetOrdered(false)
-		updateRes, err := auctionsColl.BulkWrite(context.TODO(), bulkModels)
+// auction room-daky ahli seller-lere taze bid barada habar beryar.
+func announceBid(ctx context.Context, result *auctionBidResult) {
+	log := auctionsLogger.Group("announceBid()")
+	realtime := config.AuctionService.Realtime
+	realtime.BidPlaced(auctionservice.AuctionBidPlaced{
+		AuctionId: result.Auction.Id,
+		SellerId:  result.NewWinner.SellerId,
+		Sum:       result.NewWinner.LastBid,
+		CreatedAt: result.NewWinner.CreatedAt,
+	})
+	realtime.MinimalBidChanged(auctionservice.AuctionMinimalBidChanged{
+		AuctionId:  result.Auction.Id,
+		MinimalBid: result.NewWinner.LastBid,
+	})
+	if result.LastWinner != nil {
+		realtime.Outbid(auctionservice.AuctionOutbid{
+			AuctionId: result.Auction.Id,
+			SellerId:  result.LastWinner.SellerId,
+			LastBid:   result.LastWinner.LastBid,
+		})
+	}
+	participants, winners, err := config.AuctionService.Winners(ctx, result.Auction.Id)
+	if err != nil {
+		log.Errorf("Winners(): %v", err)
+		return
+	}
+	realtime.ParticipantsChanged(auctionservice.AuctionParticipantsChanged{
+		AuctionId:    result.Auction.Id,
+		Participants: participants,
+		Winners:      winners,
+	})
+}
+
+// auction rules boyunca kabul edilyan in az bid: ilkinji bid ucin initial_minimal_bid,
+// sonra minimal_bid + increment (azyndan AUCTION_MIN_BID_STEP).
+func nextMinimalBid(auction models.BidAuctionFind) float64 {
+	if len(auction.Winners) == 0 && auction.InitialMinimalBid > auction.MinimalBid {
+		return auction.InitialMinimalBid
+	}
+	next := auction.Rules.MinNextBid(auction.MinimalBid)
+	if next <= auction.MinimalBid {
+		next = auction.MinimalBid + config.AUCTION_MIN_BID_STEP
+	}
+	return next
+}
+
+// winners[]-dan cykarylan seller-in proxy bid-i bar bolsa, onun ugruna nextMinimalBid()
+// mukdarynda bid edyar. Taze bid basga bir seller-i cykarsa, zynjyr dowam edyar.
+func runProxyBids(ctx context.Context, auctionObjId primitive.ObjectID, outbid *models.AuctionDetailWinner) {
+	log := auctionsLogger.Group("runProxyBids()")
+	proxiesColl := config.MI.DB.Collection(config.AUCTION_PROXY_BIDS)
+	auctionsColl := config.MI.DB.Collection(config.AUCTIONS)
+	for round := 0; outbid != nil && round < config.AUCTION_MAX_PROXY_ROUNDS; round++ {
+		var proxy models.AuctionProxyBid
+		err := proxiesColl.FindOne(ctx, bson.M{
+			"auction_id": auctionObjId,
+			"seller_id":  outbid.SellerId,
+		}).Decode(&proxy)
 		if err != nil {
-			if updateRes.ModifiedCount == 0 {
-				return c.JSON(errRes("NewUpdateOneModel().PushWinner", err, config.CANT_UPDATE))
-			}
-			if updateRes.ModifiedCount == 1 {
-				return c.JSON(errRes("NewUpdateOneModel().PopWinner", err, config.CANT_UPDATE))
+			if err != mongo.ErrNoDocuments {
+				log.Errorf("FindOne(proxy): %v", err)
 			}
+			return
 		}
-		// fmt.Printf("\nBidAuction: auctionsColl.BulkWrite() successful.\n")
-
-		// new winner's wallet must be updated!
-		bulkModels = []mongo.WriteModel{
-			mongo.NewUpdateOneModel().
-				SetFilter(bson.M{"seller_id": newWinner.SellerId}).
-				SetUpdate(
-					bson.M{
-						"$inc": bson.M{
-							"balance": -newWinner.LastBid,
-						},
-						"$push": bson.M{
-							"in_auction": bson.M{
-								"$each": bson.A{fiber.Map{
-									"auction_id": auction.Id,
-									"amount":     newWinner.LastBid,
-									"name":       auction.Heading,
-								}},
-								// "$position": 0,
-							},
-						},
-					},
-				),
+		var auction models.BidAuctionFind
+		err = auctionsColl.FindOne(ctx, bson.M{"_id": auctionObjId}).Decode(&auction)
+		if err != nil {
+			log.Errorf("FindOne(auction): %v", err)
+			return
 		}
-		// last winner's wallet must be updated if he has to be removed from winners list.
-		if lastWinner.SellerId != primitive.NilObjectID {
-			bulkModels = append(bulkModels,
-				mongo.NewUpdateOneModel().
-					SetFilter(bson.M{"seller_id": lastWinner.SellerId}).
-					SetUpdate(
-						bson.M{
-							"$inc": bson.M{
-								"balance": lastWinner.LastBid,
-							},
-							"$pull": bson.M{
-								"in_auction": bson.M{
-									"auction_id": auction.Id,
-								},
-							},
-						},
-					),
-			)
+		nextBid := nextMinimalBid(auction)
+		if nextBid > proxy.MaxBid {
+			return
 		}
-		var retryCount int = 0
-	RetryPoint:
-		// fmt.Printf("\nBidAuction: retryCount: %v\n", retryCount)
-
-		updateRes, err = walletsColl.BulkWrite(context.TODO(), bulkModels)
-		// fmt.Printf("\nWalletsColl BulkWrite Result: %v\n", updateRes)
+		result, err := placeBid(ctx, auctionObjId, proxy.SellerId, nextBid, true)
 		if err != nil {
-			// fmt.Printf("\nBidAuction: walletsColl.BulkWrite() error!\n")
-
-			if retryCount == 2 {
-				return c.JSON(errRes("Retried walletsColl.BulkWrite() x 2", err, config.CANT_UPDATE))
-			}
-			if updateRes.ModifiedCount == 0 {
-				// retry 2 times only!
-				retryCount++
-				goto RetryPoint
-			}
-			if updateRes.ModifiedCount == 1 && len(bulkModels) == 2 {
-				// retry only second operation
-				_, err = walletsColl.BulkWrite(context.TODO(), bulkModels[1:])
-				if err != nil {
-					return c.JSON(errRes("Retry: BulkWrite()[1:]", err, config.CANT_UPDATE))
-				}
-			}
+			log.Errorf("placeBid(seller: %v, sum: %v): %v", proxy.SellerId.Hex(), nextBid, err)
+			return
 		}
-		// fmt.Printf("\nBidAuction: all updates successful!\n")
+		outbid = result.LastWinner
+	}
+}
 
-		return c.JSON(models.Response[fiber.Map]{
-			IsSuccess: true,
-			Result: fiber.Map{
-				"balance": sellerWallet.Balance - float64(newWinner.LastBid),
-				"in_auction": fiber.Map{
-					"auction_id": auction.Id,
-					"amount":     newWinner.LastBid,
-					"name":       auction.Heading,
-				},
+func BidAuction(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("BidAuction")
+	auctionObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	var bidSum struct {
+		Sum float64 `json:"sum"`
+	}
+	err = c.BodyParser(&bidSum)
+	if err != nil {
+		return c.JSON(errRes("BodyParser()", err, config.CANT_DECODE))
+	}
+	var sellerObjId primitive.ObjectID
+	err = helpers.GetCurrentSeller(c, &sellerObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentSeller()", err, config.AUTH_REQUIRED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := placeBid(ctx, auctionObjId, sellerObjId, bidSum.Sum, false)
+	if err != nil {
+		return auctionBidErrorResponse(c, errRes, err)
+	}
+	runProxyBids(ctx, auctionObjId, result.LastWinner)
+
+	return c.JSON(models.Response[fiber.Map]{
+		IsSuccess: true,
+		Result: fiber.Map{
+			"balance": result.Balance,
+			"in_auction": fiber.Map{
+				"auction_id": result.Auction.Id,
+				"amount":     result.NewWinner.LastBid,
+				"name":       result.Auction.Heading,
 			},
-		})
+		},
+	})
+}
+
+func GetProxyBid(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("GetProxyBid")
+	auctionObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	var sellerObjId primitive.ObjectID
+	err = helpers.GetCurrentSeller(c, &sellerObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentSeller()", err, config.AUTH_REQUIRED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	proxiesColl := config.MI.DB.Collection(config.AUCTION_PROXY_BIDS)
+	var proxy models.AuctionProxyBid
+	err = proxiesColl.FindOne(ctx, bson.M{
+		"auction_id": auctionObjId,
+		"seller_id":  sellerObjId,
+	}).Decode(&proxy)
+	if err != nil {
+		return c.JSON(errRes("FindOne(proxy)", err, config.NOT_FOUND))
 	}
-	return c.JSON(errRes("Auction.MinimalBid > BidSum", errors.New("Bid sum must be more than minimal bid amount."), config.NOT_ALLOWED))
+	return c.JSON(models.Response[models.AuctionProxyBid]{
+		IsSuccess: true,
+		Result:    proxy,
+	})
+}
+
+// Seller auction ucin in kop tolejek mocberini (max_sum) bellap goyyar. Seller winners[]-dan
+// cykarylanda server onun ugruna awtomatik bid edyar. max_sum seller-in hazirki bid-inden
+// pes bolup bilmez, yone azaldylyp bilner.
+func SetProxyBid(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("SetProxyBid")
+	auctionObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	var payload struct {
+		MaxSum float64 `json:"max_sum"`
+	}
+	err = c.BodyParser(&payload)
+	if err != nil {
+		return c.JSON(errRes("BodyParser()", err, config.CANT_DECODE))
+	}
+	if payload.MaxSum <= 0 {
+		return c.JSON(errRes("MaxSum", errors.New("Max sum must be positive."), config.NOT_ALLOWED))
+	}
+	var sellerObjId primitive.ObjectID
+	err = helpers.GetCurrentSeller(c, &sellerObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentSeller()", err, config.AUTH_REQUIRED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	auctionsColl := config.MI.DB.Collection(config.AUCTIONS)
+	now := time.Now()
+	var auction models.BidAuctionFind
+	err = auctionsColl.FindOne(ctx, bson.M{
+		"_id":         auctionObjId,
+		"is_finished": false,
+		"finished_at": bson.M{"$gt": now},
+	}).Decode(&auction)
+	if err != nil {
+		return c.JSON(errRes("FindOne(auction)", err, config.NOT_FOUND))
+	}
+	var currentBid float64
+	for _, winner := range auction.Winners {
+		if winner.SellerId == sellerObjId {
+			currentBid = winner.LastBid
+			break
+		}
+	}
+	if payload.MaxSum < currentBid {
+		return c.JSON(errRes("MaxSum < LastBid", errors.New("Max sum can't be less than your current bid."), config.NOT_ALLOWED))
+	}
+	proxiesColl := config.MI.DB.Collection(config.AUCTION_PROXY_BIDS)
+	_, err = proxiesColl.UpdateOne(ctx,
+		bson.M{
+			"auction_id": auctionObjId,
+			"seller_id":  sellerObjId,
+		},
+		bson.M{
+			"$set": bson.M{
+				"max_bid":    payload.MaxSum,
+				"updated_at": now,
+			},
+			"$setOnInsert": bson.M{
+				"created_at": now,
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return c.JSON(errRes("UpdateOne(proxy)", err, config.CANT_UPDATE))
+	}
+	response := fiber.Map{
+		"max_bid": payload.MaxSum,
+	}
+	// seller hazir winners[]-da yok bolsa we auction baslan bolsa, ilkinji bid-i hem edelin.
+	if nextBid := nextMinimalBid(auction); currentBid == 0 && auction.StartedAt.Before(now) && nextBid <= payload.MaxSum {
+		result, err := placeBid(ctx, auctionObjId, sellerObjId, nextBid, true)
+		if err != nil {
+			return auctionBidErrorResponse(c, errRes, err)
+		}
+		runProxyBids(ctx, auctionObjId, result.LastWinner)
+		response["balance"] = result.Balance
+		response["in_auction"] = fiber.Map{
+			"auction_id": result.Auction.Id,
+			"amount":     result.NewWinner.LastBid,
+			"name":       result.Auction.Heading,
+		}
+	}
+
+	return c.JSON(models.Response[fiber.Map]{
+		IsSuccess: true,
+		Result:    response,
+	})
+}
+
+// Proxy bid-i ayyryar. Seller-in eyyam eden bid-leri ozgermeyar.
+func RemoveProxyBid(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("RemoveProxyBid")
+	auctionObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	var sellerObjId primitive.ObjectID
+	err = helpers.GetCurrentSeller(c, &sellerObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentSeller()", err, config.AUTH_REQUIRED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	auctionsColl := config.MI.DB.Collection(config.AUCTIONS)
+	count, err := auctionsColl.CountDocuments(ctx, bson.M{
+		"_id":         auctionObjId,
+		"is_finished": false,
+		"finished_at": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return c.JSON(errRes("CountDocuments(auction)", err, config.DBQUERY_ERROR))
+	}
+	if count == 0 {
+		return c.JSON(errRes("auction.IsFinished", errors.New("Auction is finished."), config.NOT_ALLOWED))
+	}
+	proxiesColl := config.MI.DB.Collection(config.AUCTION_PROXY_BIDS)
+	deleteResult, err := proxiesColl.DeleteOne(ctx, bson.M{
+		"auction_id": auctionObjId,
+		"seller_id":  sellerObjId,
+	})
+	if err != nil {
+		return c.JSON(errRes("DeleteOne(proxy)", err, config.CANT_DELETE))
+	}
+	if deleteResult.DeletedCount == 0 {
+		return c.JSON(errRes("DeletedCount", errors.New("Proxy bid not found."), config.NOT_FOUND))
+	}
+	return c.JSON(models.Response[string]{
+		IsSuccess: true,
+		Result:    config.DELETED,
+	})
 }
 
 func GetAuctionDetail(c *fiber.Ctx) error {
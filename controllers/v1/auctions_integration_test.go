@@ -0,0 +1,246 @@
+//go:build integration
+
+package v1
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/ledger"
+	"github.com/devzatruk/bizhubBackend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Bir wagtda gelen bid-leri barlayar: vagtlayyn auction we wallet-ler doredilyar, ahli seller-ler
+// bir wagtda bid edyar, sonra invariant-lar barlanyar we hemme zat pozulyar.
+// Transaction ucin mongo replica set gerek. go test paket papkasynda isleyar, sonun ucin .env okalmayar
+// (APP_ENV=production) we firebase file yoly env-den berilyar:
+//
+//	APP_ENV=production FIREBASE_CREDENTIALS=$PWD/config/firebase-config.json \
+//	MONGO_URI="mongodb://localhost:27017/?replicaSet=rs0" DB=bizhub_test \
+//	go test -tags integration -run TestConcurrentBidding ./controllers/v1/
+
+const (
+	biddingSellers        = 20
+	biddingBidsPerSeller  = 5
+	biddingParticipants   = 3
+	biddingInitialBalance = 100.0
+	biddingBidStep        = 1.0
+)
+
+type biddingWallet struct {
+	SellerId  primitive.ObjectID `bson:"seller_id"`
+	Balance   float64            `bson:"balance"`
+	InAuction []struct {
+		AuctionId primitive.ObjectID `bson:"auction_id"`
+		Amount    float64            `bson:"amount"`
+	} `bson:"in_auction"`
+}
+
+func connectIntegrationDB(t *testing.T) {
+	uri := os.Getenv("MONGO_URI")
+	if len(uri) == 0 {
+		t.Skip("MONGO_URI not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("Connect(): %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	dbName := os.Getenv("DB")
+	if len(dbName) == 0 {
+		dbName = "bizhub_test"
+	}
+	config.MI = &config.MongoInstance{
+		Client: client,
+		DB:     client.Database(dbName),
+	}
+	config.AuctionService.Init(config.MI.DB, config.OjoWS)
+	config.Ledger.Init(config.MI.DB)
+	config.NotificationManager.SetDatabase(config.MI.DB)
+}
+
+func TestConcurrentBidding(t *testing.T) {
+	connectIntegrationDB(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	auctionsColl := config.MI.DB.Collection(config.AUCTIONS)
+	walletsColl := config.MI.DB.Collection(config.WALLETS)
+	bidsColl := config.MI.DB.Collection(config.AUCTION_BIDS)
+	entriesColl := config.MI.DB.Collection(ledger.CollEntries)
+
+	now := time.Now()
+	auctionId := primitive.NewObjectID()
+	heading := models.Translation{
+		En: "concurrent bidding test",
+		Tm: "concurrent bidding test",
+		Tr: "concurrent bidding test",
+		Ru: "concurrent bidding test",
+	}
+	_, err := auctionsColl.InsertOne(ctx, bson.M{
+		"_id":                 auctionId,
+		"heading":             heading,
+		"initial_minimal_bid": 0,
+		"minimal_bid":         0,
+		"participants":        biddingParticipants,
+		"winners":             bson.A{},
+		"started_at":          now.Add(-time.Minute),
+		"finished_at":         now.Add(time.Hour),
+		"is_finished":         false,
+		"status":              config.STATUS_PUBLISHED,
+	})
+	if err != nil {
+		t.Fatalf("InsertOne(auction): %v", err)
+	}
+	sellerIds := make([]primitive.ObjectID, biddingSellers)
+	wallets := make([]interface{}, biddingSellers)
+	for i := range sellerIds {
+		sellerIds[i] = primitive.NewObjectID()
+		wallets[i] = models.SellerWallet{
+			SellerId:  sellerIds[i],
+			Balance:   biddingInitialBalance,
+			CreatedAt: now,
+			Status:    config.SELLER_STATUS_PUBLISHED,
+			InAuction: make([]models.InAuctionObject, 0),
+		}
+	}
+	t.Cleanup(func() {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		auctionsColl.DeleteOne(cleanupCtx, bson.M{"_id": auctionId})
+		walletsColl.DeleteMany(cleanupCtx, bson.M{"seller_id": bson.M{"$in": sellerIds}})
+		bidsColl.DeleteMany(cleanupCtx, bson.M{"auction_id": auctionId})
+		accounts := make([]string, 0, len(sellerIds)*3)
+		for _, sellerId := range sellerIds {
+			accounts = append(accounts, ledger.SellerAvailable(sellerId), ledger.SellerHeld(sellerId), ledger.SellerWithdrawPending(sellerId))
+		}
+		entriesColl.DeleteMany(cleanupCtx, bson.M{"lines.account": bson.M{"$in": accounts}})
+	})
+	_, err = walletsColl.InsertMany(ctx, wallets)
+	if err != nil {
+		t.Fatalf("InsertMany(wallets): %v", err)
+	}
+
+	var counter, accepted int64
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, sellerId := range sellerIds {
+		wg.Add(1)
+		go func(sellerId primitive.ObjectID) {
+			defer wg.Done()
+			<-start
+			for i := 0; i < biddingBidsPerSeller; i++ {
+				sum := float64(atomic.AddInt64(&counter, 1)) * biddingBidStep
+				if _, err := placeBid(ctx, auctionId, sellerId, sum, false); err == nil {
+					atomic.AddInt64(&accepted, 1)
+				}
+			}
+		}(sellerId)
+	}
+	close(start)
+	wg.Wait()
+	if accepted == 0 {
+		t.Fatalf("no bid was accepted")
+	}
+
+	var auction models.BidAuctionFind
+	err = auctionsColl.FindOne(ctx, bson.M{"_id": auctionId}).Decode(&auction)
+	if err != nil {
+		t.Fatalf("FindOne(auction): %v", err)
+	}
+	cursor, err := walletsColl.Find(ctx, bson.M{"seller_id": bson.M{"$in": sellerIds}})
+	if err != nil {
+		t.Fatalf("Find(wallets): %v", err)
+	}
+	var finalWallets []biddingWallet
+	if err = cursor.All(ctx, &finalWallets); err != nil {
+		t.Fatalf("cursor.All(wallets): %v", err)
+	}
+	checkBiddingInvariants(t, auction, finalWallets)
+	// wallets.balance we in_auction[] ledger-in proyeksiyasy bolmaly.
+	for _, wallet := range finalWallets {
+		journaled, err := config.Ledger.Balance(ctx, ledger.SellerAvailable(wallet.SellerId))
+		if err != nil {
+			t.Fatalf("Ledger.Balance(): %v", err)
+		}
+		if journaled != wallet.Balance {
+			t.Errorf("seller %v: balance %v != ledger %v", wallet.SellerId.Hex(), wallet.Balance, journaled)
+		}
+		journaledHeld, err := config.Ledger.Balance(ctx, ledger.SellerHeld(wallet.SellerId))
+		if err != nil {
+			t.Fatalf("Ledger.Balance(): %v", err)
+		}
+		held := float64(0)
+		for _, inAuction := range wallet.InAuction {
+			held += inAuction.Amount
+		}
+		if journaledHeld != held {
+			t.Errorf("seller %v: in_auction %v != ledger held %v", wallet.SellerId.Hex(), held, journaledHeld)
+		}
+	}
+}
+
+func checkBiddingInvariants(t *testing.T, auction models.BidAuctionFind, wallets []biddingWallet) {
+	if int64(len(auction.Winners)) > auction.Participants {
+		t.Errorf("winners: %v > participants: %v", len(auction.Winners), auction.Participants)
+	}
+	winners := map[primitive.ObjectID]float64{}
+	maxBid := float64(0)
+	for i, winner := range auction.Winners {
+		if _, ok := winners[winner.SellerId]; ok {
+			t.Errorf("seller %v is in winners[] twice", winner.SellerId.Hex())
+		}
+		winners[winner.SellerId] = winner.LastBid
+		if i > 0 && auction.Winners[i-1].LastBid <= winner.LastBid {
+			t.Errorf("winners[] not sorted: %v <= %v", auction.Winners[i-1].LastBid, winner.LastBid)
+		}
+		if winner.LastBid > maxBid {
+			maxBid = winner.LastBid
+		}
+	}
+	if auction.MinimalBid != maxBid {
+		t.Errorf("minimal_bid: %v != highest winner bid: %v", auction.MinimalBid, maxBid)
+	}
+	holders := 0
+	for _, wallet := range wallets {
+		if wallet.Balance < 0 {
+			t.Errorf("seller %v has negative balance: %v", wallet.SellerId.Hex(), wallet.Balance)
+		}
+		held := float64(0)
+		holds := 0
+		for _, inAuction := range wallet.InAuction {
+			if inAuction.AuctionId == auction.Id {
+				held += inAuction.Amount
+				holds++
+			}
+		}
+		if wallet.Balance+held != biddingInitialBalance {
+			t.Errorf("seller %v: balance %v + held %v != initial %v", wallet.SellerId.Hex(), wallet.Balance, held, biddingInitialBalance)
+		}
+		lastBid, isWinner := winners[wallet.SellerId]
+		switch {
+		case holds > 1:
+			t.Errorf("seller %v has %v holds", wallet.SellerId.Hex(), holds)
+		case isWinner && held != lastBid:
+			t.Errorf("winner %v: held %v != last_bid %v", wallet.SellerId.Hex(), held, lastBid)
+		case !isWinner && holds > 0:
+			t.Errorf("seller %v has a hold but is not a winner", wallet.SellerId.Hex())
+		}
+		if holds > 0 {
+			holders++
+		}
+	}
+	if holders != len(auction.Winners) {
+		t.Errorf("wallets with hold: %v != winners: %v", holders, len(auction.Winners))
+	}
+}
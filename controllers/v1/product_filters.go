@@ -0,0 +1,521 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/helpers"
+	"github.com/devzatruk/bizhubBackend/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// bir text attribute ucin facet-de in kop gorkezilyan baha.
+const attributeFacetValuesLimit = 50
+
+type productFilterError struct {
+	Fn   string
+	Err  error
+	Code string
+}
+
+func (e *productFilterError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Fn, e.Err)
+}
+
+func productFilterErrorResponse(c *fiber.Ctx, errRes helpers.ResponseFunc, err error) error {
+	if filterErr, ok := err.(*productFilterError); ok {
+		return c.JSON(errRes(filterErr.Fn, filterErr.Err, filterErr.Code))
+	}
+	return c.JSON(errRes("productFilter", err, config.SERVER_ERROR))
+}
+
+// ["_id", ...] sekilli query-ni ObjectID-lere owuryar.
+func objectIdsFromQuery(c *fiber.Ctx, name string) (bson.A, error) {
+	query := c.Query(name, "all")
+	if query == "all" {
+		return nil, nil
+	}
+	var ids = []string{}
+	err := json.Unmarshal([]byte(query), &ids)
+	if err != nil {
+		return nil, &productFilterError{fmt.Sprintf("json.Unmarshal(%v)", name), err, config.CANT_DECODE}
+	}
+	var objIds = bson.A{}
+	for _, id := range ids {
+		objId, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, &productFilterError{fmt.Sprintf("ObjectIDFromHex(%v)", name), err, config.CANT_DECODE}
+		}
+		objIds = append(objIds, objId)
+	}
+	return objIds, nil
+}
+
+// FilterProduct we GetProductsFilterAggregations ucin umumy stage-ler:
+// cities bar bolsa seller lookup, son $match.
+func productFilterStages(ctx context.Context, c *fiber.Ctx) (bson.A, error) {
+	match := bson.M{"status": config.STATUS_PUBLISHED}
+	aggregationArray := bson.A{}
+	for _, field := range []struct{ query, key string }{
+		{"categories", "category_id"},
+		{"brands", "brand_id"},
+		{"sellers", "seller_id"},
+	} {
+		ids, err := objectIdsFromQuery(c, field.query)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) != 0 {
+			match[field.key] = bson.M{"$in": ids}
+		}
+	}
+
+	cities, err := objectIdsFromQuery(c, "cities")
+	if err != nil {
+		return nil, err
+	}
+	if len(cities) != 0 {
+		match["seller.city_id"] = bson.M{
+			"$in": cities,
+		}
+		aggregationArray = append(aggregationArray, bson.M{
+			"$lookup": bson.M{
+				"from":         "sellers",
+				"localField":   "seller_id",
+				"foreignField": "_id",
+				"as":           "seller",
+			},
+		}, bson.M{
+			"$unwind": bson.M{
+				"path": "$seller",
+			},
+		})
+	}
+
+	// price: in arzan baha, AddNewProduct/EditProduct price_tiers we variants-den yazyar.
+	priceQuery := c.Query("price", "none")
+	if priceQuery != "none" {
+		var price = []any{}
+		err := json.Unmarshal([]byte(priceQuery), &price)
+		if err != nil {
+			return nil, &productFilterError{"json.Unmarshal(price)", err, config.CANT_DECODE}
+		}
+		if len(price) == 2 {
+			_p := bson.M{}
+			if price[0] != "none" {
+				_p["$gte"] = price[0]
+			}
+			if price[1] != "none" {
+				_p["$lte"] = price[1]
+			}
+			if len(_p) != 0 {
+				match["price"] = _p
+			}
+		}
+	}
+
+	attrConditions, err := productAttrConditions(ctx, c.Query("attrs", "none"))
+	if err != nil {
+		return nil, err
+	}
+	if len(attrConditions) != 0 {
+		match["$and"] = attrConditions
+	}
+	return append(aggregationArray, bson.M{"$match": match}), nil
+}
+
+// attrs=[{"attr_id":"..","values":["Gyzyl"]},{"attr_id":"..","min":4,"max":16,"unit":"GB"}]
+func productAttrConditions(ctx context.Context, attrsQuery string) (bson.A, error) {
+	if attrsQuery == "none" {
+		return nil, nil
+	}
+	var filters = []models.ProductAttrFilter{}
+	err := json.Unmarshal([]byte(attrsQuery), &filters)
+	if err != nil {
+		return nil, &productFilterError{"json.Unmarshal(attrs)", err, config.CANT_DECODE}
+	}
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	attrIds := bson.A{}
+	for _, filter := range filters {
+		attrIds = append(attrIds, filter.Id)
+	}
+	cursor, err := config.MI.DB.Collection(config.ATTRIBUTES).Find(ctx, bson.M{"_id": bson.M{"$in": attrIds}})
+	if err != nil {
+		return nil, &productFilterError{"Find(attributes)", err, config.DBQUERY_ERROR}
+	}
+	var attributes []models.NewAttribute
+	if err = cursor.All(ctx, &attributes); err != nil {
+		return nil, &productFilterError{"cursor.All(attributes)", err, config.CANT_DECODE}
+	}
+	attributesById := map[primitive.ObjectID]models.NewAttribute{}
+	for _, attribute := range attributes {
+		attributesById[attribute.Id] = attribute
+	}
+
+	conditions := bson.A{}
+	for _, filter := range filters {
+		attribute, ok := attributesById[filter.Id]
+		if !ok {
+			return nil, &productFilterError{"FindOne(attribute)", fmt.Errorf("Attribute %v not found.", filter.Id.Hex()), config.NOT_FOUND}
+		}
+		if len(filter.Values) != 0 {
+			match := bson.M{
+				"$elemMatch": bson.M{
+					"attr_id": filter.Id,
+					"value":   bson.M{"$in": filter.Values},
+				},
+			}
+			// variant-yn bahasy hem gecyar (mysal renk dine variant-da)
+			conditions = append(conditions, bson.M{
+				"$or": bson.A{
+					bson.M{"attrs": match},
+					bson.M{"variants": bson.M{"$elemMatch": bson.M{"attrs": match}}},
+				},
+			})
+			continue
+		}
+		if filter.Min == nil && filter.Max == nil {
+			return nil, &productFilterError{"ProductAttrFilter", fmt.Errorf("Attribute %v has no values or range.", filter.Id.Hex()), config.QUERY_NOT_PROVIDED}
+		}
+		if !attribute.IsNumber {
+			return nil, &productFilterError{"ProductAttrFilter", fmt.Errorf("Attribute %v is not a number.", filter.Id.Hex()), config.NOT_ALLOWED}
+		}
+		condition, err := numberAttrCondition(attribute, filter)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+// her product oz birliginde (units_array[unit_index]) yazylan: bahalar filter
+// birligine owrulip denesdirilyar, owrup bolmayan birlikli product-lar gecmeyar.
+func numberAttrCondition(attribute models.NewAttribute, filter models.ProductAttrFilter) (bson.M, error) {
+	unit := filter.Unit
+	if unit == "" && len(attribute.UnitsArray) > 0 {
+		unit = attribute.UnitsArray[0]
+	}
+	factors := bson.A{}
+	convertible := false
+	for _, from := range attribute.UnitsArray {
+		factor, ok := helpers.UnitFactor(from, unit)
+		if ok {
+			factors = append(factors, factor)
+			convertible = true
+		} else {
+			factors = append(factors, nil)
+		}
+	}
+	if len(attribute.UnitsArray) == 0 {
+		factors = append(factors, 1)
+		convertible = filter.Unit == ""
+	}
+	if !convertible {
+		return nil, &productFilterError{"UnitFactor()", fmt.Errorf("Unit %v not valid for attribute %v.", filter.Unit, filter.Id.Hex()), config.NOT_ALLOWED}
+	}
+	value := bson.M{
+		"$multiply": bson.A{
+			bson.M{
+				"$convert": bson.M{
+					"input": bson.M{
+						"$cond": bson.A{
+							bson.M{"$eq": bson.A{bson.M{"$type": "$$this.value"}, "string"}},
+							bson.M{"$replaceAll": bson.M{"input": bson.M{"$trim": bson.M{"input": "$$this.value"}}, "find": ",", "replacement": "."}},
+							"$$this.value",
+						},
+					},
+					"to":      "double",
+					"onError": nil,
+					"onNull":  nil,
+				},
+			},
+			bson.M{"$arrayElemAt": bson.A{factors, bson.M{"$ifNull": bson.A{"$$this.unit_index", 0}}}},
+		},
+	}
+	cond := bson.A{bson.M{"$eq": bson.A{"$$this.attr_id", filter.Id}}, bson.M{"$ne": bson.A{value, nil}}}
+	if filter.Min != nil {
+		cond = append(cond, bson.M{"$gte": bson.A{value, *filter.Min}})
+	}
+	if filter.Max != nil {
+		cond = append(cond, bson.M{"$lte": bson.A{value, *filter.Max}})
+	}
+	hasMatch := func(attrs string) bson.M {
+		return bson.M{
+			"$gt": bson.A{
+				bson.M{
+					"$size": bson.M{
+						"$filter": bson.M{
+							"input": bson.M{"$ifNull": bson.A{attrs, bson.A{}}},
+							"cond":  bson.M{"$and": cond},
+						},
+					},
+				},
+				0,
+			},
+		}
+	}
+	// text filter yaly variant-yn bahasy hem gecyar.
+	return bson.M{
+		"$expr": bson.M{
+			"$or": bson.A{
+				hasMatch("$attrs"),
+				bson.M{
+					"$anyElementTrue": bson.A{
+						bson.M{
+							"$map": bson.M{
+								"input": bson.M{"$ifNull": bson.A{"$variants", bson.A{}}},
+								"as":    "variant",
+								"in":    hasMatch("$$variant.attrs"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+func attrNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		number, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(v), ",", ".", 1), 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+// attrs-y facet ucin birmenzes sekle getiryar, $setUnion dine su field-ler boyunca denesdirer.
+func facetAttrs(input interface{}) bson.M {
+	return bson.M{
+		"$map": bson.M{
+			"input": input,
+			"in": bson.M{
+				"attr_id":    "$$this.attr_id",
+				"value":      "$$this.value",
+				"unit_index": "$$this.unit_index",
+			},
+		},
+	}
+}
+
+// FilterProduct-yn netijesi ucin facet-ler: category, brand, city we attribute bahalary boyunca sanlar.
+func GetProductsFilterAggregations(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Mobile.GetProductsFilterAggregations")
+	culture := helpers.GetCultureFromQuery(c)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	aggregationArray, err := productFilterStages(ctx, c)
+	if err != nil {
+		return productFilterErrorResponse(c, errRes, err)
+	}
+	nameLookup := func(from string, name interface{}) bson.M {
+		return bson.M{
+			"$lookup": bson.M{
+				"from":         from,
+				"localField":   "_id",
+				"foreignField": "_id",
+				"as":           "detail",
+				"pipeline":     bson.A{bson.M{"$project": bson.M{"name": name}}},
+			},
+		}
+	}
+	facetTail := bson.A{
+		bson.M{"$project": bson.M{"count": 1, "name": bson.M{"$first": "$detail.name"}}},
+		bson.M{"$sort": bson.M{"count": -1}},
+	}
+	aggregationArray = append(aggregationArray, bson.M{
+		"$facet": bson.M{
+			"total": bson.A{bson.M{"$count": "count"}},
+			"categories": append(bson.A{
+				bson.M{"$group": bson.M{"_id": "$category_id", "count": bson.M{"$sum": 1}}},
+				nameLookup(config.CATEGORIES, culture.Stringf("$name.%v")),
+			}, facetTail...),
+			"brands": append(bson.A{
+				bson.M{"$group": bson.M{"_id": "$brand_id", "count": bson.M{"$sum": 1}}},
+				nameLookup(config.BRANDS, 1),
+			}, facetTail...),
+			"cities": append(bson.A{
+				bson.M{
+					"$lookup": bson.M{
+						"from":         config.SELLERS,
+						"localField":   "seller_id",
+						"foreignField": "_id",
+						"as":           "seller_city",
+						"pipeline":     bson.A{bson.M{"$project": bson.M{"city_id": 1}}},
+					},
+				},
+				bson.M{"$group": bson.M{"_id": bson.M{"$first": "$seller_city.city_id"}, "count": bson.M{"$sum": 1}}},
+				nameLookup(config.CITIES, culture.Stringf("$name.%v")),
+			}, facetTail...),
+			// product-yn we variant-laryn bahalary: $setUnion bilen her product bir baha bir gezek sanalyar.
+			"attributes": bson.A{
+				bson.M{
+					"$project": bson.M{
+						"facet_attrs": bson.M{
+							"$setUnion": bson.A{
+								facetAttrs(bson.M{"$ifNull": bson.A{"$attrs", bson.A{}}}),
+								facetAttrs(bson.M{
+									"$reduce": bson.M{
+										"input":        bson.M{"$ifNull": bson.A{"$variants", bson.A{}}},
+										"initialValue": bson.A{},
+										"in":           bson.M{"$concatArrays": bson.A{"$$value", bson.M{"$ifNull": bson.A{"$$this.attrs", bson.A{}}}}},
+									},
+								}),
+							},
+						},
+					},
+				},
+				bson.M{"$unwind": bson.M{"path": "$facet_attrs"}},
+				bson.M{
+					"$group": bson.M{
+						"_id": bson.M{
+							"attr_id":    "$facet_attrs.attr_id",
+							"value":      "$facet_attrs.value",
+							"unit_index": "$facet_attrs.unit_index",
+						},
+						"count": bson.M{"$sum": 1},
+					},
+				},
+			},
+		},
+	})
+	cursor, err := config.MI.DB.Collection(config.PRODUCTS).Aggregate(ctx, aggregationArray)
+	if err != nil {
+		return c.JSON(errRes("Aggregate()", err, config.DBQUERY_ERROR))
+	}
+	defer cursor.Close(ctx)
+	var facets struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Categories []models.FacetCount `bson:"categories"`
+		Brands     []models.FacetCount `bson:"brands"`
+		Cities     []models.FacetCount `bson:"cities"`
+		Attributes []struct {
+			Id struct {
+				AttrId    primitive.ObjectID `bson:"attr_id"`
+				Value     interface{}        `bson:"value"`
+				UnitIndex int64              `bson:"unit_index"`
+			} `bson:"_id"`
+			Count int64 `bson:"count"`
+		} `bson:"attributes"`
+	}
+	if !cursor.Next(ctx) {
+		return c.JSON(errRes("cursor.Next()", errors.New("Facets not found."), config.DBQUERY_ERROR))
+	}
+	if err = cursor.Decode(&facets); err != nil {
+		return c.JSON(errRes("Decode(facets)", err, config.CANT_DECODE))
+	}
+
+	result := models.ProductsFilterAggregations{
+		Categories: facets.Categories,
+		Brands:     facets.Brands,
+		Cities:     facets.Cities,
+		Attributes: []models.AttributeFacet{},
+	}
+	if len(facets.Total) > 0 {
+		result.Total = facets.Total[0].Count
+	}
+	attrIds := bson.A{}
+	for _, bucket := range facets.Attributes {
+		attrIds = append(attrIds, bucket.Id.AttrId)
+	}
+	if len(attrIds) != 0 {
+		attrsCursor, err := config.MI.DB.Collection(config.ATTRIBUTES).Aggregate(ctx, bson.A{
+			bson.M{"$match": bson.M{"_id": bson.M{"$in": attrIds}}},
+			bson.M{
+				"$project": bson.M{
+					"name":        culture.Stringf("$name.%v"),
+					"is_number":   1,
+					"units_array": 1,
+				},
+			},
+			bson.M{"$sort": bson.M{"name": 1}},
+		})
+		if err != nil {
+			return c.JSON(errRes("Aggregate(attributes)", err, config.DBQUERY_ERROR))
+		}
+		defer attrsCursor.Close(ctx)
+		if err = attrsCursor.All(ctx, &result.Attributes); err != nil {
+			return c.JSON(errRes("All(attributes)", err, config.CANT_DECODE))
+		}
+	}
+	for i := range result.Attributes {
+		attribute := &result.Attributes[i]
+		attribute.Values = []models.AttributeFacetValue{}
+		if len(attribute.Units) > 0 {
+			attribute.Unit = attribute.Units[0]
+		}
+		byValue := map[string]int{}
+		for _, bucket := range facets.Attributes {
+			if bucket.Id.AttrId != attribute.Id {
+				continue
+			}
+			unit := ""
+			if bucket.Id.UnitIndex >= 0 && bucket.Id.UnitIndex < int64(len(attribute.Units)) {
+				unit = attribute.Units[bucket.Id.UnitIndex]
+			}
+			if attribute.IsNumber {
+				if number, ok := attrNumber(bucket.Id.Value); ok {
+					if factor, ok := helpers.UnitFactor(unit, attribute.Unit); ok {
+						number *= factor
+						if attribute.Min == nil || number < *attribute.Min {
+							attribute.Min = &number
+						}
+						if attribute.Max == nil || number > *attribute.Max {
+							attribute.Max = &number
+						}
+					}
+				}
+			}
+			key := fmt.Sprintf("%v|%v", bucket.Id.Value, unit)
+			if index, ok := byValue[key]; ok {
+				attribute.Values[index].Count += bucket.Count
+				continue
+			}
+			byValue[key] = len(attribute.Values)
+			attribute.Values = append(attribute.Values, models.AttributeFacetValue{
+				Value: bucket.Id.Value,
+				Unit:  unit,
+				Count: bucket.Count,
+			})
+		}
+		values := attribute.Values
+		if attribute.IsNumber {
+			sort.SliceStable(values, func(a, b int) bool {
+				numberA, _ := attrNumber(values[a].Value)
+				numberB, _ := attrNumber(values[b].Value)
+				factorA, _ := helpers.UnitFactor(values[a].Unit, attribute.Unit)
+				factorB, _ := helpers.UnitFactor(values[b].Unit, attribute.Unit)
+				return numberA*factorA < numberB*factorB
+			})
+		} else {
+			sort.SliceStable(values, func(a, b int) bool { return values[a].Count > values[b].Count })
+			if len(values) > attributeFacetValuesLimit {
+				attribute.Values = values[:attributeFacetValuesLimit]
+			}
+		}
+	}
+	return c.JSON(models.Response[models.ProductsFilterAggregations]{
+		IsSuccess: true,
+		Result:    result,
+	})
+}
@@ -0,0 +1,81 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/helpers"
+	"github.com/devzatruk/bizhubBackend/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+type productPricingError struct {
+	Fn   string
+	Err  error
+	Code string
+}
+
+func (e *productPricingError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Fn, e.Err)
+}
+
+func productPricingErrorResponse(c *fiber.Ctx, errRes helpers.ResponseFunc, err error) error {
+	if pricingErr, ok := err.(*productPricingError); ok {
+		return c.JSON(errRes(pricingErr.Fn, pricingErr.Err, pricingErr.Code))
+	}
+	return c.JSON(errRes("productPricing", err, config.SERVER_ERROR))
+}
+
+// AddNewProduct (prefix "") we EditProduct (prefix "new_") ucin form-dan:
+// price_tiers (JSON), min_order_qty, sale_unit; price_tiers yok bolsa price bir tier bolyar.
+// changed=false bolsa form-da baha maglumaty yok, pricing uytgemeyar.
+func productPricingFromForm(c *fiber.Ctx, prefix string, pricing models.ProductPricing) (models.ProductPricing, bool, error) {
+	changed := false
+	if value := c.FormValue(prefix + "min_order_qty"); len(value) > 0 {
+		minOrderQty, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return pricing, false, &productPricingError{"FormValue(min_order_qty)", err, config.CANT_DECODE}
+		}
+		pricing.MinOrderQty = minOrderQty
+		changed = true
+	}
+	if value := c.FormValue(prefix + "sale_unit"); len(value) > 0 {
+		if !helpers.SliceContains(config.SALE_UNITS, value) {
+			return pricing, false, &productPricingError{"FormValue(sale_unit)", errors.New("Sale unit not valid."), config.NOT_ALLOWED}
+		}
+		pricing.SaleUnit = value
+		changed = true
+	}
+	if value := c.FormValue(prefix + "price_tiers"); len(value) > 0 {
+		var tiers []models.PriceTier
+		err := json.Unmarshal([]byte(value), &tiers)
+		if err != nil {
+			return pricing, false, &productPricingError{"Unmarshal(price_tiers)", err, config.CANT_DECODE}
+		}
+		pricing.PriceTiers = tiers
+		changed = true
+	} else if value := c.FormValue(prefix + "price"); len(value) > 0 {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return pricing, false, &productPricingError{"FormValue(price)", err, config.BODY_NOT_PROVIDED}
+		}
+		pricing.PriceTiers = helpers.SinglePriceTier(price, pricing.MinOrderQty)
+		changed = true
+	} else if changed && len(pricing.PriceTiers) > 0 {
+		// dine min_order_qty uytgedi: birinji tier sondan baslayar.
+		pricing.PriceTiers = append([]models.PriceTier{}, pricing.PriceTiers...)
+		pricing.PriceTiers[0].MinQty = pricing.MinOrderQty
+	}
+	if !changed {
+		return pricing, false, nil
+	}
+	tiers, err := helpers.ValidatePriceTiers(pricing.PriceTiers, pricing.MinOrderQty)
+	if err != nil {
+		return pricing, false, &productPricingError{"ValidatePriceTiers()", err, config.NOT_ALLOWED}
+	}
+	pricing.PriceTiers = tiers
+	return pricing, true, nil
+}
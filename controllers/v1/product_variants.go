@@ -0,0 +1,201 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/helpers"
+	"github.com/devzatruk/bizhubBackend/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type productVariantError struct {
+	Fn   string
+	Err  error
+	Code string
+}
+
+func (e *productVariantError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Fn, e.Err)
+}
+
+func productVariantErrorResponse(c *fiber.Ctx, errRes helpers.ResponseFunc, err error) error {
+	if variantErr, ok := err.(*productVariantError); ok {
+		return c.JSON(errRes(variantErr.Fn, variantErr.Err, variantErr.Code))
+	}
+	return c.JSON(errRes("productVariants", err, config.SERVER_ERROR))
+}
+
+type productVariantInput struct {
+	Id      *primitive.ObjectID   `json:"_id"`
+	Attrs   []models.NewProd_Attr `json:"attrs"`
+	Price   *float64              `json:"price"`
+	Images  []string              `json:"images"`
+	InStock *bool                 `json:"in_stock"`
+}
+
+type productVariantsForm struct {
+	models.ProductVariants
+	Changed       bool
+	NewImages     []string
+	DeletedImages []string
+}
+
+// AddNewProduct (prefix "") we EditProduct (prefix "new_") ucin form-dan:
+// variant_attrs (category attribute id-leri, JSON), variants (JSON) we her variant ucin
+// variant_images_<index> suratlar. variants kone variant-lary dolylygyna calysyar:
+// _id bilen gelen variant-yn images-da galan kone suratlary saklanyar.
+func productVariantsFromForm(c *fiber.Ctx, form *multipart.Form, prefix string, category models.CategoryAttributes, old models.ProductVariants) (result productVariantsForm, err error) {
+	result.ProductVariants = old
+	variantAttrsValue := c.FormValue(prefix + "variant_attrs")
+	variantsValue := c.FormValue(prefix + "variants")
+	if len(variantAttrsValue) == 0 && len(variantsValue) == 0 {
+		return result, nil
+	}
+	defer func() {
+		if err != nil {
+			helpers.DeleteImages(result.NewImages)
+			result.NewImages = nil
+		}
+	}()
+
+	variantAttrs := old.VariantAttrs
+	if len(variantAttrsValue) > 0 {
+		variantAttrs = []primitive.ObjectID{}
+		err = json.Unmarshal([]byte(variantAttrsValue), &variantAttrs)
+		if err != nil {
+			return result, &productVariantError{"Unmarshal(variant_attrs)", err, config.CANT_DECODE}
+		}
+	}
+	if len(variantAttrs) > config.PRODUCT_VARIANT_ATTRS_MAX {
+		return result, &productVariantError{"VariantAttrs", fmt.Errorf("At most %v variant attributes allowed.", config.PRODUCT_VARIANT_ATTRS_MAX), config.NOT_ALLOWED}
+	}
+	categoryAttrs := map[primitive.ObjectID]models.CatAttr{}
+	for _, attr := range category.Attributes {
+		categoryAttrs[attr.Id] = attr
+	}
+	for i, attrId := range variantAttrs {
+		if _, ok := categoryAttrs[attrId]; !ok {
+			return result, &productVariantError{"VariantAttrs", errors.New("Variant attribute not valid."), config.NOT_ALLOWED}
+		}
+		for _, other := range variantAttrs[:i] {
+			if other == attrId {
+				return result, &productVariantError{"VariantAttrs", errors.New("Variant attribute repeated."), config.NOT_ALLOWED}
+			}
+		}
+	}
+
+	inputs := []productVariantInput{}
+	if len(variantsValue) > 0 {
+		err = json.Unmarshal([]byte(variantsValue), &inputs)
+		if err != nil {
+			return result, &productVariantError{"Unmarshal(variants)", err, config.CANT_DECODE}
+		}
+	}
+	if len(variantAttrs) == 0 && len(inputs) > 0 {
+		return result, &productVariantError{"MissingData", errors.New("Variant attributes not provided."), config.BODY_NOT_PROVIDED}
+	}
+	if len(variantAttrs) > 0 && len(inputs) == 0 { // variant_attrs uytgese kone variant-lar gabat gelenok
+		return result, &productVariantError{"MissingData", errors.New("Variants not provided."), config.BODY_NOT_PROVIDED}
+	}
+	if len(inputs) > config.PRODUCT_VARIANTS_MAX {
+		return result, &productVariantError{"Variants", fmt.Errorf("At most %v variants allowed.", config.PRODUCT_VARIANTS_MAX), config.NOT_ALLOWED}
+	}
+
+	oldVariants := map[primitive.ObjectID]models.ProductVariant{}
+	for _, variant := range old.Variants {
+		oldVariants[variant.Id] = variant
+	}
+	combinations := map[string]bool{}
+	keptImages := []string{}
+	variants := []models.ProductVariant{}
+	for index, input := range inputs {
+		variant := models.ProductVariant{
+			Id:      primitive.NewObjectID(),
+			Attrs:   make([]models.NewProd_Attr, len(variantAttrs)),
+			Price:   input.Price,
+			Images:  []string{},
+			InStock: true,
+		}
+		if input.InStock != nil {
+			variant.InStock = *input.InStock
+		}
+		if variant.Price != nil && *variant.Price <= 0 {
+			return result, &productVariantError{"Variants", errors.New("Variant price must be positive."), config.NOT_ALLOWED}
+		}
+		if input.Id != nil {
+			oldVariant, ok := oldVariants[*input.Id]
+			if !ok {
+				return result, &productVariantError{"Variants", errors.New("Variant not found."), config.NOT_FOUND}
+			}
+			delete(oldVariants, oldVariant.Id) // bir variant iki gezek gelmesin
+			variant.Id = oldVariant.Id
+			for _, image := range input.Images {
+				if !helpers.SliceContains(oldVariant.Images, image) {
+					return result, &productVariantError{"Variants", errors.New("Variant image not found."), config.NOT_FOUND}
+				}
+				variant.Images = append(variant.Images, image)
+			}
+		} else if len(input.Images) > 0 {
+			return result, &productVariantError{"Variants", errors.New("Images of a new variant must be uploaded."), config.NOT_ALLOWED}
+		}
+
+		// her variant_attrs ucin bir baha, variant_attrs tertibinde
+		if len(input.Attrs) != len(variantAttrs) {
+			return result, &productVariantError{"Variants", errors.New("Variant must have one value for each variant attribute."), config.NOT_ALLOWED}
+		}
+		combination := ""
+		for i, attrId := range variantAttrs {
+			found := false
+			for _, attr := range input.Attrs {
+				if attr.Id != attrId {
+					continue
+				}
+				units := categoryAttrs[attrId].UnitsArray
+				if len(units) > 0 && (attr.UnitIndex < 0 || attr.UnitIndex >= int64(len(units))) {
+					return result, &productVariantError{"IndexOutOfRange", errors.New("Unit index out of range."), config.NOT_ALLOWED}
+				}
+				if attr.Value == nil || len(fmt.Sprint(attr.Value)) == 0 {
+					return result, &productVariantError{"MissingData", errors.New("Variant attribute value not provided."), config.BODY_NOT_PROVIDED}
+				}
+				variant.Attrs[i] = attr
+				found = true
+			}
+			if !found {
+				return result, &productVariantError{"Variants", errors.New("Variant must have one value for each variant attribute."), config.NOT_ALLOWED}
+			}
+			combination += fmt.Sprintf("%v|%v;", variant.Attrs[i].Value, variant.Attrs[i].UnitIndex)
+		}
+		if combinations[combination] {
+			return result, &productVariantError{"Variants", errors.New("Variant repeated."), config.NOT_ALLOWED}
+		}
+		combinations[combination] = true
+
+		files := form.File[fmt.Sprintf("%vvariant_images_%v", prefix, index)]
+		if len(variant.Images)+len(files) > config.PRODUCT_VARIANT_IMAGES_MAX {
+			return result, &productVariantError{"Variants", fmt.Errorf("At most %v images allowed for a variant.", config.PRODUCT_VARIANT_IMAGES_MAX), config.NOT_ALLOWED}
+		}
+		for _, imageFile := range files {
+			imagePath, err := helpers.SaveFileheader(c, imageFile, config.FOLDER_PRODUCTS)
+			if err != nil {
+				return result, &productVariantError{"SaveFileheader(variant_image)", err, config.CANT_DECODE}
+			}
+			result.NewImages = append(result.NewImages, imagePath)
+			variant.Images = append(variant.Images, imagePath)
+		}
+		keptImages = append(keptImages, variant.Images...)
+		variants = append(variants, variant)
+	}
+	for _, image := range helpers.VariantImages(old.Variants) {
+		if !helpers.SliceContains(keptImages, image) {
+			result.DeletedImages = append(result.DeletedImages, image)
+		}
+	}
+	result.ProductVariants = models.ProductVariants{VariantAttrs: variantAttrs, Variants: variants}
+	result.Changed = true
+	return result, nil
+}
@@ -2,7 +2,6 @@ package v1
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -13,6 +12,7 @@ import (
 	ojocronlisteners "github.com/devzatruk/bizhubBackend/config/ojocron_listeners"
 	"github.com/devzatruk/bizhubBackend/helpers"
 	"github.com/devzatruk/bizhubBackend/models"
+	"github.com/devzatruk/bizhubBackend/searchservice"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -58,31 +58,27 @@ func SetProductDiscount(c *fiber.Ctx) error {
 	if sellerObjId != productDetail.SellerId {
 		return c.JSON(errRes("NotOwner", errors.New("Seller not the owner."), config.NOT_ALLOWED))
 	}
-	var discount float64
-	if discountData.Type == config.DISCOUNT_PERCENT {
-		// if discountData.Percent > 0 {
-		discount = discountData.Percent
-		// discountData.Price = productDetail.Price * (100.0 - discount) / 100.0 // satmaly bahasy
-		discountData.Price = productDetail.Price * discount / 100.0 // indirim etmeli mukdary, bu satmaly bahasy dal!
-	} else if discountData.Type == config.DISCOUNT_PRICE {
-		discount = discountData.Price * 100.0 / productDetail.Price
-		discountData.Percent = discount
+	// price in arzan baha (tier/variant): discount percent-de saklanyar we hemme tier-e, variant-a
+	// birmenzes ulanylyar (GetProductDetail-de discounted_price).
+	if discountData.Type == config.DISCOUNT_PRICE && discountData.Price >= productDetail.Price {
+		return c.JSON(errRes("DiscountTooLarge", errors.New("Discount must be less than the lowest tier price."), config.NOT_ALLOWED))
 	}
-
-	_, err = productsColl.UpdateOne(ctx, bson.M{"_id": productObjId},
-		bson.M{
-			"$set": bson.M{
-				"discount":      discountData.Percent,
-				"discount_data": discountData,
-			},
-		})
+	// starts_at/ends_at berilmese discount hazir baslayar, duration-dan gutaryar (kone client-ler).
+	now := time.Now()
+	err = helpers.DiscountWindow(&discountData, now)
 	if err != nil {
-		return c.JSON(errRes("UpdateOne(product)", err, config.CANT_UPDATE))
+		return c.JSON(errRes("DiscountWindow()", err, config.NOT_ALLOWED))
 	}
-	// calculate when to send auto posts and schedule
-	err = ojocronlisteners.ScheduleAutoPostAddDiscountTimes(discountData.Duration, discountData.DurationType, productObjId)
+	// percent/price baslanda product bahasyna gora hasaplanyar; onki discount-yn job-lary calysylyar.
+	err = ojocronlisteners.ScheduleProductDiscount(ctx, productObjId, discountData)
 	if err != nil {
-		c.JSON(errRes("ScheduleAutoPostAddDiscountTimes()", err, config.CANT_INSERT))
+		return c.JSON(errRes("ScheduleProductDiscount()", err, config.CANT_UPDATE))
+	}
+	if discountData.StartsAt.After(now) {
+		return c.JSON(models.Response[string]{
+			IsSuccess: true,
+			Result:    "Discount scheduled successfully.",
+		})
 	}
 	return c.JSON(models.Response[string]{
 		IsSuccess: true,
@@ -108,18 +104,40 @@ func RemoveProductDiscount(c *fiber.Ctx) error {
 
 	productsColl := config.MI.DB.Collection(config.PRODUCTS)
 	// indi products collection-da product-y tapyp, discount = 0 etmeli, discountDetails =nil etmeli
+	// garasyan scheduled_discount hem yatyrylyar
 	updateResult := productsColl.FindOneAndUpdate(ctx, bson.M{"_id": productObjId, "seller_id": sellerObjId},
 		bson.M{
 			"$set": bson.M{
 				"discount":      0,
 				"discount_data": nil,
 			},
+			"$unset": bson.M{
+				"scheduled_discount": "",
+			},
 		})
 	if err = updateResult.Err(); err != nil {
 		log.Errorf("Update product discount failed.")
 		return c.JSON(errRes("FindOneAndUpdate()", err, config.NOT_FOUND))
 	}
+	var oldProduct struct {
+		Discount float64 `bson:"discount"`
+	}
+	err = updateResult.Decode(&oldProduct)
+	if err != nil {
+		return c.JSON(errRes("Decode(product)", err, config.CANT_DECODE))
+	}
+	// discount_started, add_discount we discount_removed job-lary galmasyn
+	err = config.OjoCronService.RemoveJobsByGroup(productObjId)
+	if err != nil {
+		log.Errorf("RemoveJobsByGroup(): %v", err)
+	}
 	log.Logf("Product discount removed.")
+	if oldProduct.Discount == 0 { // dine scheduled discount yatyryldy, auto post gerek dal
+		return c.JSON(models.Response[string]{
+			IsSuccess: true,
+			Result:    "Discount cancelled successfully.",
+		})
+	}
 
 	pResult, err := productsColl.Aggregate(ctx, bson.A{
 		bson.M{
@@ -155,7 +173,6 @@ func RemoveProductDiscount(c *fiber.Ctx) error {
 				"seller_id":   1,
 				"heading":     1,
 				"seller_name": "$seller.name",
-				"discount":    1,
 			},
 		},
 	})
@@ -170,7 +187,6 @@ func RemoveProductDiscount(c *fiber.Ctx) error {
 		Image      string             `bson:"image"`
 		Heading    models.Translation `bson:"heading"`
 		SellerName string             `bson:"seller_name"`
-		Discount   float64            `bson:"discount"`
 	}
 	if pResult.Next(ctx) {
 		err = pResult.Decode(&product)
@@ -187,13 +203,13 @@ func RemoveProductDiscount(c *fiber.Ctx) error {
 
 	heading := models.Translation{
 		En: fmt.Sprintf(os.Getenv("AnnounceEnRem"), product.SellerName,
-			product.Discount, product.Heading.En),
+			oldProduct.Discount, product.Heading.En),
 		Tm: fmt.Sprintf(os.Getenv("AnnounceTmRem"), product.SellerName,
-			product.Heading.Tm, product.Discount),
+			product.Heading.Tm, oldProduct.Discount),
 		Ru: fmt.Sprintf(os.Getenv("AnnounceRuRem"), product.SellerName,
-			product.Discount, product.Heading.Ru),
+			oldProduct.Discount, product.Heading.Ru),
 		Tr: fmt.Sprintf(os.Getenv("AnnounceTrRem"), product.SellerName,
-			product.Heading.Tr, product.Discount),
+			product.Heading.Tr, oldProduct.Discount),
 	}
 
 	post := models.PostUpsert{
@@ -215,11 +231,6 @@ func RemoveProductDiscount(c *fiber.Ctx) error {
 	// gercek posts collection-a gosmaly
 	log.Logf("AutoPost - post published -> %v", result.InsertedID)
 
-	_ = config.OjoCronService.RemoveJobsByGroup(productObjId)
-	// if err != nil {
-	// 	log.Errorf("Couldn't delete from cron jobs...")
-	// 	return c.JSON(errRes("RemoveJobsByGroup(cron_job)", err, config.CANT_DELETE))
-	// }
 	return c.JSON(models.Response[string]{
 		IsSuccess: true,
 		Result:    "Discount removed successfully.",
@@ -616,6 +627,23 @@ func GetProductDetail(c *fiber.Ctx) error {
 				"path": "$brand",
 			},
 		},
+		bson.M{
+			"$lookup": bson.M{
+				"from":         "attributes",
+				"localField":   "variant_attrs",
+				"foreignField": "_id",
+				"as":           "variant_attrs",
+				"pipeline": bson.A{
+					bson.M{
+						"$project": bson.M{
+							"name":        culture.Stringf("$name.%v"),
+							"is_number":   1,
+							"units_array": 1,
+						},
+					},
+				},
+			},
+		},
 		bson.M{
 			"$project": bson.M{
 				"seller":        1,
@@ -634,6 +662,11 @@ func GetProductDetail(c *fiber.Ctx) error {
 				"category_id":   1,
 				"images":        1,
 				"seller_id":     1,
+				"price_tiers":   1,
+				"min_order_qty": 1,
+				"sale_unit":     1,
+				"variant_attrs": 1,
+				"variants":      1,
 			},
 		},
 	}
@@ -654,6 +687,9 @@ func GetProductDetail(c *fiber.Ctx) error {
 	if err = cursor.Err(); err != nil {
 		return c.JSON(errRes("cursor.Err()", err, config.DBQUERY_ERROR))
 	}
+	productDetail.ProductPricing = helpers.NormalizeProductPricing(productDetail.Price, productDetail.ProductPricing)
+	helpers.ApplyDiscountToTiers(productDetail.PriceTiers, productDetail.Discount)
+	helpers.ApplyDiscountToVariants(productDetail.Variants, productDetail.Discount)
 	return c.JSON(models.Response[models.ProductDetail]{
 		IsSuccess: true,
 		Result:    productDetail,
@@ -686,132 +722,36 @@ func SearchProduct(c *fiber.Ctx) error {
 	y, m, d := currentDate.Date()
 	yesterday := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
 	lastWeek := yesterday.AddDate(0, 0, -6)
-	aggregationArray := bson.A{
-		bson.M{
-			"$facet": bson.M{
-				"byHeading": bson.A{
-					bson.M{
-						"$match": bson.M{
-							fmt.Sprintf("heading.%v", culture.Lang): primitive.Regex{Pattern: fmt.Sprintf("(%v)", searchQuery), Options: "gi"},
-						},
-					},
-				},
-				"byMoreDetails": bson.A{
-					bson.M{
-						"$match": bson.M{
-							fmt.Sprintf("more_details.%v", culture.Lang): primitive.Regex{Pattern: fmt.Sprintf("(%v)", searchQuery), Options: "gi"},
-						},
-					},
-				},
-			},
-		},
-		bson.M{
-			"$skip": pageIndex * limit,
-		},
-		bson.M{
-			"$limit": limit,
-		},
-		bson.M{
-			"$lookup": bson.M{
-				"from": "categories",
-				"pipeline": bson.A{
-					bson.M{
-						"$match": bson.M{
-							fmt.Sprintf("name.%v", culture.Lang): primitive.Regex{Pattern: fmt.Sprintf("(%v)", searchQuery), Options: "gi"},
-						},
-					},
-					bson.M{
-						"$project": bson.M{
-							"_id": 1,
-						},
-					},
-					bson.M{
-						"$lookup": bson.M{
-							"from":         "products",
-							"localField":   "_id",
-							"foreignField": "category_id",
-							"as":           "products",
-						},
-					},
-				},
-				"as": "category_results",
-			},
-		},
-		bson.M{
-			"$addFields": bson.M{
-				"category_results": bson.M{
-					"$reduce": bson.M{
-						"input": bson.M{
-							"$map": bson.M{
-								"input": "$category_results",
-								"as":    "cat",
-								"in":    "$$cat.products",
-							},
-						},
-						"initialValue": bson.A{},
-						"in": bson.M{
-							"$concatArrays": bson.A{"$$this", "$$value"},
-						},
-					},
-				},
-			},
-		},
-		bson.M{
-			"$addFields": bson.M{
-				"result": bson.M{
-					"$concatArrays": bson.A{"$byHeading", "$byMoreDetails", "$category_results"},
-				},
-			},
-		},
-		bson.M{
-			"$unwind": bson.M{
-				"path": "$result",
-			},
-		},
-		bson.M{
-			"$group": bson.M{
-				"_id":    "$result._id",
-				"result": bson.M{"$first": "$result"},
-			},
-		},
-		bson.M{
-			"$replaceRoot": bson.M{
-				"newRoot": "$result",
-			},
-		},
-		bson.M{
-			"$sort": bson.M{
-				fmt.Sprintf("heading.%v", culture.Lang): -1,
+	// query normalizasiya edilyar (diakritik, kiril/latyn, stem), heading/brand/category/seller/details
+	// boyunca relevance + viewed/likes bilen tertiplenyar.
+	aggregationArray := config.SearchService.ProductsPipeline(searchQuery, bson.M{"status": config.STATUS_PUBLISHED}, int64(pageIndex*limit), int64(limit))
+	var products = []models.Product{}
+	if aggregationArray == nil {
+		return c.JSON(models.Response[[]models.Product]{
+			IsSuccess: true,
+			Result:    products,
+		})
+	}
+	aggregationArray = append(aggregationArray, bson.M{
+		"$project": bson.M{
+			"image": bson.M{
+				"$first": "$images",
 			},
-		},
-		// bson.M{
-		// 	"$skip": pageIndex * limit,
-		// },
-		// bson.M{
-		// 	"$limit": limit,
-		// }, // TODO: su $skip we $limit yokary gecirdim, dogrumy?
-		bson.M{
-			"$project": bson.M{
-				"image": bson.M{
-					"$first": "$images",
-				},
-				"heading":  fmt.Sprintf("$heading.%v", culture.Lang),
-				"price":    1,
-				"discount": 1,
-				"viewed":   1,
-				"is_new": bson.M{
-					"$gt": bson.A{"$created_at", lastWeek},
-				},
+			"heading":  fmt.Sprintf("$heading.%v", culture.Lang),
+			"price":    1,
+			"discount": 1,
+			"viewed":   1,
+			"is_new": bson.M{
+				"$gt": bson.A{"$created_at", lastWeek},
 			},
 		},
-	}
+	})
 
 	cursor, err := productsCollection.Aggregate(ctx, aggregationArray)
 	if err != nil {
 		return c.JSON(errRes("Aggregate()", err, config.DBQUERY_ERROR))
 	}
 	defer cursor.Close(ctx)
-	var products = []models.Product{}
 	for cursor.Next(ctx) {
 		var product models.Product
 		err := cursor.Decode(&product)
@@ -823,12 +763,44 @@ func SearchProduct(c *fiber.Ctx) error {
 	if err := cursor.Err(); err != nil {
 		return c.JSON(errRes("cursor.Err()", err, config.DBQUERY_ERROR))
 	}
+	// netije beren query-ler suggest ucin sanalyar (dine birinji sahypa).
+	if pageIndex == 0 && len(products) > 0 {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := config.SearchService.LogQuery(ctx, searchQuery)
+			if err != nil {
+				config.ProductsV1Logger.Group("SearchProduct()").Errorf("LogQuery(): %v", err)
+			}
+		}()
+	}
 	return c.JSON(models.Response[[]models.Product]{
 		IsSuccess: true,
 		Result:    products,
 	})
 }
 
+// search box ucin typeahead: yzygiderli gozlenen query-ler, son product, category, brand we seller-ler.
+func SuggestProducts(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Mobile.SuggestProducts")
+	culture := helpers.GetCultureFromQuery(c)
+	searchQuery := c.Query("q")
+	if len(searchQuery) == 0 {
+		return c.JSON(errRes("Query(q)", errors.New("Search query parameter not provided."), config.QUERY_NOT_PROVIDED))
+	}
+	limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(config.SEARCH_SUGGEST_LIMIT)))
+	if err != nil {
+		return c.JSON(errRes("Query(limit)", err, config.QUERY_NOT_PROVIDED))
+	}
+	if limit > config.SEARCH_SUGGEST_MAX_LIMIT {
+		limit = config.SEARCH_SUGGEST_MAX_LIMIT
+	}
+	return c.JSON(models.Response[[]searchservice.Suggestion]{
+		IsSuccess: true,
+		Result:    config.SearchService.Suggest(searchQuery, culture.Lang, limit),
+	})
+}
+
 func FilterProduct(c *fiber.Ctx) error {
 	errRes := helpers.ErrorResponse("Mobile.FilterProduct")
 	culture := helpers.GetCultureFromQuery(c)
@@ -841,177 +813,24 @@ func FilterProduct(c *fiber.Ctx) error {
 		return c.JSON(errRes("Query(limit)", err, config.QUERY_NOT_PROVIDED))
 	}
 
-	categoriesQuery := c.Query("categories", "all")
-	brandsQuery := c.Query("brands", "all")
-	sellersQuery := c.Query("sellers", "all") // ["_id"] sekilde
-	citiesQuery := c.Query("cities", "all")
-	priceQuery := c.Query("price", "none")
 	sortQuery := c.Query("sort", "none")
 
-	match := bson.M{}
-	aggregationArray := bson.A{}
-
-	if categoriesQuery != "all" {
-		var bsonCategories = bson.A{}
-		var categories = []string{}
-		err := json.Unmarshal([]byte(categoriesQuery), &categories)
-		if err != nil {
-			return c.JSON(errRes("json.Unmarshal(categories)", err, config.CANT_DECODE))
-		}
-
-		for i := 0; i < len(categories); i++ {
-			catId, err := primitive.ObjectIDFromHex(categories[i])
-			if err != nil {
-				return c.JSON(errRes("ObjectIDFromHex(categories)", err, config.CANT_DECODE))
-			}
-			bsonCategories = append(bsonCategories, catId)
-		}
-		if len(categories) != 0 {
-			match["category_id"] = bson.M{
-				"$in": bsonCategories,
-			}
-		}
-	}
-
-	if brandsQuery != "all" {
-		var bsonBrands = bson.A{}
-		var brands = []string{}
-		err := json.Unmarshal([]byte(brandsQuery), &brands)
-		if err != nil {
-			return c.JSON(errRes("json.Unmarshal(brands)", err, config.CANT_DECODE))
-		}
-
-		for i := 0; i < len(brands); i++ {
-			catId, err := primitive.ObjectIDFromHex(brands[i])
-			if err != nil {
-				return c.JSON(errRes("ObjectIDFromHex(brands)", err, config.CANT_DECODE))
-			}
-			bsonBrands = append(bsonBrands, catId)
-		}
-		if len(brands) != 0 {
-			match["brand_id"] = bson.M{
-				"$in": bsonBrands,
-			}
-		}
-	}
-
-	if sellersQuery != "all" {
-		var bsonSellers = bson.A{}
-		var sellers = []string{}
-		err := json.Unmarshal([]byte(sellersQuery), &sellers)
-		if err != nil {
-			return c.JSON(errRes("json.Unmarshal(sellers)", err, config.CANT_DECODE))
-		}
-
-		for i := 0; i < len(sellers); i++ {
-			catId, err := primitive.ObjectIDFromHex(sellers[i])
-			if err != nil {
-				return c.JSON(errRes("ObjectIDFromHex(sellers)", err, config.CANT_DECODE))
-			}
-			bsonSellers = append(bsonSellers, catId)
-		}
-		if len(sellers) != 0 {
-			match["seller_id"] = bson.M{
-				"$in": bsonSellers,
-			}
-		}
-	}
-
-	if citiesQuery != "all" {
-		var bsonCities = bson.A{}
-		var cities = []string{}
-		err := json.Unmarshal([]byte(citiesQuery), &cities)
-		if err != nil {
-			return c.JSON(errRes("json.Unmarshal(cities)", err, config.CANT_DECODE))
-		}
-
-		for i := 0; i < len(cities); i++ {
-			catId, err := primitive.ObjectIDFromHex(cities[i])
-			if err != nil {
-				return c.JSON(errRes("ObjectIDFromHex(cities)", err, config.CANT_DECODE))
-			}
-			bsonCities = append(bsonCities, catId)
-		}
-		if len(cities) != 0 {
-			match["seller.city_id"] = bson.M{
-				"$in": bsonCities,
-			}
-			aggregationArray = append(aggregationArray, bson.M{
-				"$lookup": bson.M{
-					"from":         "sellers",
-					"localField":   "seller_id",
-					"foreignField": "_id",
-					"as":           "seller",
-				},
-			}, bson.M{
-				"$unwind": bson.M{
-					"path": "$seller",
-				},
-			})
-		}
-	}
-
-	if priceQuery != "none" {
-		var price = []any{}
-		err := json.Unmarshal([]byte(priceQuery), &price)
-		if err != nil {
-			return c.JSON(errRes("json.Unmarshal(price)", err, config.CANT_DECODE))
-		}
-		// fmt.Println(price)
-		if len(price) == 2 {
-			_p := bson.M{}
-			if price[0] != "none" {
-				_p["$gte"] = price[0]
-			}
-			if price[1] != "none" {
-				_p["$lte"] = price[1]
-			}
-			if len(_p) != 0 {
-				match["price"] = _p
-			}
-		}
-	}
-	if len(match) != 0 {
-		aggregationArray = append(aggregationArray, bson.M{"$match": match})
-	}
-	productsCollection := config.MI.DB.Collection(config.PRODUCTS)
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 	defer cancel()
+	// categories, brands, sellers, cities, price we attrs filter-leri
+	aggregationArray, err := productFilterStages(ctx, c)
+	if err != nil {
+		return productFilterErrorResponse(c, errRes, err)
+	}
+	productsCollection := config.MI.DB.Collection(config.PRODUCTS)
 
 	currentDate := time.Now()
 	y, m, d := currentDate.Date()
 	yesterday := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
 	lastWeek := yesterday.AddDate(0, 0, -6)
 
-	aggregationArray = append(aggregationArray, bson.A{
-		bson.M{
-			"$skip": pageIndex * limit,
-		},
-		bson.M{
-			"$limit": limit,
-		},
-		bson.M{
-			"$project": bson.M{
-				"images":     1,
-				"heading":    1,
-				"price":      1,
-				"discount":   1,
-				"created_at": 1,
-			},
-		},
-		bson.M{
-			"$addFields": bson.M{
-				"is_new": bson.M{
-					"$gt": bson.A{"$created_at", lastWeek},
-				},
-				"heading": fmt.Sprintf("$heading.%v", culture.Lang),
-				"image": bson.M{
-					"$first": "$images",
-				},
-			},
-		},
-	}...)
-
+	// sort $skip-den on: dine sahypa dal, hemme netije tertiplenyar.
+	// price in arzan baha (helpers.LowestProductPrice).
 	if sortQuery != "none" {
 		index, err := strconv.Atoi(sortQuery)
 		if err != nil {
@@ -1032,7 +851,7 @@ func FilterProduct(c *fiber.Ctx) error {
 		} else if index == 2 { // New Products
 			aggregationArray = append(aggregationArray, bson.M{
 				"$sort": bson.M{
-					"is_new": -1,
+					"created_at": -1,
 				},
 			})
 		} else if index == 3 { // Trending
@@ -1046,6 +865,35 @@ func FilterProduct(c *fiber.Ctx) error {
 		}
 	}
 
+	aggregationArray = append(aggregationArray, bson.A{
+		bson.M{
+			"$skip": pageIndex * limit,
+		},
+		bson.M{
+			"$limit": limit,
+		},
+		bson.M{
+			"$project": bson.M{
+				"images":     1,
+				"heading":    1,
+				"price":      1,
+				"discount":   1,
+				"created_at": 1,
+			},
+		},
+		bson.M{
+			"$addFields": bson.M{
+				"is_new": bson.M{
+					"$gt": bson.A{"$created_at", lastWeek},
+				},
+				"heading": fmt.Sprintf("$heading.%v", culture.Lang),
+				"image": bson.M{
+					"$first": "$images",
+				},
+			},
+		},
+	}...)
+
 	cursor, err := productsCollection.Aggregate(ctx, aggregationArray)
 	if err != nil {
 		return c.JSON(errRes("Aggregate()", err, config.DBQUERY_ERROR))
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/devzatruk/bizhubBackend/config"
+	ojocronlisteners "github.com/devzatruk/bizhubBackend/config/ojocron_listeners"
 	"github.com/devzatruk/bizhubBackend/helpers"
 	"github.com/devzatruk/bizhubBackend/models"
 	ojoTr "github.com/devzatruk/bizhubBackend/transaction_manager"
@@ -194,15 +195,14 @@ func AddNewProduct(c *fiber.Ctx) error {
 	} else {
 		return c.JSON(errRes("MissingData", errors.New("Brand ID not provided."), config.BODY_NOT_PROVIDED))
 	}
-	if len(c.FormValue("price")) > 0 {
-		price, err := strconv.ParseFloat(c.FormValue("price"), 64)
-		if err != nil {
-			return c.JSON(errRes("FormValue(price)", err, config.BODY_NOT_PROVIDED))
-		}
-		productData.Price = price
-	} else {
+	pricing, ok, err := productPricingFromForm(c, "", models.ProductPricing{MinOrderQty: 1, SaleUnit: config.SALE_UNIT_PIECE})
+	if err != nil {
+		return productPricingErrorResponse(c, errRes, err)
+	}
+	if !ok {
 		return c.JSON(errRes("MissingData", errors.New("Price not provided."), config.BODY_NOT_PROVIDED))
 	}
+	productData.ProductPricing = pricing
 	productData.Attributes = []models.NewProd_Attr{}
 	attributes, ok := form.Value["attributes"]
 
@@ -252,6 +252,12 @@ func AddNewProduct(c *fiber.Ctx) error {
 	} else {
 		return c.JSON(errRes("MissingData", errors.New("Images not provided."), config.BODY_NOT_PROVIDED))
 	}
+	variants, err := productVariantsFromForm(c, form, "", category, models.ProductVariants{})
+	if err != nil {
+		helpers.DeleteImages(productData.Images)
+		return productVariantErrorResponse(c, errRes, err)
+	}
+	productData.Price = helpers.LowestProductPrice(pricing.PriceTiers, variants.Variants)
 	heading := models.Translation{}
 	more_details := models.Translation{}
 	switch culture.Lang {
@@ -283,6 +289,9 @@ func AddNewProduct(c *fiber.Ctx) error {
 		Likes:        0,
 		Status:       config.STATUS_CHECKING,
 		DiscountData: nil,
+
+		ProductPricing:  productData.ProductPricing,
+		ProductVariants: variants.ProductVariants,
 	}
 	transaction_manager := ojoTr.NewTransaction(&ctx, config.MI.DB, 3)
 	tr_productsColl := transaction_manager.Collection(config.PRODUCTS)
@@ -294,6 +303,7 @@ func AddNewProduct(c *fiber.Ctx) error {
 			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
 		}
 		helpers.DeleteImages(prodforDB.Images)
+		helpers.DeleteImages(variants.NewImages)
 		return c.JSON(errRes("InsertOne(product)", err, config.CANT_INSERT))
 	}
 	insertedID := insertResult.InsertedID.(primitive.ObjectID)
@@ -325,6 +335,7 @@ func AddNewProduct(c *fiber.Ctx) error {
 			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
 		}
 		helpers.DeleteImages(prodforDB.Images)
+		helpers.DeleteImages(variants.NewImages)
 		return c.JSON(errRes("Rollback()", err, config.TRANSACTION_FAILED))
 	}
 	config.CheckerTaskService.Writer.Product(insertedID, productData.Heading, sellerObjId)
@@ -371,12 +382,15 @@ func EditProduct(c *fiber.Ctx) error {
 		whichDetails := culture.Stringf("more_details.%v")
 		newData[whichDetails] = c.FormValue("new_more_details")
 	}
-	if len(c.FormValue("new_price")) > 0 {
-		price, err := strconv.ParseFloat(c.FormValue("new_price"), 64)
-		if err != nil {
-			return c.JSON(errRes("FormValue(price)", err, config.BODY_NOT_PROVIDED))
-		}
-		newData["price"] = price
+	oldPricing := helpers.NormalizeProductPricing(oldData.Price, oldData.ProductPricing)
+	pricing, pricingChanged, err := productPricingFromForm(c, "new_", oldPricing)
+	if err != nil {
+		return productPricingErrorResponse(c, errRes, err)
+	}
+	if pricingChanged {
+		newData["price_tiers"] = pricing.PriceTiers
+		newData["min_order_qty"] = pricing.MinOrderQty
+		newData["sale_unit"] = pricing.SaleUnit
 	}
 	// db
 	categoriesColl := config.MI.DB.Collection(config.CATEGORIES)
@@ -478,6 +492,29 @@ func EditProduct(c *fiber.Ctx) error {
 		}
 		newData["attrs"] = remaining_attrs
 	}
+	variants, err := productVariantsFromForm(c, form, "new_", category, oldData.ProductVariants)
+	if err != nil {
+		return productVariantErrorResponse(c, errRes, err)
+	}
+	if variants.Changed {
+		newData["variant_attrs"] = variants.VariantAttrs
+		newData["variants"] = variants.Variants
+	}
+	if pricingChanged || variants.Changed {
+		price := helpers.LowestProductPrice(pricing.PriceTiers, variants.Variants)
+		newData["price"] = price
+		// discount gornusine gora: percent-de mukdary, price-da percent-i taze in arzan baha gora.
+		if oldData.DiscountData != nil {
+			discountData := *oldData.DiscountData
+			err = helpers.ApplyDiscountPrice(&discountData, price)
+			if err != nil {
+				helpers.DeleteImages(variants.NewImages)
+				return c.JSON(errRes("ApplyDiscountPrice()", err, config.NOT_ALLOWED))
+			}
+			newData["discount"] = discountData.Percent
+			newData["discount_data"] = discountData
+		}
+	}
 	new_images := []string{}
 	if images, ok := form.File["new_images"]; ok && len(images) > 0 {
 		for _, imageFile := range images {
@@ -485,6 +522,7 @@ func EditProduct(c *fiber.Ctx) error {
 			imagePath, err := helpers.SaveFileheader(c, imageFile, config.FOLDER_PRODUCTS)
 			if err != nil {
 				helpers.DeleteImages(new_images)
+				helpers.DeleteImages(variants.NewImages)
 				return c.JSON(errRes("SaveFileheader(product_image)", err, config.CANT_DECODE))
 			} else {
 				new_images = append(new_images, imagePath)
@@ -521,21 +559,32 @@ func EditProduct(c *fiber.Ctx) error {
 			"$set": newData,
 		}).
 		SetRollbackUpdate(bson.M{
-			"heading":      oldData.Heading,
-			"more_details": oldData.MoreDetails,
-			"price":        oldData.Price,
-			"attrs":        oldData.Attributes,
-			"images":       oldData.Images,
-			"status":       oldData.Status,
+			"$set": bson.M{
+				"heading":       oldData.Heading,
+				"more_details":  oldData.MoreDetails,
+				"price":         oldData.Price,
+				"price_tiers":   oldData.PriceTiers,
+				"min_order_qty": oldData.MinOrderQty,
+				"sale_unit":     oldData.SaleUnit,
+				"variant_attrs": oldData.VariantAttrs,
+				"variants":      oldData.Variants,
+				"discount":      oldData.Discount,
+				"discount_data": oldData.DiscountData,
+				"attrs":         oldData.Attributes,
+				"images":        oldData.Images,
+				"status":        oldData.Status,
+			},
 		})
-	_, err = tr_productsColl.FindOneAndUpdate(update_model)
+	// UpdateOne: ojoTr FindOneAndUpdate rollback-da SetRollbackUpdate-i ulananok.
+	_, err = tr_productsColl.UpdateOne(update_model)
 	if err != nil {
 		trErr := transaction_manager.Rollback()
 		if trErr != nil {
 			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
 		}
 		helpers.DeleteImages(new_images)
-		return c.JSON(errRes("FindOneAndUpdate(product)", err, config.CANT_UPDATE))
+		helpers.DeleteImages(variants.NewImages)
+		return c.JSON(errRes("UpdateOne(product)", err, config.CANT_UPDATE))
 	}
 
 	if err = transaction_manager.Err(); err != nil {
@@ -544,6 +593,7 @@ func EditProduct(c *fiber.Ctx) error {
 			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
 		}
 		helpers.DeleteImages(new_images)
+		helpers.DeleteImages(variants.NewImages)
 		return c.JSON(errRes("Rollback()", err, config.TRANSACTION_FAILED))
 	}
 	if culture.Lang == "en" && len(c.FormValue("new_heading")) > 0 {
@@ -552,6 +602,7 @@ func EditProduct(c *fiber.Ctx) error {
 		config.CheckerTaskService.Writer.Product(productObjId, oldData.Heading.En, oldData.SellerId)
 	}
 	helpers.DeleteImages(deleted_images)
+	helpers.DeleteImages(variants.DeletedImages)
 	return c.JSON(models.Response[string]{
 		IsSuccess: true,
 		Result:    config.UPDATED,
@@ -784,6 +835,7 @@ func DeleteProduct(c *fiber.Ctx) error {
 		return c.JSON(errRes("Rollback()", err, config.TRANSACTION_FAILED))
 	}
 	helpers.DeleteImages(oldData.Images)
+	helpers.DeleteImages(helpers.VariantImages(oldData.Variants))
 	return c.JSON(models.Response[string]{
 		IsSuccess: true,
 		Result:    config.DELETED,
@@ -931,15 +983,39 @@ func GetProductForEditing(c *fiber.Ctx) error {
 				"path": "$brand",
 			},
 		},
+		bson.M{
+			"$lookup": bson.M{
+				"from":         "attributes",
+				"localField":   "variant_attrs",
+				"foreignField": "_id",
+				"as":           "variant_attrs",
+				"pipeline": bson.A{
+					bson.M{
+						"$project": bson.M{
+							"name":        fmt.Sprintf("$name.%v", culture.Lang),
+							"is_number":   1,
+							"units_array": 1,
+						},
+					},
+				},
+			},
+		},
 		bson.M{
 			"$project": bson.M{
-				"heading":      fmt.Sprintf("$heading.%v", culture.Lang),
-				"more_details": fmt.Sprintf("$more_details.%v", culture.Lang),
-				"price":        1,
-				"images":       1,
-				"brand":        1,
-				"category":     1,
-				"attrs":        1,
+				"heading":            fmt.Sprintf("$heading.%v", culture.Lang),
+				"more_details":       fmt.Sprintf("$more_details.%v", culture.Lang),
+				"price":              1,
+				"price_tiers":        1,
+				"min_order_qty":      1,
+				"sale_unit":          1,
+				"images":             1,
+				"brand":              1,
+				"category":           1,
+				"attrs":              1,
+				"variant_attrs":      1,
+				"variants":           1,
+				"discount_data":      1,
+				"scheduled_discount": 1,
 			},
 		},
 	}
@@ -965,6 +1041,7 @@ func GetProductForEditing(c *fiber.Ctx) error {
 	if productDetails.Id == primitive.NilObjectID {
 		return c.JSON(errRes("NilObjectID", errors.New("Product not found."), config.NOT_FOUND))
 	}
+	productDetails.ProductPricing = helpers.NormalizeProductPricing(productDetails.Price, productDetails.ProductPricing)
 	return c.JSON(models.Response[models.ProductDetailForEditing]{
 		IsSuccess: true,
 		Result:    productDetails,
@@ -1466,6 +1543,9 @@ func UpdateSellerProfile(c *fiber.Ctx) error {
 	} else {
 		config.CheckerTaskService.Writer.SellerProfile(sellerObjId, seller.Bio.En)
 	}
+	if newData.Name != "" || newData.CityId != "" {
+		ojocronlisteners.ReindexSeller(sellerObjId)
+	}
 	return c.JSON(models.Response[string]{
 		IsSuccess: true,
 		Result:    config.STATUS_COMPLETED,
@@ -1526,6 +1606,8 @@ func BecomeSeller(c *fiber.Ctx) error {
 		ClosedAt:  nil,
 		Status:    config.STATUS_ACTIVE,
 		InAuction: make([]models.InAuctionObject, 0),
+		// taze wallet bos, opening_balance gerek dal.
+		LedgerOpenedAt: &now,
 	}
 	tr_insertModel := ojoTr.NewModel().SetDocument(newWallet)
 	tr_walletsColl := transaction_manager.Collection(config.WALLETS)
@@ -2029,100 +2111,18 @@ func SearchSellers(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	sellers := config.MI.DB.Collection("sellers")
-	aggregationArray := bson.A{
-		bson.M{
-			"$facet": bson.M{
-				"byName": bson.A{
-					bson.M{
-						"$match": bson.M{
-							"name": primitive.Regex{Pattern: fmt.Sprintf("(%v)", searchQuery), Options: "gi"},
-						},
-					},
-				},
-			},
-		},
-		bson.M{
-			"$sort": bson.M{
-				"type": 1,
-				"name": -1,
-			},
-		},
-		bson.M{
-			"$skip": pageIndex * limit,
-		},
-		bson.M{
-			"$limit": limit,
-		},
-		bson.M{
-			"$lookup": bson.M{
-				"from": "cities",
-				"pipeline": bson.A{
-					bson.M{
-						"$match": bson.M{
-							culture.Stringf("name.%v"): primitive.Regex{Pattern: fmt.Sprintf("(%v)", searchQuery), Options: "gi"},
-						},
-					},
-					bson.M{
-						"$project": bson.M{
-							"_id": 1,
-						},
-					},
-					bson.M{
-						"$lookup": bson.M{
-							"from":         "sellers",
-							"foreignField": "city_id",
-							"localField":   "_id",
-							"as":           "sellers",
-						},
-					},
-				},
-				"as": "city_results",
-			},
-		},
-		bson.M{
-			"$addFields": bson.M{
-				"city_results": bson.M{
-					"$map": bson.M{
-						"input": "$city_results",
-						"as":    "city_result",
-						"in":    "$$city_result.sellers",
-					},
-				},
-			},
-		},
-		bson.M{
-			"$addFields": bson.M{
-				"result": bson.M{
-					"$concatArrays": bson.A{
-						bson.M{"$cond": bson.A{
-							bson.M{
-								"$eq": bson.A{"$city_results", bson.A{}},
-							},
-							bson.A{}, bson.M{"$first": "$city_results"},
-						}},
-						"$byName",
-					},
-				},
-			},
-		},
-		bson.M{
-			"$unwind": bson.M{
-				"path": "$result",
-			},
-		},
-		bson.M{
-			"$group": bson.M{
-				"_id": "$result._id",
-				"result": bson.M{
-					"$first": "$result",
-				},
-			},
-		},
-		bson.M{
-			"$replaceRoot": bson.M{
-				"newRoot": "$result",
-			},
-		},
+	// name we city boyunca normalizasiya edilen gozleg, relevance + likes bilen tertiplenyar.
+	aggregationArray := config.SearchService.SellersPipeline(searchQuery, bson.M{
+		"status": config.SELLER_STATUS_PUBLISHED,
+	}, int64(pageIndex*limit), int64(limit))
+	var sellersResult = []models.Seller{}
+	if aggregationArray == nil {
+		return c.JSON(models.Response[[]models.Seller]{
+			IsSuccess: true,
+			Result:    sellersResult,
+		})
+	}
+	aggregationArray = append(aggregationArray,
 		bson.M{
 			"$lookup": bson.M{
 				"from":         "cities",
@@ -2144,14 +2144,13 @@ func SearchSellers(c *fiber.Ctx) error {
 				// "preserveNullAndEmptyArrays": true,
 			},
 		},
-	}
+	)
 
 	cursor, err := sellers.Aggregate(ctx, aggregationArray)
 	if err != nil {
 		return c.JSON(errRes("Aggregate()", err, config.DBQUERY_ERROR))
 	}
 	defer cursor.Close(ctx)
-	var sellersResult = []models.Seller{}
 	for cursor.Next(ctx) {
 		var seller models.Seller
 		err := cursor.Decode(&seller)
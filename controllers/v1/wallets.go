@@ -3,21 +3,29 @@ package v1
 import (
 	"errors"
 	"fmt"
-	"os"
 	"strconv"
 	"time"
 
+	"github.com/devzatruk/bizhubBackend/cashierservice"
+	"github.com/devzatruk/bizhubBackend/codeservice"
 	"github.com/devzatruk/bizhubBackend/config"
 	"github.com/devzatruk/bizhubBackend/helpers"
+	"github.com/devzatruk/bizhubBackend/ledger"
 	"github.com/devzatruk/bizhubBackend/models"
 	"github.com/devzatruk/bizhubBackend/ojocronservice"
+	"github.com/devzatruk/bizhubBackend/ojologger"
+	"github.com/devzatruk/bizhubBackend/packageservice"
+	"github.com/devzatruk/bizhubBackend/receiptservice"
 	ojoTr "github.com/devzatruk/bizhubBackend/transaction_manager"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/net/context"
 )
 
+var walletsLogger = ojologger.LoggerService.Logger("Wallets v1")
+
 func GetWalletHistory(c *fiber.Ctx) error {
 	errRes := helpers.ErrorResponse("GetWalletHistory")
 	var sellerObjId primitive.ObjectID
@@ -63,12 +71,7 @@ func GetWalletHistory(c *fiber.Ctx) error {
 						fmt.Sprintf("$note.%v", culture.Lang), nil,
 					},
 				},
-
-				"code": bson.M{
-					"$ifNull": bson.A{
-						"$code", nil,
-					},
-				},
+				"discount": 1,
 				// "completed_at": 1,
 			},
 		},
@@ -131,16 +134,28 @@ func Withdraw(c *fiber.Ctx) error {
 	if sellerWallet.Balance < toBeWithdrawn.Sum {
 		return c.JSON(errRes("BalanceNotEnough", errors.New("No enough funds."), config.NOT_ALLOWED))
 	}
-	generatedCode := helpers.RandomCodeGenerator()
+	// waiting wallet_history yazylmazdan on: yogsa bu withdraw opening_balance-a-da girer.
+	err = config.Ledger.OpenWallets(ctx, sellerObjId)
+	if err != nil {
+		return c.JSON(errRes("Ledger.OpenWallets()", err, config.CANT_UPDATE))
+	}
 	now := time.Now()
+	walletHistoryId := primitive.NewObjectID()
+	// code wallet_history-e bagly, withdraw request bilen bile expired bolyar.
+	generatedCode, err := config.CodeService.Issue(ctx, walletHistoryId, sellerObjId,
+		config.INTENT_WITHDRAW, config.WithdrawExpiresAfter())
+	if err != nil {
+		return c.JSON(errRes("CodeService.Issue()", err, config.CANT_INSERT))
+	}
 	wh := models.MyWalletHistory{
+		Id:          walletHistoryId,
 		SellerId:    sellerObjId,
 		WalletId:    sellerWallet.Id,
 		OldBalance:  sellerWallet.Balance,
 		Amount:      toBeWithdrawn.Sum,
 		Intent:      config.INTENT_WITHDRAW,
 		Note:        nil,
-		Code:        &generatedCode,
+		Code:        nil, // code dine sha256 bilen codes-da saklanyar
 		Status:      config.STATUS_WAITING,
 		EmployeeId:  nil,
 		CompletedAt: nil,
@@ -156,39 +171,16 @@ func Withdraw(c *fiber.Ctx) error {
 		if trErr != nil {
 			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
 		}
+		config.CodeService.Revoke(ctx, walletHistoryId)
 		return c.JSON(errRes("InsertOne(wallet_history)", err, config.CANT_INSERT))
 	}
-	// fmt.Printf("\nInsertOne() ok, waiting for 5 seconds... and trying failing operation: UpdateOne()\n")
-	// time.Sleep(time.Second * 5)
-	walletsCollTrans := transaction_manager.Collection(config.WALLETS)
-	update_model := ojoTr.NewModel().
-		SetFilter(bson.M{"_id": sellerWallet.Id}).
-		SetUpdate(bson.M{
-			"$inc": bson.M{
-				"balance": -wh.Amount,
-			},
-		}).
-		SetRollbackUpdate(bson.M{
-			"$inc": bson.M{
-				"balance": wh.Amount,
-			},
-		})
-	_, err = walletsCollTrans.UpdateOne(update_model)
-	if err != nil {
-		trErr := transaction_manager.Rollback()
-		if trErr != nil {
-			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
-		}
-		return c.JSON(errRes("UpdateOne(wallets)", err, config.CANT_UPDATE))
-	}
-
 	newWalletHistoryId := walletHistoryInsertResult.InsertedID.(primitive.ObjectID)
 
 	jobModel := ojocronservice.NewOjoCronJobModel()
 	jobModel.Group(newWalletHistoryId)
 	jobModel.ListenerName(config.CANCEL_WITHDRAW_ACTION).Payload(map[string]interface{}{
 		"transaction_id": newWalletHistoryId,
-	}).RunAt(wh.CreatedAt.Add(time.Hour * 24))
+	}).RunAt(wh.CreatedAt.Add(config.WithdrawExpiresAfter()))
 
 	err = config.OjoCronService.NewJob(jobModel)
 	if err != nil {
@@ -196,6 +188,7 @@ func Withdraw(c *fiber.Ctx) error {
 		if trErr != nil {
 			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
 		}
+		config.CodeService.Revoke(ctx, walletHistoryId)
 		return c.JSON(errRes("NewJob(cancel_withdraw_action)", err, config.CANT_INSERT))
 	}
 
@@ -204,12 +197,33 @@ func Withdraw(c *fiber.Ctx) error {
 		if trErr != nil {
 			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
 		}
+		config.CodeService.Revoke(ctx, walletHistoryId)
 		return c.JSON(errRes("Rollback()", err, config.TRANSACTION_FAILED))
 	}
+	// balance ledger arkaly, in sonky adim: yalnys bolsa entry yazylmaz.
+	postResult, err := config.Ledger.Post(ctx, ledger.Withdraw(sellerObjId, wh.Amount).
+		WithRef(config.WALLETHISTORY, newWalletHistoryId))
+	if err != nil {
+		fn, code := "Ledger.Post(withdraw)", config.CANT_UPDATE
+		if err == ledger.ErrInsufficientFunds {
+			fn, code = "BalanceNotEnough", config.NOT_ALLOWED
+		}
+		trErr := transaction_manager.Rollback()
+		if trErr != nil {
+			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+		}
+		jobErr := config.OjoCronService.RemoveJobsByGroup(newWalletHistoryId)
+		if jobErr != nil {
+			err = fmt.Errorf("Source: %v - RemoveJobsByGroup: %v", err.Error(), jobErr.Error())
+		}
+		config.CodeService.Revoke(ctx, walletHistoryId)
+		return c.JSON(errRes(fn, err, code))
+	}
 	return c.JSON(models.Response[fiber.Map]{
 		IsSuccess: true,
 		Result: fiber.Map{
-			"balance": wh.OldBalance - wh.Amount,
+			"balance": postResult.NewBalance(sellerObjId),
+			"code":    generatedCode, // dine su wagt gorkezilyar
 		},
 	})
 }
@@ -275,6 +289,7 @@ func GetMyWallet(c *fiber.Ctx) error {
 				"seller_id": 1,
 				"package": bson.M{
 					"expires_at":   "$seller.package.to",
+					"auto_renew":   bson.M{"$ifNull": bson.A{"$seller.package.auto_renew", false}},
 					"type":         "$package.type",
 					"name":         fmt.Sprintf("$package.name.%v", culture.Lang),
 					"price":        "$package.price",
@@ -317,86 +332,88 @@ func CancelWithdraw(c *fiber.Ctx) error {
 	if err != nil {
 		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
 	}
+	var sellerObjId primitive.ObjectID
+	err = helpers.GetCurrentSeller(c, &sellerObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentSeller()", err, config.AUTH_REQUIRED))
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	transaction_manager := ojoTr.NewTransaction(&ctx, config.MI.DB, 3)
-	whistoryColl := transaction_manager.Collection(config.WALLETHISTORY)
-	now := time.Now()
-	update_model := ojoTr.NewModel().
-		SetFilter(bson.M{
-			"_id":    transactionObjId,
-			"intent": config.INTENT_WITHDRAW,
-			"status": config.STATUS_WAITING,
-		}).
-		SetUpdate(bson.M{
-			"$set": bson.M{
-				"status":       config.STATUS_CANCELLED,
-				"completed_at": now,
-			},
-		}).
-		SetRollbackUpdate(bson.M{
-			"$set": bson.M{
-				"status":       config.STATUS_WAITING,
-				"completed_at": nil,
-			},
-		})
-	updateResult, err := whistoryColl.FindOneAndUpdate(update_model)
+	// baska seller-in withdraw request-ini cancel edip bolmaz.
+	count, err := config.MI.DB.Collection(config.WALLETHISTORY).CountDocuments(ctx, bson.M{
+		"_id":       transactionObjId,
+		"seller_id": sellerObjId,
+	})
 	if err != nil {
-		errTr := transaction_manager.Rollback()
-		if errTr != nil {
-			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), errTr.Error())
-		}
-		return c.JSON(errRes("UpdateOne(wallet_history)", err, config.CANT_UPDATE))
+		return c.JSON(errRes("CountDocuments(wallet_history)", err, config.DBQUERY_ERROR))
 	}
-	var oldHistory models.MyWalletHistory
-	err = updateResult.Decode(&oldHistory)
-	if err != nil {
-		errTr := transaction_manager.Rollback()
-		if errTr != nil {
-			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), errTr.Error())
-		}
-		return c.JSON(errRes("Decode(wallet_history)", err, config.CANT_DECODE))
-	}
-	walletsColl := transaction_manager.Collection(config.WALLETS)
-	update_model = ojoTr.NewModel().
-		SetFilter(bson.M{"_id": oldHistory.WalletId}).
-		SetUpdate(bson.M{"$inc": bson.M{
-			"balance": oldHistory.Amount,
-		}}).
-		SetRollbackUpdate(bson.M{"$inc": bson.M{
-			"balance": -oldHistory.Amount,
-		}})
-	walletResult, err := walletsColl.FindOneAndUpdate(update_model)
-	if err != nil {
-		errTr := transaction_manager.Rollback()
-		if errTr != nil {
-			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), errTr.Error())
-		}
-		return c.JSON(errRes("FindOneAndUpdate(wallet)", err, config.CANT_UPDATE))
+	if count == 0 {
+		return c.JSON(errRes("NilObjectID", cashierservice.ErrWithdrawNotWaiting, config.NOT_FOUND))
 	}
-	var oldWallet models.SellerWallet
-	err = walletResult.Decode(&oldWallet)
+	oldHistory, postResult, err := cashierservice.CancelWithdrawRequest(ctx, transactionObjId, config.STATUS_CANCELLED)
 	if err != nil {
-		errTr := transaction_manager.Rollback()
-		if errTr != nil {
-			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), errTr.Error())
+		if err == cashierservice.ErrWithdrawNotWaiting {
+			return c.JSON(errRes("CancelWithdrawRequest()", err, config.NOT_FOUND))
 		}
-		return c.JSON(errRes("Decode(wallet)", err, config.CANT_DECODE))
+		return c.JSON(errRes("CancelWithdrawRequest()", err, config.CANT_UPDATE))
 	}
 
 	err = config.OjoCronService.RemoveJobsByGroup(transactionObjId)
 	if err != nil {
-		errTr := transaction_manager.Rollback()
-		if errTr != nil {
-			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), errTr.Error())
-		}
-		return c.JSON(errRes("RemoveJobsByGroup(transactionObjId)", err, config.CANT_DECODE))
+		// job galsa hem zyyany yok: request indi waiting dal, job hic zat etmez.
+		walletsLogger.Group("CancelWithdraw()").Errorf("RemoveJobsByGroup(%v): %v", transactionObjId.Hex(), err)
 	}
 
 	return c.JSON(models.Response[fiber.Map]{
 		IsSuccess: true,
 		Result: fiber.Map{
-			"balance": oldWallet.Balance + oldHistory.Amount,
+			"balance": postResult.NewBalance(oldHistory.SellerId),
+		},
+	})
+}
+
+// seller withdraw code-yny yitirse: waiting withdraw ucin taze code, onki code gecersiz bolyar.
+func ReissueWithdrawCode(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Mobile.ReissueWithdrawCode")
+	transactionObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	var sellerObjId primitive.ObjectID
+	err = helpers.GetCurrentSeller(c, &sellerObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentSeller()", err, config.AUTH_REQUIRED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var walletHistory models.MyWalletHistory
+	err = config.MI.DB.Collection(config.WALLETHISTORY).FindOne(ctx, bson.M{
+		"_id":       transactionObjId,
+		"seller_id": sellerObjId,
+		"intent":    config.INTENT_WITHDRAW,
+		"status":    config.STATUS_WAITING,
+	}).Decode(&walletHistory)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.JSON(errRes("FindOne(wallet_history)", cashierservice.ErrWithdrawNotWaiting, config.NOT_FOUND))
+		}
+		return c.JSON(errRes("FindOne(wallet_history)", err, config.DBQUERY_ERROR))
+	}
+	// taze code hem withdraw request bilen bile expired bolyar.
+	expiresAt := walletHistory.CreatedAt.Add(config.WithdrawExpiresAfter())
+	generatedCode, err := config.CodeService.Reissue(ctx, walletHistory.Id, sellerObjId,
+		config.INTENT_WITHDRAW, expiresAt)
+	if err != nil {
+		if err == codeservice.ErrCodeExpired {
+			return c.JSON(errRes("CodeService.Reissue()", err, config.NOT_ALLOWED))
+		}
+		return c.JSON(errRes("CodeService.Reissue()", err, config.CANT_INSERT))
+	}
+	return c.JSON(models.Response[fiber.Map]{
+		IsSuccess: true,
+		Result: fiber.Map{
+			"code":       generatedCode, // dine su wagt gorkezilyar
+			"expires_at": expiresAt,
 		},
 	})
 }
@@ -430,248 +447,97 @@ func PayForPackage(c *fiber.Ctx) error {
 	if err != nil {
 		return c.JSON(errRes("ValidatePayload()", err, config.NOT_ALLOWED))
 	}
-	// seller has enough money?
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	packagesColl := config.MI.DB.Collection(config.PACKAGES)
-	packageResult := packagesColl.FindOne(ctx, bson.M{
-		"type": payPayload.PackageType,
-	})
-	if err = packageResult.Err(); err != nil {
-		return c.JSON(errRes("packageResult.Err()", err, config.NOT_FOUND))
-	}
-	var packageDetail models.PackageWithoutName
-	err = packageResult.Decode(&packageDetail)
+	payment, err := packageservice.PayPackage(ctx, sellerObjId, payPayload, culture.Lang)
 	if err != nil {
-		return c.JSON(errRes("Decode(package)", err, config.CANT_DECODE))
+		if paymentErr, ok := err.(*packageservice.PackagePaymentError); ok {
+			return c.JSON(errRes(paymentErr.Fn, paymentErr.Err, paymentErr.Code))
+		}
+		return c.JSON(errRes("PayPackage()", err, config.CANT_UPDATE))
 	}
-	sellersColl := config.MI.DB.Collection(config.SELLERS)
-	cursor, err := sellersColl.Aggregate(ctx, bson.A{
-		bson.M{
-			"$match": bson.M{
-				"_id": sellerObjId,
-			},
-		},
-		bson.M{
-			"$lookup": bson.M{
-				"from":         "packages",
-				"localField":   "package.type",
-				"foreignField": "type",
-				"as":           "package.detail",
-				"pipeline": bson.A{
-					bson.M{
-						"$project": bson.M{
-							"name":  "$name.en",
-							"price": 1,
-						},
-					},
-				},
-			},
-		},
-		bson.M{
-			"$unwind": bson.M{
-				"path": "$package.detail",
-			},
-		},
-		bson.M{
-			"$addFields": bson.M{
-				"package.name":  "$package.detail.name",
-				"package.price": "$package.detail.price",
-			},
-		},
-		bson.M{
-			"$project": bson.M{
-				"package": bson.M{
-					"history_id": "$package.package_history_id",
-					"type":       "$package.type",
-					"name":       "$package.name",
-					"price":      "$package.price",
-					"to":         "$package.to",
-				},
-			},
+	return c.JSON(models.Response[fiber.Map]{
+		IsSuccess: true,
+		Result: fiber.Map{
+			"balance":            payment.Balance,
+			"package_expires_at": payment.ExpiresAt,
+			"payment":            payment.Preview,
 		},
 	})
+}
+
+// ?package_type=premium&action=changed&coupon=NEWYEAR: PayForPackage-dan on proration hasaby, hic zat tolenmeyar.
+func PreviewPackagePayment(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Mobile.PreviewPackagePayment")
+	culture := helpers.GetCultureFromQuery(c)
+	var sellerObjId primitive.ObjectID
+	err := helpers.GetCurrentSeller(c, &sellerObjId)
 	if err != nil {
-		return c.JSON(errRes("Aggregate()", err, config.DBQUERY_ERROR))
+		return c.JSON(errRes("GetCurrentSeller()", err, config.AUTH_REQUIRED))
 	}
-	defer cursor.Close(ctx)
-	var sellerInfo struct {
-		SellerId primitive.ObjectID `bson:"_id"`
-		Package  struct {
-			HistoryId primitive.ObjectID `bson:"history_id"`
-			Type      string             `bson:"type"`
-			Name      string             `bson:"name"`
-			Price     float64            `bson:"price"`
-			To        time.Time          `bson:"to"`
-		} `bson:"package"`
+	payPayload := models.PayPayload{
+		PackageType: c.Query("package_type"),
+		Action:      c.Query("action", config.PACKAGE_PAY),
+		Coupon:      c.Query("coupon"),
 	}
-	if cursor.Next(ctx) {
-		err = cursor.Decode(&sellerInfo)
-		if err != nil {
-			return c.JSON(errRes("Decode(sellerInfo)", err, config.CANT_DECODE))
-		}
+	err = ValidatePayload(&payPayload)
+	if err != nil {
+		return c.JSON(errRes("ValidatePayload()", err, config.NOT_ALLOWED))
 	}
-	if err = cursor.Err(); err != nil {
-		return c.JSON(errRes("cursor.Err()", err, config.DBQUERY_ERROR))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	preview, err := packageservice.PreviewPackagePayment(ctx, sellerObjId, payPayload, culture.Lang)
+	if err != nil {
+		if paymentErr, ok := err.(*packageservice.PackagePaymentError); ok {
+			return c.JSON(errRes(paymentErr.Fn, paymentErr.Err, paymentErr.Code))
+		}
+		return c.JSON(errRes("PreviewPackagePayment()", err, config.DBQUERY_ERROR))
 	}
-	if sellerInfo.SellerId == primitive.NilObjectID {
-		return c.JSON(errRes("NilObjectID", errors.New("Seller not found."), config.NOT_FOUND))
+	return c.JSON(models.Response[*models.PackagePaymentPreview]{
+		IsSuccess: true,
+		Result:    preview,
+	})
+}
+
+// auto_renew acylanda onki synanysyklar arassalanyar: job tazeden toleg etmage synanysar.
+func SetPackageAutoRenew(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Mobile.SetPackageAutoRenew")
+	var sellerObjId primitive.ObjectID
+	err := helpers.GetCurrentSeller(c, &sellerObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentSeller()", err, config.AUTH_REQUIRED))
 	}
-	walletsColl := config.MI.DB.Collection(config.WALLETS)
-	findResult := walletsColl.FindOne(ctx, bson.M{"seller_id": sellerObjId})
-	if err = findResult.Err(); err != nil {
-		return c.JSON(errRes("FindOne(wallet)", err, config.NOT_FOUND))
+	var payload struct {
+		AutoRenew bool `json:"auto_renew"`
 	}
-	var sellerWallet models.SellerWallet
-	err = findResult.Decode(&sellerWallet)
+	err = c.BodyParser(&payload)
 	if err != nil {
-		return c.JSON(errRes("Decode(sellerWallet)", err, config.CANT_DECODE))
+		return c.JSON(errRes("BodyParser()", err, config.CANT_DECODE))
 	}
-	if sellerWallet.Balance < packageDetail.Price {
-		return c.JSON(errRes("BalanceNotEnough", errors.New("No enough funds."), config.NOT_ALLOWED))
+	update := bson.M{
+		"$set": bson.M{"package.auto_renew": payload.AutoRenew},
 	}
-	transaction_manager := ojoTr.NewTransaction(&ctx, config.MI.DB, 3)
-	tr_walletsColl := transaction_manager.Collection(config.WALLETS)
-	tr_updateModel := ojoTr.NewModel().
-		SetFilter(bson.M{"_id": sellerWallet.Id}).
-		SetUpdate(bson.M{"$inc": bson.M{"balance": -packageDetail.Price}}).
-		SetRollbackUpdate(bson.M{"$inc": bson.M{"balance": packageDetail.Price}})
-	_, err = tr_walletsColl.FindOneAndUpdate(tr_updateModel)
-	if err != nil {
-		errTr := transaction_manager.Rollback()
-		if errTr != nil {
-			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), errTr.Error())
+	if payload.AutoRenew {
+		update["$unset"] = bson.M{
+			"package.renew_attempts":  "",
+			"package.renew_next_at":   "",
+			"package.renew_failed_at": "",
 		}
-		return c.JSON(errRes("FindOneAndUpdate(wallet)", err, config.CANT_UPDATE))
-	}
-	// wallet_history.InsertNewTransfer()
-	transferNote := ""
-	switch culture.Lang {
-	case "en":
-		transferNote = fmt.Sprintf(os.Getenv("MonthlyPaymentNoteEn"), payPayload.PackageType, packageDetail.Price)
-	case "ru":
-		transferNote = fmt.Sprintf(os.Getenv("MonthlyPaymentNoteRu"), payPayload.PackageType, packageDetail.Price)
-	case "tm":
-		transferNote = fmt.Sprintf(os.Getenv("MonthlyPaymentNoteTm"), payPayload.PackageType, packageDetail.Price)
-	case "tr":
-		transferNote = fmt.Sprintf(os.Getenv("MonthlyPaymentNoteTr"), payPayload.PackageType, packageDetail.Price)
-	}
-	now := time.Now()
-	newTransfer := models.WalletTransfer{
-		SellerId:    sellerWallet.SellerId,
-		WalletId:    sellerWallet.Id,
-		OldBalance:  sellerWallet.Balance,
-		Amount:      packageDetail.Price,
-		Intent:      config.INTENT_PAYMENT,
-		Note:        &transferNote,
-		Code:        nil,
-		Status:      config.STATUS_COMPLETED,
-		EmployeeId:  nil,
-		CreatedAt:   now,
-		CompletedAt: &now,
 	}
-	tr_whistoryColl := transaction_manager.Collection(config.WALLETHISTORY)
-	tr_insertModel := ojoTr.NewModel().SetDocument(newTransfer)
-	tr_insertResult, err := tr_whistoryColl.InsertOne(tr_insertModel)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	sellersColl := config.MI.DB.Collection(config.SELLERS)
+	updateResult, err := sellersColl.UpdateOne(ctx, bson.M{"_id": sellerObjId}, update)
 	if err != nil {
-		errTr := transaction_manager.Rollback()
-		if errTr != nil {
-			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), errTr.Error())
-		}
-		return c.JSON(errRes("InsertOne(transfer)", err, config.CANT_INSERT))
+		return c.JSON(errRes("UpdateOne(seller.package.auto_renew)", err, config.CANT_UPDATE))
 	}
-	// seller.transfers.updateArray()
-	packageExpiresAt := sellerInfo.Package.To.AddDate(0, 1, 0)
-
-	// fmt.Printf("\n                  Package to: %v\n", sellerInfo.Package.To)
-	// fmt.Printf("\n          Package Expires at: %v\n", packageExpiresAt)
-	// fmt.Printf("\nPackage Expires at FORMATTED: %v\n", packageExpiresAt.Format(time.RFC3339))
-
-	tr_sellersColl := transaction_manager.Collection(config.SELLERS)
-	tr_updateModel = ojoTr.NewModel().
-		SetFilter(bson.M{"_id": sellerWallet.SellerId}).
-		SetUpdate(bson.M{
-			"$set": bson.M{
-				"package.to": packageExpiresAt, // 1 ay uzaldyas!
-			},
-			"$push": bson.M{
-				"transfers": bson.M{
-					"$each":     bson.A{tr_insertResult.InsertedID.(primitive.ObjectID)},
-					"$slice":    2,
-					"$position": 0,
-				},
-			},
-		}).
-		SetRollbackUpdate(bson.M{
-			"$set": bson.M{
-				"package.to": sellerInfo.Package.To,
-			},
-			"$pull": bson.M{
-				"transfers": tr_insertResult.InsertedID.(primitive.ObjectID),
-			},
-		})
-	_, err = tr_sellersColl.FindOneAndUpdate(tr_updateModel)
-	if err != nil {
-		errTr := transaction_manager.Rollback()
-		if errTr != nil {
-			err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), errTr.Error())
-		}
-		return c.JSON(errRes("FindOneAndUpdate(seller)", err, config.CANT_UPDATE))
-	}
-	if payPayload.Action == config.PACKAGE_CHANGE {
-		newPackageHistory := models.SellerPackageHistoryFull{
-			SellerId:       sellerObjId,
-			From:           now, // ya onki from-danmy?
-			To:             packageExpiresAt,
-			AmountPaid:     packageDetail.Price,
-			Action:         config.PACKAGE_CHANGE,
-			OldPackage:     &sellerInfo.Package.Type,
-			CurrentPackage: payPayload.PackageType,
-		}
-		tr_packHisColl := transaction_manager.Collection(config.PACKAGEHISTORY)
-		tr_packHisModel := ojoTr.NewModel().SetDocument(newPackageHistory)
-		tr_insertResult, err = tr_packHisColl.InsertOne(tr_packHisModel)
-		if err != nil {
-			errTr := transaction_manager.Rollback()
-			if errTr != nil {
-				err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), errTr.Error())
-			}
-			return c.JSON(errRes("InsertOne(package_history)", err, config.CANT_INSERT))
-		}
-		tr_updateModel = ojoTr.NewModel().
-			SetFilter(bson.M{"_id": sellerInfo.SellerId}).
-			SetUpdate(bson.M{
-				"$set": bson.M{
-					"package": bson.M{
-						"package_history_id": tr_insertResult.InsertedID.(primitive.ObjectID),
-						"to":                 packageExpiresAt,
-						"type":               payPayload.PackageType,
-					},
-				},
-			}).
-			SetRollbackUpdate(bson.M{
-				"$set": bson.M{
-					"package": bson.M{
-						"package_history_id": sellerInfo.Package.HistoryId,
-						"to":                 sellerInfo.Package.To,
-						"type":               sellerInfo.Package.Type,
-					},
-				},
-			})
-		_, err = tr_sellersColl.FindOneAndUpdate(tr_updateModel)
-		if err != nil {
-			errTr := transaction_manager.Rollback()
-			if errTr != nil {
-				err = fmt.Errorf("Source: %v - Rollback: %v", err.Error(), errTr.Error())
-			}
-			return c.JSON(errRes("FindOneAndUpdate(seller.package)", err, config.CANT_UPDATE))
-		}
+	if updateResult.MatchedCount == 0 {
+		return c.JSON(errRes("NilObjectID", errors.New("Seller not found."), config.NOT_FOUND))
 	}
 	return c.JSON(models.Response[fiber.Map]{
 		IsSuccess: true,
 		Result: fiber.Map{
-			"balance":            sellerWallet.Balance - packageDetail.Price,
-			"package_expires_at": packageExpiresAt,
+			"auto_renew": payload.AutoRenew,
 		},
 	})
 }
@@ -860,3 +726,72 @@ func PayForPackage(c *fiber.Ctx) error {
 // 		},
 // 	})
 // }
+
+// ?format=pdf|html&culture=ru: wallet_history entry-nin kwitansiyasy.
+func GetWalletReceipt(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Mobile.GetWalletReceipt")
+	culture := helpers.GetCultureFromQuery(c)
+	var sellerObjId primitive.ObjectID
+	err := helpers.GetCurrentSeller(c, &sellerObjId)
+	if err != nil {
+		return c.JSON(errRes("GetCurrentSeller()", err, config.AUTH_REQUIRED))
+	}
+	historyObjId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.JSON(errRes("Params(id)", err, config.PARAM_NOT_PROVIDED))
+	}
+	format := c.Query("format", receiptservice.FormatHTML)
+	if format != receiptservice.FormatHTML && format != receiptservice.FormatPDF {
+		return c.JSON(errRes("Query(format)", errors.New("Format must be html or pdf."), config.QUERY_NOT_PROVIDED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	view, err := config.ReceiptService.Build(ctx, historyObjId, &sellerObjId, culture.Lang)
+	if err != nil {
+		if err == receiptservice.ErrHistoryNotFound {
+			return c.JSON(errRes("Build()", err, config.NOT_FOUND))
+		}
+		return c.JSON(errRes("Build()", err, config.DBQUERY_ERROR))
+	}
+	contentType, body, err := config.ReceiptService.Render(view, format, culture.Lang)
+	if err != nil {
+		return c.JSON(errRes("Render()", err, config.SERVER_ERROR))
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="receipt-%v.%v"`,
+		receiptservice.FormatNumber(view.Receipt.Number), format))
+	return c.Send(body)
+}
+
+// ?number=123&hash=...: kwitansiyanyn hakykydygyny barlamak, token gerek dal.
+func VerifyWalletReceipt(c *fiber.Ctx) error {
+	errRes := helpers.ErrorResponse("Mobile.VerifyWalletReceipt")
+	number, err := strconv.ParseInt(c.Query("number"), 10, 64)
+	if err != nil {
+		return c.JSON(errRes("Query(number)", err, config.QUERY_NOT_PROVIDED))
+	}
+	hash := c.Query("hash")
+	if hash == "" {
+		return c.JSON(errRes("Query(hash)", errors.New("Hash not provided."), config.QUERY_NOT_PROVIDED))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	receipt, err := config.ReceiptService.Verify(ctx, number, hash)
+	if err != nil {
+		switch err {
+		case receiptservice.ErrReceiptNotFound, receiptservice.ErrHistoryNotFound:
+			return c.JSON(errRes("Verify()", err, config.NOT_FOUND))
+		case receiptservice.ErrInvalidHash:
+			return c.JSON(errRes("Verify()", err, config.NOT_ALLOWED))
+		}
+		return c.JSON(errRes("Verify()", err, config.DBQUERY_ERROR))
+	}
+	return c.JSON(models.Response[fiber.Map]{
+		IsSuccess: true,
+		Result: fiber.Map{
+			"number":    receipt.Number,
+			"issued_at": receipt.IssuedAt,
+			"valid":     true,
+		},
+	})
+}
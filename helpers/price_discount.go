@@ -1,7 +1,9 @@
 package helpers
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/devzatruk/bizhubBackend/config"
 	"github.com/devzatruk/bizhubBackend/models"
@@ -9,18 +11,20 @@ import (
 
 func ValidateDiscountData(data models.DiscountData) error {
 	errStr := ""
-	if data.Duration < 1 {
-		// fmt.Printf("\nDuration: %v\n", data.Duration)
-		errStr = errStr + " [duration not valid]"
+	if data.EndsAt == nil { // ends_at berilse duration gerek dal
+		if data.Duration < 1 {
+			// fmt.Printf("\nDuration: %v\n", data.Duration)
+			errStr = errStr + " [duration not valid]"
+		}
+		if !SliceContains(config.DURATION_TYPES, data.DurationType) {
+			// fmt.Printf("\nDuration type: %v\n", data.DurationType)
+			errStr = errStr + " [duration type not valid]"
+		}
 	}
 	if !SliceContains(config.DISCOUNT_TYPES, data.Type) {
 		// fmt.Printf("\nDiscount type: %v\n", data.Type)
 		errStr = errStr + " [discount type not valid]"
 	}
-	if !SliceContains(config.DURATION_TYPES, data.DurationType) {
-		// fmt.Printf("\nDuration type: %v\n", data.DurationType)
-		errStr = errStr + " [duration type not valid]"
-	}
 	if data.Percent <= 0 || data.Percent > 100 {
 		// fmt.Printf("\nPercent: %v\n", data.Percent)
 		errStr = errStr + " [discount percentage not valid]"
@@ -34,3 +38,69 @@ func ValidateDiscountData(data models.DiscountData) error {
 	}
 	return nil
 }
+
+// month 30 gun hasaplanyar.
+func DiscountEndDate(start time.Time, duration int64, durationType string) (time.Time, error) {
+	day := time.Hour * 24
+	switch durationType {
+	case config.DURATION_HOUR:
+		return start.Add(time.Hour * time.Duration(duration)), nil
+	case config.DURATION_DAY:
+		return start.Add(day * time.Duration(duration)), nil
+	case config.DURATION_MONTH:
+		return start.Add(day * 30 * time.Duration(duration)), nil
+	}
+	return start, errors.New("invalid duration type")
+}
+
+// starts_at berilmese ya-da gecen bolsa discount hazir baslayar; ends_at berilmese
+// duration-dan hasaplanyar (kone client-ler) we DISCOUNT_MAX_DAYS-dan uzyn bolanok.
+func DiscountWindow(data *models.DiscountData, now time.Time) error {
+	start := now
+	if data.StartsAt != nil && data.StartsAt.After(now) {
+		start = *data.StartsAt
+	}
+	if start.After(now.AddDate(0, 0, config.DISCOUNT_MAX_START_DAYS)) {
+		return fmt.Errorf("Discount must start within %v days.", config.DISCOUNT_MAX_START_DAYS)
+	}
+	maxEnd := start.AddDate(0, 0, config.DISCOUNT_MAX_DAYS)
+	var end time.Time
+	if data.EndsAt != nil {
+		end = *data.EndsAt
+		if !end.After(start) {
+			return errors.New("Discount must end after it starts.")
+		}
+		if end.After(maxEnd) {
+			return fmt.Errorf("Discount can last at most %v days.", config.DISCOUNT_MAX_DAYS)
+		}
+	} else {
+		var err error
+		end, err = DiscountEndDate(start, data.Duration, data.DurationType)
+		if err != nil {
+			return err
+		}
+		if end.After(maxEnd) { // 2 aydan kop discount edip bilenoklar!!!
+			end = maxEnd
+		}
+	}
+	data.StartsAt = &start
+	data.EndsAt = &end
+	return nil
+}
+
+// percent-den price (indirim mukdary, satmaly bahasy dal) ya-da price-dan percent hasaplanyar.
+// product bahasy discount goyulandan son uytgap biler: baslanda tazeden hasaplanyar.
+func ApplyDiscountPrice(data *models.DiscountData, price float64) error {
+	if price <= 0 {
+		return errors.New("Product price not valid.")
+	}
+	if data.Type == config.DISCOUNT_PERCENT {
+		data.Price = price * data.Percent / 100.0
+	} else if data.Type == config.DISCOUNT_PRICE {
+		if data.Price >= price {
+			return errors.New("Discount must be less than the lowest price.")
+		}
+		data.Percent = data.Price * 100.0 / price
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/models"
+)
+
+// tier-ler min_qty boyunca tertiplenyar, birinjisi min_order_qty-dan baslayar,
+// her indiki tier arzan bolmaly; max_qty indiki tier-den hasaplanyar.
+func ValidatePriceTiers(tiers []models.PriceTier, minOrderQty int64) ([]models.PriceTier, error) {
+	if len(tiers) == 0 {
+		return nil, errors.New("Price tiers not provided.")
+	}
+	if len(tiers) > config.PRICE_TIERS_MAX {
+		return nil, fmt.Errorf("At most %v price tiers allowed.", config.PRICE_TIERS_MAX)
+	}
+	if minOrderQty < 1 {
+		return nil, errors.New("Minimum order quantity must be at least 1.")
+	}
+	sorted := append([]models.PriceTier{}, tiers...)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a].MinQty < sorted[b].MinQty })
+	if sorted[0].MinQty != minOrderQty {
+		return nil, errors.New("First price tier must start at the minimum order quantity.")
+	}
+	for i := range sorted {
+		if sorted[i].Price <= 0 {
+			return nil, errors.New("Price tier price must be positive.")
+		}
+		sorted[i].MaxQty = nil
+		sorted[i].DiscountedPrice = nil
+		if i == 0 {
+			continue
+		}
+		if sorted[i].MinQty == sorted[i-1].MinQty {
+			return nil, errors.New("Price tiers overlap.")
+		}
+		if sorted[i].Price >= sorted[i-1].Price {
+			return nil, errors.New("Each price tier must be cheaper than the previous one.")
+		}
+		maxQty := sorted[i].MinQty - 1
+		sorted[i-1].MaxQty = &maxQty
+	}
+	return sorted, nil
+}
+
+func SinglePriceTier(price float64, minOrderQty int64) []models.PriceTier {
+	return []models.PriceTier{{MinQty: minOrderQty, Price: price}}
+}
+
+func LowestTierPrice(tiers []models.PriceTier) float64 {
+	lowest := 0.0
+	for i, tier := range tiers {
+		if i == 0 || tier.Price < lowest {
+			lowest = tier.Price
+		}
+	}
+	return lowest
+}
+
+// price_tiers-den onki product-lar: bir tier (price), min_order_qty 1, piece.
+func NormalizeProductPricing(price float64, pricing models.ProductPricing) models.ProductPricing {
+	if pricing.MinOrderQty < 1 {
+		pricing.MinOrderQty = 1
+	}
+	if pricing.SaleUnit == "" {
+		pricing.SaleUnit = config.SALE_UNIT_PIECE
+	}
+	if len(pricing.PriceTiers) == 0 {
+		pricing.PriceTiers = SinglePriceTier(price, pricing.MinOrderQty)
+	}
+	return pricing
+}
+
+// discount (percent) hemme tier-e birmenzes ulanylyar.
+func ApplyDiscountToTiers(tiers []models.PriceTier, percent float64) {
+	if percent <= 0 {
+		return
+	}
+	for i := range tiers {
+		discounted := math.Round(tiers[i].Price*(100.0-percent)) / 100.0
+		tiers[i].DiscountedPrice = &discounted
+	}
+}
@@ -0,0 +1,58 @@
+package helpers
+
+import (
+	"math"
+
+	"github.com/devzatruk/bizhubBackend/models"
+)
+
+// product-yn price-y: variant yok bolsa in arzan tier bahasy, bar bolsa
+// in arzan variant bahasy (price-y yok variant tier bahasyny alyar).
+// satuwda bar variant-lar hasaplanyar, hic biri yok bolsa hemmesi.
+func LowestProductPrice(tiers []models.PriceTier, variants []models.ProductVariant) float64 {
+	tierPrice := LowestTierPrice(tiers)
+	if len(variants) == 0 {
+		return tierPrice
+	}
+	lowest, found := 0.0, false
+	for _, inStockOnly := range []bool{true, false} {
+		for _, variant := range variants {
+			if inStockOnly && !variant.InStock {
+				continue
+			}
+			price := tierPrice
+			if variant.Price != nil {
+				price = *variant.Price
+			}
+			if !found || price < lowest {
+				lowest, found = price, true
+			}
+		}
+		if found {
+			break
+		}
+	}
+	return lowest
+}
+
+func VariantImages(variants []models.ProductVariant) []string {
+	images := []string{}
+	for _, variant := range variants {
+		images = append(images, variant.Images...)
+	}
+	return images
+}
+
+// discount (percent) oz bahasy bolan variant-lara hem ulanylyar.
+func ApplyDiscountToVariants(variants []models.ProductVariant, percent float64) {
+	if percent <= 0 {
+		return
+	}
+	for i := range variants {
+		if variants[i].Price == nil {
+			continue
+		}
+		discounted := math.Round(*variants[i].Price*(100.0-percent)) / 100.0
+		variants[i].DiscountedPrice = &discounted
+	}
+}
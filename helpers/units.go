@@ -0,0 +1,61 @@
+package helpers
+
+import "strings"
+
+type unitInfo struct {
+	dimension string
+	factor    float64 // esasy birlige gore (g, m, l, B, W, Hz, s, Ah)
+}
+
+// attribute units_array-da admin yazan birlikler (kicijik harp bilen). Sanawda yok
+// birlik dine ozi bilen denesdirilyar.
+var units = map[string]unitInfo{
+	"mg": {"mass", 0.001}, "мг": {"mass", 0.001},
+	"g": {"mass", 1}, "gr": {"mass", 1}, "гр": {"mass", 1}, "г": {"mass", 1},
+	"kg": {"mass", 1000}, "кг": {"mass", 1000},
+	"t": {"mass", 1000000}, "ton": {"mass", 1000000}, "тонна": {"mass", 1000000}, "т": {"mass", 1000000},
+
+	"mm": {"length", 0.001}, "мм": {"length", 0.001},
+	"cm": {"length", 0.01}, "sm": {"length", 0.01}, "см": {"length", 0.01},
+	"m": {"length", 1}, "м": {"length", 1},
+	"km": {"length", 1000}, "км": {"length", 1000},
+	"inch": {"length", 0.0254}, "in": {"length", 0.0254}, "\"": {"length", 0.0254}, "дюйм": {"length", 0.0254},
+
+	"ml": {"volume", 0.001}, "мл": {"volume", 0.001},
+	"l": {"volume", 1}, "litr": {"volume", 1}, "л": {"volume", 1}, "литр": {"volume", 1},
+
+	"b": {"data", 1}, "kb": {"data", 1 << 10}, "mb": {"data", 1 << 20}, "gb": {"data", 1 << 30}, "tb": {"data", 1 << 40},
+	"кб": {"data", 1 << 10}, "мб": {"data", 1 << 20}, "гб": {"data", 1 << 30}, "тб": {"data", 1 << 40},
+
+	"w": {"power", 1}, "вт": {"power", 1}, "kw": {"power", 1000}, "квт": {"power", 1000},
+	"hz": {"frequency", 1}, "гц": {"frequency", 1},
+	"khz": {"frequency", 1e3}, "кгц": {"frequency", 1e3},
+	"mhz": {"frequency", 1e6}, "мгц": {"frequency", 1e6},
+	"ghz": {"frequency", 1e9}, "ггц": {"frequency", 1e9},
+	"mah": {"charge", 0.001}, "мач": {"charge", 0.001}, "ah": {"charge", 1}, "ач": {"charge", 1},
+	"sec": {"time", 1}, "s": {"time", 1}, "сек": {"time", 1},
+	"min": {"time", 60}, "мин": {"time", 60},
+	"h": {"time", 3600}, "sagat": {"time", 3600}, "ч": {"time", 3600}, "час": {"time", 3600},
+}
+
+func normalizeUnit(unit string) string {
+	return strings.ToLower(strings.TrimSpace(unit))
+}
+
+// from birligindaki bahany to birligine owurmek ucin kopeldiji; birlikler
+// denesdirip bolmayan bolsa ok=false.
+func UnitFactor(from string, to string) (float64, bool) {
+	from, to = normalizeUnit(from), normalizeUnit(to)
+	if from == to {
+		return 1, true
+	}
+	fromInfo, ok := units[from]
+	if !ok {
+		return 0, false
+	}
+	toInfo, ok := units[to]
+	if !ok || fromInfo.dimension != toInfo.dimension {
+		return 0, false
+	}
+	return fromInfo.factor / toInfo.factor, true
+}
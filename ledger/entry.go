@@ -0,0 +1,171 @@
+package ledger
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EntryOpeningBalance = "opening_balance"
+	EntryDeposit        = "deposit"         // cash -> available
+	EntryWithdraw       = "withdraw"        // available -> withdraw_pending
+	EntryWithdrawCancel = "withdraw_cancel" // withdraw_pending -> available
+	EntryWithdrawPayout = "withdraw_payout" // withdraw_pending -> cash
+	EntryPackagePayment = "package_payment" // available -> revenue:packages
+	EntryAuctionHold    = "auction_hold"    // available -> held
+	EntryAuctionRelease = "auction_release" // held -> available
+	EntryAuctionCapture = "auction_capture" // held -> revenue:auctions
+	EntryReversal       = "reversal"
+	EntryCorrection     = "correction" // reconciliation-dan son admin duzedisi
+)
+
+const (
+	AccountCash           = "platform:cash"
+	AccountOpening        = "platform:opening"
+	AccountPackageRevenue = "platform:revenue:packages"
+	AccountAuctionRevenue = "platform:revenue:auctions"
+	AccountCorrections    = "platform:corrections"
+	kindAvailable         = "available"
+	kindHeld              = "held"
+	kindWithdrawPending   = "withdraw_pending"
+)
+
+func sellerAccount(sellerId primitive.ObjectID, kind string) string {
+	return fmt.Sprintf("seller:%v:%v", sellerId.Hex(), kind)
+}
+
+// seller-in ulanyp bilyan puly, wallets.balance su hasabyn proyeksiyasy.
+func SellerAvailable(sellerId primitive.ObjectID) string {
+	return sellerAccount(sellerId, kindAvailable)
+}
+
+// auction ucin saklanan pul (wallets.in_auction[]).
+func SellerHeld(sellerId primitive.ObjectID) string {
+	return sellerAccount(sellerId, kindHeld)
+}
+
+// withdraw edilip, entek cashier tarapyndan berilmedik pul.
+func SellerWithdrawPending(sellerId primitive.ObjectID) string {
+	return sellerAccount(sellerId, kindWithdrawPending)
+}
+
+type Line struct {
+	Account string  `json:"account" bson:"account"`
+	Debit   float64 `json:"debit" bson:"debit"`
+	Credit  float64 `json:"credit" bson:"credit"`
+}
+
+type Ref struct {
+	Collection string             `json:"collection" bson:"collection"`
+	Id         primitive.ObjectID `json:"id" bson:"id"`
+}
+
+type Entry struct {
+	Id         primitive.ObjectID  `json:"_id" bson:"_id"`
+	Type       string              `json:"type" bson:"type"`
+	Lines      []Line              `json:"lines" bson:"lines"`
+	Ref        *Ref                `json:"ref" bson:"ref"`
+	Note       string              `json:"note" bson:"note"`
+	ReversalOf *primitive.ObjectID `json:"reversal_of" bson:"reversal_of"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// entry haysy dokument sebapli yazyldy: wallet_history, auctions...
+func (e *Entry) WithRef(collection string, id primitive.ObjectID) *Entry {
+	e.Ref = &Ref{Collection: collection, Id: id}
+	return e
+}
+
+func (e *Entry) WithNote(note string) *Entry {
+	e.Note = note
+	return e
+}
+
+// from hasabyndan to hasabyna amount gecirilyar: from debit, to credit.
+func transfer(entryType string, from string, to string, amount float64) *Entry {
+	return &Entry{
+		Type: entryType,
+		Lines: []Line{
+			{Account: from, Debit: amount},
+			{Account: to, Credit: amount},
+		},
+	}
+}
+
+func Deposit(sellerId primitive.ObjectID, amount float64) *Entry {
+	return transfer(EntryDeposit, AccountCash, SellerAvailable(sellerId), amount)
+}
+
+func Withdraw(sellerId primitive.ObjectID, amount float64) *Entry {
+	return transfer(EntryWithdraw, SellerAvailable(sellerId), SellerWithdrawPending(sellerId), amount)
+}
+
+func WithdrawCancel(sellerId primitive.ObjectID, amount float64) *Entry {
+	return transfer(EntryWithdrawCancel, SellerWithdrawPending(sellerId), SellerAvailable(sellerId), amount)
+}
+
+func WithdrawPayout(sellerId primitive.ObjectID, amount float64) *Entry {
+	return transfer(EntryWithdrawPayout, SellerWithdrawPending(sellerId), AccountCash, amount)
+}
+
+func PackagePayment(sellerId primitive.ObjectID, amount float64) *Entry {
+	return transfer(EntryPackagePayment, SellerAvailable(sellerId), AccountPackageRevenue, amount)
+}
+
+func AuctionHold(sellerId primitive.ObjectID, amount float64) *Entry {
+	return transfer(EntryAuctionHold, SellerAvailable(sellerId), SellerHeld(sellerId), amount)
+}
+
+type Hold struct {
+	SellerId primitive.ObjectID
+	Amount   float64
+}
+
+// birnace seller-in saklanan puluny bir entry bilen gaytaryar.
+func AuctionRelease(holds ...Hold) *Entry {
+	entry := &Entry{Type: EntryAuctionRelease, Lines: []Line{}}
+	for _, hold := range holds {
+		entry.Lines = append(entry.Lines,
+			Line{Account: SellerHeld(hold.SellerId), Debit: hold.Amount},
+			Line{Account: SellerAvailable(hold.SellerId), Credit: hold.Amount})
+	}
+	return entry
+}
+
+// yenen seller-lerin saklanan puly platforma gecyar.
+func AuctionCapture(holds ...Hold) *Entry {
+	entry := &Entry{Type: EntryAuctionCapture, Lines: []Line{}}
+	for _, hold := range holds {
+		entry.Lines = append(entry.Lines,
+			Line{Account: SellerHeld(hold.SellerId), Debit: hold.Amount},
+			Line{Account: AccountAuctionRevenue, Credit: hold.Amount})
+	}
+	return entry
+}
+
+// wallet-i reconciliation-da tapylan dogry yagdaya getiryar: available we held
+// hasaplary delta-lar boyunca, garsy tarapy platform:corrections.
+func Correction(sellerId primitive.ObjectID, availableDelta float64, heldDelta float64) *Entry {
+	entry := &Entry{Type: EntryCorrection, Lines: []Line{}}
+	for _, part := range []struct {
+		account string
+		delta   float64
+	}{
+		{SellerAvailable(sellerId), availableDelta},
+		{SellerHeld(sellerId), heldDelta},
+	} {
+		switch {
+		case round(part.delta) > 0:
+			entry.Lines = append(entry.Lines,
+				Line{Account: AccountCorrections, Debit: part.delta},
+				Line{Account: part.account, Credit: part.delta})
+		case round(part.delta) < 0:
+			entry.Lines = append(entry.Lines,
+				Line{Account: part.account, Debit: -part.delta},
+				Line{Account: AccountCorrections, Credit: -part.delta})
+		}
+	}
+	return entry
+}
@@ -0,0 +1,367 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+var (
+	CollEntries       = "ledger_entries"
+	CollWallets       = "wallets"
+	CollWalletHistory = "wallet_history"
+)
+
+var (
+	ErrUnbalanced        = errors.New("ledger: entry is not balanced")
+	ErrEmptyEntry        = errors.New("ledger: entry has no lines")
+	ErrInsufficientFunds = errors.New("ledger: insufficient funds")
+	ErrWalletNotFound    = errors.New("ledger: wallet not found")
+	ErrAlreadyReversed   = errors.New("ledger: entry already reversed")
+)
+
+// Ahli pul hereketleri ucin yeke-tak API: her hereket uytgedilmeyan (immutable) journal entry,
+// debit we credit setirleri den bolmaly. wallets.balance dine seller:{{id}}:available
+// hasabynyn proyeksiyasy, ony dine su paket uytgedyar.
+type Ledger struct {
+	db *mongo.Database
+}
+
+func NewLedger() *Ledger {
+	return &Ledger{}
+}
+
+func (l *Ledger) Init(db *mongo.Database) {
+	l.db = db
+}
+
+type PostResult struct {
+	EntryId     primitive.ObjectID
+	oldBalances map[primitive.ObjectID]float64
+	newBalances map[primitive.ObjectID]float64
+}
+
+// entry-den on seller-in wallets.balance-y.
+func (r *PostResult) OldBalance(sellerId primitive.ObjectID) float64 {
+	return r.oldBalances[sellerId]
+}
+
+// entry-den son seller-in wallets.balance-y.
+func (r *PostResult) NewBalance(sellerId primitive.ObjectID) float64 {
+	return r.newBalances[sellerId]
+}
+
+// cent-e tegelek: float jemlerinde 0.1+0.2 yaly yalnyslyklar bolmasyn.
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func (e *Entry) validate() error {
+	if len(e.Lines) == 0 {
+		return ErrEmptyEntry
+	}
+	var debit, credit float64
+	for _, line := range e.Lines {
+		if line.Debit < 0 || line.Credit < 0 || (line.Debit > 0) == (line.Credit > 0) {
+			return fmt.Errorf("ledger: line %v must have exactly one positive side", line.Account)
+		}
+		debit += line.Debit
+		credit += line.Credit
+	}
+	if round(debit) != round(credit) {
+		return fmt.Errorf("%w: debit %v != credit %v", ErrUnbalanced, debit, credit)
+	}
+	return nil
+}
+
+// her seller ucin available hasabynyn uytgeyisi (credit - debit).
+func (e *Entry) availableDeltas() map[primitive.ObjectID]float64 {
+	deltas := map[primitive.ObjectID]float64{}
+	for _, line := range e.Lines {
+		sellerId, kind, ok := parseSellerAccount(line.Account)
+		if !ok || kind != kindAvailable {
+			continue
+		}
+		deltas[sellerId] += line.Credit - line.Debit
+	}
+	return deltas
+}
+
+func (e *Entry) sellerIds() []primitive.ObjectID {
+	seen := map[primitive.ObjectID]bool{}
+	ids := []primitive.ObjectID{}
+	for _, line := range e.Lines {
+		sellerId, _, ok := parseSellerAccount(line.Account)
+		if ok && !seen[sellerId] {
+			seen[sellerId] = true
+			ids = append(ids, sellerId)
+		}
+	}
+	return ids
+}
+
+// Entry-ni journal-a yazyar we wallets.balance proyeksiyasyny tazeleyar.
+// ctx mongo.SessionContext bolsa (mes: bid transaction-y), sol transaction-yn icinde isleyar,
+// yogsa oz transaction-yny acyar.
+func (l *Ledger) Post(ctx context.Context, entry *Entry) (*PostResult, error) {
+	if err := entry.validate(); err != nil {
+		return nil, err
+	}
+	var result *PostResult
+	err := l.inTransaction(ctx, func(sc mongo.SessionContext) error {
+		var err error
+		result, err = l.post(sc, entry)
+		return err
+	})
+	return result, err
+}
+
+// entry-nin tersine entry yazyar (entry pozulmayar). Bir entry dine bir gezek ters edilip bilner.
+func (l *Ledger) Reverse(ctx context.Context, entryId primitive.ObjectID, note string) (*PostResult, error) {
+	var result *PostResult
+	err := l.inTransaction(ctx, func(sc mongo.SessionContext) error {
+		entriesColl := l.db.Collection(CollEntries)
+		count, err := entriesColl.CountDocuments(sc, bson.M{"reversal_of": entryId})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyReversed
+		}
+		var original Entry
+		err = entriesColl.FindOne(sc, bson.M{"_id": entryId}).Decode(&original)
+		if err != nil {
+			return err
+		}
+		reversal := &Entry{
+			Type:       EntryReversal,
+			Lines:      make([]Line, 0, len(original.Lines)),
+			Ref:        original.Ref,
+			Note:       note,
+			ReversalOf: &original.Id,
+		}
+		for _, line := range original.Lines {
+			reversal.Lines = append(reversal.Lines, Line{
+				Account: line.Account,
+				Debit:   line.Credit,
+				Credit:  line.Debit,
+			})
+		}
+		result, err = l.post(sc, reversal)
+		return err
+	})
+	return result, err
+}
+
+func (l *Ledger) inTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	if sc, ok := ctx.(mongo.SessionContext); ok {
+		return fn(sc)
+	}
+	if session := mongo.SessionFromContext(ctx); session != nil {
+		return fn(mongo.NewSessionContext(ctx, session))
+	}
+	session, err := l.db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	opts := options.Transaction().
+		SetReadConcern(readconcern.Snapshot()).
+		SetWriteConcern(writeconcern.New(writeconcern.WMajority()))
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	}, opts)
+	return err
+}
+
+func (l *Ledger) post(sc mongo.SessionContext, entry *Entry) (*PostResult, error) {
+	for _, sellerId := range entry.sellerIds() {
+		if err := l.openWallet(sc, sellerId); err != nil {
+			return nil, err
+		}
+	}
+	entry.Id = primitive.NewObjectID()
+	entry.CreatedAt = time.Now()
+	_, err := l.db.Collection(CollEntries).InsertOne(sc, entry)
+	if err != nil {
+		return nil, err
+	}
+	result := &PostResult{
+		EntryId:     entry.Id,
+		oldBalances: map[primitive.ObjectID]float64{},
+		newBalances: map[primitive.ObjectID]float64{},
+	}
+	walletsColl := l.db.Collection(CollWallets)
+	for sellerId, delta := range entry.availableDeltas() {
+		filter := bson.M{"seller_id": sellerId}
+		// ters entry-ler (reversal) we duzedisler (correction) balance-y minusa dusurip biler, beylekiler dal.
+		if delta < 0 && entry.Type != EntryReversal && entry.Type != EntryCorrection {
+			filter["balance"] = bson.M{"$gte": -delta}
+		}
+		var wallet struct {
+			Balance float64 `bson:"balance"`
+		}
+		err = walletsColl.FindOneAndUpdate(sc, filter, bson.M{
+			"$inc": bson.M{"balance": delta},
+		}).Decode(&wallet)
+		if err == mongo.ErrNoDocuments {
+			if delta < 0 {
+				return nil, ErrInsufficientFunds
+			}
+			return nil, ErrWalletNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.oldBalances[sellerId] = wallet.Balance
+		result.newBalances[sellerId] = wallet.Balance + delta
+	}
+	return result, nil
+}
+
+// Ledger-den onki wallet-ler ucin opening_balance entry-ni yazyar, eyyam acylan wallet-e degmeyar.
+// Post() hem acyar, yone snapshot wallets.in_auction[] we waiting withdraw-lardan alynyar:
+// olary uytgedyan caller bu funksiyany uytgetmezden ON caglamaly, yogsa sol hereket
+// opening_balance-a hem, entry-nin ozune hem girip iki gezek hasaplanar.
+func (l *Ledger) OpenWallets(ctx context.Context, sellerIds ...primitive.ObjectID) error {
+	return l.inTransaction(ctx, func(sc mongo.SessionContext) error {
+		for _, sellerId := range sellerIds {
+			if err := l.openWallet(sc, sellerId); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Ledger-den onki wallet-ler ucin bir gezeklik opening_balance entry: wallets.balance,
+// in_auction[] we garasylyan withdraw-lar journal-a gecirilyar (proyeksiya uytgemeyar).
+func (l *Ledger) openWallet(sc mongo.SessionContext, sellerId primitive.ObjectID) error {
+	walletsColl := l.db.Collection(CollWallets)
+	var wallet struct {
+		Id             primitive.ObjectID `bson:"_id"`
+		Balance        float64            `bson:"balance"`
+		LedgerOpenedAt *time.Time         `bson:"ledger_opened_at"`
+		InAuction      []struct {
+			Amount float64 `bson:"amount"`
+		} `bson:"in_auction"`
+	}
+	err := walletsColl.FindOne(sc, bson.M{"seller_id": sellerId}).Decode(&wallet)
+	if err == mongo.ErrNoDocuments {
+		return ErrWalletNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if wallet.LedgerOpenedAt != nil {
+		return nil
+	}
+	var held float64
+	for _, inAuction := range wallet.InAuction {
+		held += inAuction.Amount
+	}
+	cursor, err := l.db.Collection(CollWalletHistory).Find(sc, bson.M{
+		"seller_id": sellerId,
+		"intent":    "withdraw",
+		"status":    bson.M{"$in": bson.A{"waiting", "pending_approval"}},
+	})
+	if err != nil {
+		return err
+	}
+	var pending []struct {
+		Amount float64 `bson:"amount"`
+	}
+	if err = cursor.All(sc, &pending); err != nil {
+		return err
+	}
+	var withdrawPending float64
+	for _, p := range pending {
+		withdrawPending += p.Amount
+	}
+	opening := &Entry{
+		Type:  EntryOpeningBalance,
+		Lines: []Line{},
+		Note:  "opening balance",
+	}
+	for _, part := range []struct {
+		account string
+		amount  float64
+	}{
+		{SellerAvailable(sellerId), wallet.Balance},
+		{SellerHeld(sellerId), held},
+		{SellerWithdrawPending(sellerId), withdrawPending},
+	} {
+		if round(part.amount) == 0 {
+			continue
+		}
+		opening.Lines = append(opening.Lines,
+			Line{Account: AccountOpening, Debit: part.amount},
+			Line{Account: part.account, Credit: part.amount})
+	}
+	now := time.Now()
+	if len(opening.Lines) > 0 {
+		opening.Id = primitive.NewObjectID()
+		opening.CreatedAt = now
+		_, err = l.db.Collection(CollEntries).InsertOne(sc, opening)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = walletsColl.UpdateOne(sc, bson.M{"_id": wallet.Id}, bson.M{
+		"$set": bson.M{"ledger_opened_at": now},
+	})
+	return err
+}
+
+// hasabyn galyndysy (credit - debit) journal boyunca.
+func (l *Ledger) Balance(ctx context.Context, account string) (float64, error) {
+	cursor, err := l.db.Collection(CollEntries).Aggregate(ctx, bson.A{
+		bson.M{"$match": bson.M{"lines.account": account}},
+		bson.M{"$unwind": "$lines"},
+		bson.M{"$match": bson.M{"lines.account": account}},
+		bson.M{"$group": bson.M{
+			"_id":    nil,
+			"debit":  bson.M{"$sum": "$lines.debit"},
+			"credit": bson.M{"$sum": "$lines.credit"},
+		}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+	var total struct {
+		Debit  float64 `bson:"debit"`
+		Credit float64 `bson:"credit"`
+	}
+	if cursor.Next(ctx) {
+		if err = cursor.Decode(&total); err != nil {
+			return 0, err
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return 0, err
+	}
+	return round(total.Credit - total.Debit), nil
+}
+
+func parseSellerAccount(account string) (primitive.ObjectID, string, bool) {
+	parts := strings.Split(account, ":")
+	if len(parts) != 3 || parts[0] != "seller" {
+		return primitive.NilObjectID, "", false
+	}
+	sellerId, err := primitive.ObjectIDFromHex(parts[1])
+	if err != nil {
+		return primitive.NilObjectID, "", false
+	}
+	return sellerId, parts[2], true
+}
@@ -0,0 +1,206 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Ledger-den onki (ledger_opened_at-syz) wallet-de birinji hereket: opening_balance we
+// hereketin ozi bile journal-y wallet bilen den etmeli. Transaction ucin mongo replica set gerek,
+// MONGO_URI berilmese testler gecirilyar:
+//
+//	MONGO_URI="mongodb://localhost:27017/?replicaSet=rs0" go test ./ledger/
+
+func testLedger(t *testing.T) (*Ledger, *mongo.Database) {
+	uri := os.Getenv("MONGO_URI")
+	if len(uri) == 0 {
+		t.Skip("MONGO_URI not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("Connect(): %v", err)
+	}
+	db := client.Database(fmt.Sprintf("ledger_test_%v", primitive.NewObjectID().Hex()))
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	l := NewLedger()
+	l.Init(db)
+	return l, db
+}
+
+type testHold struct {
+	AuctionId primitive.ObjectID `bson:"auction_id"`
+	Amount    float64            `bson:"amount"`
+}
+
+// ledger-den onki wallet: ledger_opened_at yok.
+func insertLegacyWallet(t *testing.T, db *mongo.Database, balance float64, holds ...testHold) primitive.ObjectID {
+	sellerId := primitive.NewObjectID()
+	inAuction := bson.A{}
+	for _, hold := range holds {
+		inAuction = append(inAuction, hold)
+	}
+	_, err := db.Collection(CollWallets).InsertOne(context.Background(), bson.M{
+		"seller_id":  sellerId,
+		"balance":    balance,
+		"in_auction": inAuction,
+	})
+	if err != nil {
+		t.Fatalf("InsertOne(wallet): %v", err)
+	}
+	return sellerId
+}
+
+// journal hasaplary wallet-in available, in_auction[] we waiting withdraw-lary bilen den bolmaly.
+func checkWalletMatchesLedger(t *testing.T, l *Ledger, db *mongo.Database, sellerId primitive.ObjectID) {
+	ctx := context.Background()
+	var wallet struct {
+		Balance   float64    `bson:"balance"`
+		InAuction []testHold `bson:"in_auction"`
+	}
+	err := db.Collection(CollWallets).FindOne(ctx, bson.M{"seller_id": sellerId}).Decode(&wallet)
+	if err != nil {
+		t.Fatalf("FindOne(wallet): %v", err)
+	}
+	var held float64
+	for _, hold := range wallet.InAuction {
+		held += hold.Amount
+	}
+	cursor, err := db.Collection(CollWalletHistory).Find(ctx, bson.M{
+		"seller_id": sellerId,
+		"intent":    "withdraw",
+		"status":    "waiting",
+	})
+	if err != nil {
+		t.Fatalf("Find(wallet_history): %v", err)
+	}
+	var pending []struct {
+		Amount float64 `bson:"amount"`
+	}
+	if err = cursor.All(ctx, &pending); err != nil {
+		t.Fatalf("cursor.All(wallet_history): %v", err)
+	}
+	var withdrawPending float64
+	for _, p := range pending {
+		withdrawPending += p.Amount
+	}
+	for _, check := range []struct {
+		account string
+		want    float64
+	}{
+		{SellerAvailable(sellerId), wallet.Balance},
+		{SellerHeld(sellerId), held},
+		{SellerWithdrawPending(sellerId), withdrawPending},
+	} {
+		got, err := l.Balance(ctx, check.account)
+		if err != nil {
+			t.Fatalf("Balance(%v): %v", check.account, err)
+		}
+		if got != round(check.want) {
+			t.Errorf("%v: ledger %v != wallet %v", check.account, got, check.want)
+		}
+	}
+}
+
+func TestFirstAuctionHold(t *testing.T) {
+	l, db := testLedger(t)
+	ctx := context.Background()
+	sellerId := insertLegacyWallet(t, db, 100)
+	auctionId := primitive.NewObjectID()
+	// bidInTransaction tertibi: OpenWallets, in_auction push, AuctionHold.
+	if err := l.OpenWallets(ctx, sellerId); err != nil {
+		t.Fatalf("OpenWallets(): %v", err)
+	}
+	_, err := db.Collection(CollWallets).UpdateOne(ctx, bson.M{"seller_id": sellerId}, bson.M{
+		"$push": bson.M{"in_auction": testHold{AuctionId: auctionId, Amount: 30}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateOne(in_auction): %v", err)
+	}
+	result, err := l.Post(ctx, AuctionHold(sellerId, 30))
+	if err != nil {
+		t.Fatalf("Post(auction_hold): %v", err)
+	}
+	if result.OldBalance(sellerId) != 100 || result.NewBalance(sellerId) != 70 {
+		t.Errorf("balance %v -> %v, want 100 -> 70", result.OldBalance(sellerId), result.NewBalance(sellerId))
+	}
+	checkWalletMatchesLedger(t, l, db, sellerId)
+}
+
+func TestFirstAuctionRelease(t *testing.T) {
+	l, db := testLedger(t)
+	ctx := context.Background()
+	auctionId := primitive.NewObjectID()
+	sellerId := insertLegacyWallet(t, db, 70, testHold{AuctionId: auctionId, Amount: 30})
+	// ReleaseAuctionHolds tertibi: OpenWallets, in_auction pull, AuctionRelease.
+	if err := l.OpenWallets(ctx, sellerId); err != nil {
+		t.Fatalf("OpenWallets(): %v", err)
+	}
+	_, err := db.Collection(CollWallets).UpdateOne(ctx, bson.M{"seller_id": sellerId}, bson.M{
+		"$pull": bson.M{"in_auction": bson.M{"auction_id": auctionId}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateOne(in_auction): %v", err)
+	}
+	_, err = l.Post(ctx, AuctionRelease(Hold{SellerId: sellerId, Amount: 30}))
+	if err != nil {
+		t.Fatalf("Post(auction_release): %v", err)
+	}
+	checkWalletMatchesLedger(t, l, db, sellerId)
+}
+
+func TestFirstWithdraw(t *testing.T) {
+	l, db := testLedger(t)
+	ctx := context.Background()
+	sellerId := insertLegacyWallet(t, db, 100)
+	// Withdraw tertibi: OpenWallets, waiting wallet_history, Withdraw.
+	if err := l.OpenWallets(ctx, sellerId); err != nil {
+		t.Fatalf("OpenWallets(): %v", err)
+	}
+	_, err := db.Collection(CollWalletHistory).InsertOne(ctx, bson.M{
+		"seller_id": sellerId,
+		"intent":    "withdraw",
+		"status":    "waiting",
+		"amount":    20,
+	})
+	if err != nil {
+		t.Fatalf("InsertOne(wallet_history): %v", err)
+	}
+	_, err = l.Post(ctx, Withdraw(sellerId, 20))
+	if err != nil {
+		t.Fatalf("Post(withdraw): %v", err)
+	}
+	checkWalletMatchesLedger(t, l, db, sellerId)
+}
+
+// OpenWallets iki gezek caglansa opening_balance bir gezek yazylyar.
+func TestOpenWalletsOnce(t *testing.T) {
+	l, db := testLedger(t)
+	ctx := context.Background()
+	sellerId := insertLegacyWallet(t, db, 100)
+	for i := 0; i < 2; i++ {
+		if err := l.OpenWallets(ctx, sellerId); err != nil {
+			t.Fatalf("OpenWallets(): %v", err)
+		}
+	}
+	count, err := db.Collection(CollEntries).CountDocuments(ctx, bson.M{"type": EntryOpeningBalance})
+	if err != nil {
+		t.Fatalf("CountDocuments(): %v", err)
+	}
+	if count != 1 {
+		t.Errorf("opening_balance entries: %v, want 1", count)
+	}
+	checkWalletMatchesLedger(t, l, db, sellerId)
+}
@@ -76,7 +76,46 @@ func main() {
 	config.CheckerTaskService.Init(config.MI.DB.Collection("tasks"), config.OjoWS)
 	config.StatisticsService.Init(config.MI.DB.Collection("statistics"), config.MI.DB.Collection("employees"))
 	config.OjoCronService.Init(config.MI.DB.Collection("ojocron_jobs"))
+	config.AuctionService.Init(config.MI.DB, config.OjoWS)
+	config.Ledger.Init(config.MI.DB)
+	config.CodeService.Init(config.MI.DB, os.Getenv(config.WALLET_CODE_SECRET))
+	if err := config.CodeService.RevokeLegacyCodes(context.Background()); err != nil {
+		log.Printf("CodeService.RevokeLegacyCodes(): %v", err)
+	}
+	config.ReceiptService.Init(config.MI.DB, os.Getenv(config.RECEIPT_SECRET))
+	if err := config.ReceiptService.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("ReceiptService.EnsureIndexes(): %v", err)
+	}
+	config.SearchService.Init(config.MI.DB)
+	if err := config.SearchService.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("SearchService.EnsureIndexes(): %v", err)
+	}
+	if err := config.EnsureCashierShiftIndexes(context.Background()); err != nil {
+		log.Printf("EnsureCashierShiftIndexes(): %v", err)
+	}
+	if err := config.EnsureCouponIndexes(context.Background()); err != nil {
+		log.Printf("EnsureCouponIndexes(): %v", err)
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		if err := config.SearchService.RefreshSuggestions(ctx); err != nil {
+			log.Printf("SearchService.RefreshSuggestions(): %v", err)
+		}
+	}()
 	ojocronlisteners.AddOjoCronListeners()
+	if err := ojocronlisteners.ScheduleWalletReconciliation(); err != nil {
+		log.Printf("ScheduleWalletReconciliation(): %v", err)
+	}
+	if err := ojocronlisteners.SchedulePackageLifecycle(); err != nil {
+		log.Printf("SchedulePackageLifecycle(): %v", err)
+	}
+	if err := ojocronlisteners.SchedulePackageAutoRenew(); err != nil {
+		log.Printf("SchedulePackageAutoRenew(): %v", err)
+	}
+	if err := ojocronlisteners.ScheduleSearchReindex(); err != nil {
+		log.Printf("ScheduleSearchReindex(): %v", err)
+	}
 
 	config.EverydayWorkService.Init(config.MI.DB.Collection(config.EMPLOYEES), config.MI.DB.Collection(config.EVERYDAYWORK))
 
@@ -168,11 +207,17 @@ func main() {
 
 		os := c.Query("os", "android")
 
+		var lang *string
+		if lang_ := c.Query("lang"); len(lang_) > 0 {
+			lang = &lang_
+		}
+
 		nToken := notificationmanager.NotificationToken{
 			Token:      token,
 			ClientId:   clientId,
 			ClientType: clientType,
 			OS:         os,
+			Lang:       lang,
 		}
 
 		config.NotificationManager.SaveNotificationToken(nToken)
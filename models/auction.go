@@ -39,7 +39,85 @@ type AuctionDetail struct {
 	StartedAt     time.Time             `json:"started_at" bson:"started_at"`
 	FinishedAt    time.Time             `json:"finished_at" bson:"finished_at"`
 	IsFinished    bool                  `json:"is_finished" bson:"is_finished"`
+	AntiSniping   AuctionAntiSniping    `json:"anti_sniping" bson:"anti_sniping"`
+	Rules         AuctionRules          `json:"rules" bson:"rules"`
+	ReserveMet    *bool                 `json:"reserve_met" bson:"reserve_met"`
 }
+
+// Son `Within` minutda edilen bid finished_at-y `ExtendBy` minut uzaldyar.
+// Jemi uzaldylmasy `MaxExtension` minutdan gecmeyar (0 bolsa cak yok).
+// Within ya-da ExtendBy 0 bolsa, anti-sniping isjen dal.
+type AuctionAntiSniping struct {
+	Within       int64 `json:"within" bson:"within"`
+	ExtendBy     int64 `json:"extend_by" bson:"extend_by"`
+	MaxExtension int64 `json:"max_extension" bson:"max_extension"`
+	Extended     int64 `json:"extended" bson:"extended"` // su wagta cenli jemi uzaldylan minutlar
+}
+
+func (a AuctionAntiSniping) IsEnabled() bool {
+	return a.Within > 0 && a.ExtendBy > 0
+}
+
+// finishedAt-a cenli galan wagt boyunca nace minut uzaltmalydygyny gaytaryar, uzaltmaly dal bolsa 0.
+func (a AuctionAntiSniping) ExtensionFor(bidAt time.Time, finishedAt time.Time) int64 {
+	if !a.IsEnabled() || finishedAt.Sub(bidAt) > time.Duration(a.Within)*time.Minute {
+		return 0
+	}
+	extension := a.ExtendBy
+	if a.MaxExtension > 0 {
+		remaining := a.MaxExtension - a.Extended
+		if remaining <= 0 {
+			return 0
+		}
+		if extension > remaining {
+			extension = remaining
+		}
+	}
+	return extension
+}
+
+const (
+	AuctionIncrementAbsolute = "absolute"
+	AuctionIncrementPercent  = "percent"
+)
+
+// Admin tarapyndan goyulyan auction sertleri.
+// Increment: minimal_bid-den azyndan nace kop bid etmeli (absolute ya-da percent).
+// ReservePrice: in yokary bid sundan pes bolsa hic kim yenmeyar (0 bolsa yok).
+// MaxActivePerSeller: seller-in bir wagtda yenyan auction-lary (in_auction[]) sundan kop bolmaly dal (0 bolsa cak yok).
+// SellerTypes: bid edip bilyan seller type-lar (bos bolsa hemmesi).
+type AuctionRules struct {
+	IncrementType      string   `json:"increment_type" bson:"increment_type"`
+	Increment          float64  `json:"increment" bson:"increment"`
+	ReservePrice       float64  `json:"reserve_price" bson:"reserve_price"`
+	MaxActivePerSeller int64    `json:"max_active_per_seller" bson:"max_active_per_seller"`
+	SellerTypes        []string `json:"seller_types" bson:"seller_types"`
+}
+
+// minimal_bid-e gora indiki bid-in in az mukdary.
+func (r AuctionRules) MinNextBid(minimalBid float64) float64 {
+	if r.IncrementType == AuctionIncrementPercent {
+		return minimalBid + minimalBid*r.Increment/100
+	}
+	return minimalBid + r.Increment
+}
+
+func (r AuctionRules) AllowsSellerType(sellerType string) bool {
+	if len(r.SellerTypes) == 0 {
+		return true
+	}
+	for _, t := range r.SellerTypes {
+		if t == sellerType {
+			return true
+		}
+	}
+	return false
+}
+
+func (r AuctionRules) ReserveMet(highestBid float64) bool {
+	return highestBid >= r.ReservePrice
+}
+
 type NewAuction struct {
 	Image             string                   `bson:"image"`
 	Heading           Translation              `bson:"heading"`
@@ -52,6 +130,9 @@ type NewAuction struct {
 	MinimalBid        int                      `bson:"minimal_bid"`
 	IsFinished        bool                     `bson:"is_finished"`
 	Winners           []AuctionDetailNewWinner `bson:"winners"`
+	AntiSniping       AuctionAntiSniping       `bson:"anti_sniping"`
+	Rules             AuctionRules             `bson:"rules"`
+	ReserveMet        *bool                    `bson:"reserve_met"`
 	Status            string                   `bson:"status"`
 	CreatedAt         time.Time                `bson:"created_at"`
 }
@@ -64,15 +145,56 @@ func (a NewAuction) HasEmptyFields() bool {
 }
 
 type BidAuctionFind struct {
-	Id            primitive.ObjectID    `json:"_id" bson:"_id"`
-	InitialMinBid float64               `json:"initial_min_bid" bson:"initial_min_bid"`
-	MinimalBid    float64               `json:"minimal_bid" bson:"minimal_bid"`
-	Winners       []AuctionDetailWinner `json:"winners" bson:"winners"`
-	Participants  int64                 `json:"participants" bson:"participants"`
-	Heading       Translation           `json:"heading" bson:"heading"`
+	Id                primitive.ObjectID    `json:"_id" bson:"_id"`
+	InitialMinimalBid float64               `json:"initial_minimal_bid" bson:"initial_minimal_bid"`
+	MinimalBid        float64               `json:"minimal_bid" bson:"minimal_bid"`
+	Winners           []AuctionDetailWinner `json:"winners" bson:"winners"`
+	Participants      int64                 `json:"participants" bson:"participants"`
+	Heading           Translation           `json:"heading" bson:"heading"`
+	StartedAt         time.Time             `json:"started_at" bson:"started_at"`
+	FinishedAt        time.Time             `json:"finished_at" bson:"finished_at"`
+	AntiSniping       AuctionAntiSniping    `json:"anti_sniping" bson:"anti_sniping"`
+	Rules             AuctionRules          `json:"rules" bson:"rules"`
 }
 type AuctionForAdminChecker struct {
 	Id          primitive.ObjectID `json:"_id" bson:"_id"`
 	Heading     Translation        `json:"heading" bson:"heading"`
 	Description Translation        `json:"description" bson:"description"`
 }
+
+type AuctionProxyBid struct {
+	Id        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	AuctionId primitive.ObjectID `json:"auction_id" bson:"auction_id"`
+	SellerId  primitive.ObjectID `json:"seller_id" bson:"seller_id"`
+	MaxBid    float64            `json:"max_bid" bson:"max_bid"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt *time.Time         `json:"updated_at" bson:"updated_at"`
+}
+
+// auction_bids: her bid synanysygy we onun netijesi (dispute ucin audit trail).
+type AuctionBid struct {
+	Id             primitive.ObjectID  `json:"_id,omitempty" bson:"_id,omitempty"`
+	AuctionId      primitive.ObjectID  `json:"auction_id" bson:"auction_id"`
+	SellerId       primitive.ObjectID  `json:"seller_id" bson:"seller_id"`
+	Amount         float64             `json:"amount" bson:"amount"`
+	IsProxy        bool                `json:"is_proxy" bson:"is_proxy"`
+	Result         string              `json:"result" bson:"result"`
+	Reason         *string             `json:"reason" bson:"reason"`
+	RefundedAmount float64             `json:"refunded_amount" bson:"refunded_amount"`
+	RefundedAt     *time.Time          `json:"refunded_at" bson:"refunded_at"`
+	History        []AuctionBidHistory `json:"history" bson:"history"`
+	CreatedAt      time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt      *time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type AuctionBidHistory struct {
+	Result    string    `json:"result" bson:"result"`
+	Amount    float64   `json:"amount" bson:"amount"`
+	Note      *string   `json:"note" bson:"note"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+type AuctionBidWithSeller struct {
+	Seller     Seller `json:"seller" bson:"seller"`
+	AuctionBid `json:",inline" bson:",inline"`
+}
@@ -12,11 +12,53 @@ type CompletedCashierWork struct {
 }
 
 type CashierWork struct {
-	Id         primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
-	EmployeeId primitive.ObjectID `json:"employee_id" bson:"employee_id"`
-	SellerId   primitive.ObjectID `json:"seller_id" bson:"seller_id"`
-	Intent     string             `json:"intent" bson:"intent"`
-	Amount     float64            `json:"amount" bson:"amount"`
-	Code       *string            `json:"code" bson:"code"`
-	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	Id         primitive.ObjectID  `json:"_id" bson:"_id,omitempty"`
+	EmployeeId primitive.ObjectID  `json:"employee_id" bson:"employee_id"`
+	SellerId   primitive.ObjectID  `json:"seller_id" bson:"seller_id"`
+	Intent     string              `json:"intent" bson:"intent"`
+	Amount     float64             `json:"amount" bson:"amount"`
+	Code       *string             `json:"code" bson:"code"`
+	ShiftId    primitive.ObjectID  `json:"shift_id" bson:"shift_id,omitempty"`
+	ApprovalId *primitive.ObjectID `json:"approval_id,omitempty" bson:"approval_id,omitempty"`
+	ApprovedBy *primitive.ObjectID `json:"approved_by,omitempty" bson:"approved_by,omitempty"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// threshold-dan uly deposit/withdraw: cashier (maker) doredyar, manager (checker) tassyklayar.
+// Tassyklanmazdan on wallet uytgemeyar.
+type CashierApproval struct {
+	Id              primitive.ObjectID  `json:"_id" bson:"_id,omitempty"`
+	Intent          string              `json:"intent" bson:"intent"`
+	SellerId        primitive.ObjectID  `json:"seller_id" bson:"seller_id"`
+	Amount          float64             `json:"amount" bson:"amount"`
+	WalletHistoryId *primitive.ObjectID `json:"wallet_history_id" bson:"wallet_history_id"` // withdraw-de
+	MakerId         primitive.ObjectID  `json:"maker_id" bson:"maker_id"`
+	ShiftId         primitive.ObjectID  `json:"shift_id" bson:"shift_id"`
+	CheckerId       *primitive.ObjectID `json:"checker_id" bson:"checker_id"`
+	Status          string              `json:"status" bson:"status"` // pending_approval, approved, rejected, expired
+	Reason          *string             `json:"reason" bson:"reason"`
+	CashierWorkId   *primitive.ObjectID `json:"cashier_work_id" bson:"cashier_work_id"`
+	ExpiresAt       time.Time           `json:"expires_at" bson:"expires_at"`
+	DecidedAt       *time.Time          `json:"decided_at" bson:"decided_at"`
+	CreatedAt       time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// cashier-in smenasy: acylanda kassadaky pul yazylyar, yapylanda sanalan pul
+// we smenadaky deposit/withdraw-dan hasaplanan pul bilen tapawut.
+type CashierShift struct {
+	Id               primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	EmployeeId       primitive.ObjectID `json:"employee_id" bson:"employee_id"`
+	EmployeeName     string             `json:"employee_name,omitempty" bson:"employee_name,omitempty"`
+	Status           string             `json:"status" bson:"status"`
+	OpeningCash      float64            `json:"opening_cash" bson:"opening_cash"`
+	Deposits         float64            `json:"deposits" bson:"deposits"`
+	DepositsCount    int64              `json:"deposits_count" bson:"deposits_count"`
+	Withdrawals      float64            `json:"withdrawals" bson:"withdrawals"`
+	WithdrawalsCount int64              `json:"withdrawals_count" bson:"withdrawals_count"`
+	ExpectedCash     *float64           `json:"expected_cash" bson:"expected_cash"`
+	ClosingCash      *float64           `json:"closing_cash" bson:"closing_cash"`
+	Difference       *float64           `json:"difference" bson:"difference"`
+	Note             *string            `json:"note" bson:"note"`
+	OpenedAt         time.Time          `json:"opened_at" bson:"opened_at"`
+	ClosedAt         *time.Time         `json:"closed_at" bson:"closed_at"`
 }
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// package tolegleri ucin promo code. PackageTypes/SellerTypes bos bolsa hemmesi ucin.
+type Coupon struct {
+	Id             primitive.ObjectID  `json:"_id" bson:"_id,omitempty"`
+	Code           string              `json:"code" bson:"code"`
+	DiscountType   string              `json:"discount_type" bson:"discount_type"` // percent, price
+	Value          float64             `json:"value" bson:"value"`
+	ValidFrom      time.Time           `json:"valid_from" bson:"valid_from"`
+	ValidTo        time.Time           `json:"valid_to" bson:"valid_to"`
+	MaxRedemptions int64               `json:"max_redemptions" bson:"max_redemptions"` // 0: caksiz
+	MaxPerSeller   int64               `json:"max_per_seller" bson:"max_per_seller"`   // 0: caksiz
+	Redemptions    int64               `json:"redemptions" bson:"redemptions"`
+	PackageTypes   []string            `json:"package_types" bson:"package_types"`
+	SellerTypes    []string            `json:"seller_types" bson:"seller_types"`
+	Status         string              `json:"status" bson:"status"` // active, blocked
+	Note           string              `json:"note" bson:"note"`
+	CreatedBy      *primitive.ObjectID `json:"created_by" bson:"created_by"`
+	CreatedAt      time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt      *time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type CouponRedemption struct {
+	Id               primitive.ObjectID  `json:"_id" bson:"_id,omitempty"`
+	CouponId         primitive.ObjectID  `json:"coupon_id" bson:"coupon_id"`
+	Code             string              `json:"code" bson:"code"`
+	SellerId         primitive.ObjectID  `json:"seller_id" bson:"seller_id"`
+	PackageType      string              `json:"package_type" bson:"package_type"`
+	Action           string              `json:"action" bson:"action"`
+	PriceBefore      float64             `json:"price_before" bson:"price_before"`
+	Discount         float64             `json:"discount" bson:"discount"`
+	PriceAfter       float64             `json:"price_after" bson:"price_after"`
+	WalletHistoryId  *primitive.ObjectID `json:"wallet_history_id" bson:"wallet_history_id"`
+	PackageHistoryId primitive.ObjectID  `json:"package_history_id" bson:"package_history_id"`
+	N                int64               `json:"n,omitempty" bson:"n,omitempty"` // seller-in nacenji ulanysy, max_per_seller bar bolsa
+	CreatedAt        time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// wallet_history-de package tolegine edilen arzanladys.
+type WalletDiscount struct {
+	CouponId    primitive.ObjectID `json:"coupon_id" bson:"coupon_id"`
+	Code        string             `json:"code" bson:"code"`
+	PriceBefore float64            `json:"price_before" bson:"price_before"`
+	Amount      float64            `json:"amount" bson:"amount"`
+}
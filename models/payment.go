@@ -9,6 +9,28 @@ import (
 type PayPayload struct {
 	PackageType string `json:"package_type"`
 	Action      string `json:"action"`
+	Coupon      string `json:"coupon"`
+}
+
+// PayForPackage-dan on gorkezilyan hasap: upgrade-de ulanylmadyk gunler credit,
+// downgrade package.to-da baslayar.
+type PackagePaymentPreview struct {
+	Action        string    `json:"action"`
+	Mode          string    `json:"mode"` // renew, change, upgrade, downgrade
+	CurrentType   string    `json:"current_type"`
+	NewType       string    `json:"new_type"`
+	CurrentPrice  float64   `json:"current_price"`
+	NewPrice      float64   `json:"new_price"`
+	RemainingDays int64     `json:"remaining_days"`
+	Credit        float64   `json:"credit"`
+	Coupon        *string   `json:"coupon"`
+	Discount      float64   `json:"discount"`
+	Charge        float64   `json:"charge"`
+	Balance       float64   `json:"balance"`
+	Enough        bool      `json:"enough"`
+	EffectiveAt   time.Time `json:"effective_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	Note          string    `json:"note"`
 }
 type WalletTransfer struct {
 	Id          primitive.ObjectID  `json:"_id,omitempty" bson:"_id,omitempty"`
@@ -19,6 +41,7 @@ type WalletTransfer struct {
 	Intent      string              `json:"intent" bson:"intent"`
 	Note        *string             `json:"note" bson:"note"`
 	Code        *string             `json:"code" bson:"code"`
+	Discount    *WalletDiscount     `json:"discount" bson:"discount,omitempty"`
 	Status      string              `json:"status" bson:"status"`
 	EmployeeId  *primitive.ObjectID `json:"employee_id" bson:"employee_id"`
 	CompletedAt *time.Time          `json:"completed_at" bson:"completed_at"`
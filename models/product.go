@@ -24,6 +24,45 @@ type NewProductData struct {
 	Price       float64            `json:"price" bson:"price"`
 	Attributes  []NewProd_Attr     `json:"attributes" bson:"attributes"`
 	Images      []string           `json:"images" bson:"images"`
+
+	ProductPricing `json:",inline" bson:",inline"`
+}
+
+// mukdara gora baha: min_qty-dan max_qty-a cenli (sonky tier-de max_qty nil, mysal 100+).
+type PriceTier struct {
+	MinQty          int64    `json:"min_qty" bson:"min_qty"`
+	MaxQty          *int64   `json:"max_qty" bson:"max_qty"`
+	Price           float64  `json:"price" bson:"price"`
+	DiscountedPrice *float64 `json:"discounted_price,omitempty" bson:"-"`
+}
+
+// product-yn price-y in arzan baha (helpers.LowestProductPrice): filter we sort sony ulanyar.
+type ProductPricing struct {
+	PriceTiers  []PriceTier `json:"price_tiers" bson:"price_tiers"`
+	MinOrderQty int64       `json:"min_order_qty" bson:"min_order_qty"`
+	SaleUnit    string      `json:"sale_unit" bson:"sale_unit"`
+}
+
+// variant_attrs-daky her attribute ucin bir baha; price nil bolsa product-yn price_tiers-i ulanylyar.
+type ProductVariant struct {
+	Id              primitive.ObjectID `json:"_id" bson:"_id"`
+	Attrs           []NewProd_Attr     `json:"attrs" bson:"attrs"`
+	Price           *float64           `json:"price" bson:"price"`
+	DiscountedPrice *float64           `json:"discounted_price,omitempty" bson:"-"`
+	Images          []string           `json:"images" bson:"images"`
+	InStock         bool               `json:"in_stock" bson:"in_stock"`
+}
+
+// db-de variant_attrs category-nyn attribute id-leri.
+type ProductVariants struct {
+	VariantAttrs []primitive.ObjectID `json:"variant_attrs" bson:"variant_attrs"`
+	Variants     []ProductVariant     `json:"variants" bson:"variants"`
+}
+
+// gorkezmek ucin: variant_attrs attributes-dan $lookup edilen.
+type ProductVariantsDetail struct {
+	VariantAttrs []CatAttr        `json:"variant_attrs" bson:"variant_attrs"`
+	Variants     []ProductVariant `json:"variants" bson:"variants"`
 }
 
 type ProductDetail struct {
@@ -47,6 +86,9 @@ type ProductDetailWithoutSeller struct {
 	Viewed       int64                    `json:"viewed" bson:"viewed"`
 	Likes        int64                    `json:"likes" bson:"likes"`
 	Status       string                   `json:"status" bson:"status"`
+
+	ProductPricing        `json:",inline" bson:",inline"`
+	ProductVariantsDetail `json:",inline" bson:",inline"`
 }
 type ProductDetailWithTranslationWithoutSeller struct {
 	Id           primitive.ObjectID       `json:"_id" bson:"_id"`
@@ -75,6 +117,12 @@ type ProductDetailForEditing struct {
 	Brand       ProductDetailBrand           `json:"brand" bson:"brand"`
 	Category    ProductDetailCategory        `json:"category" bson:"category"`
 	Attrs       []ProductForEditingAttribute `json:"attrs" bson:"attrs"`
+
+	ProductPricing        `json:",inline" bson:",inline"`
+	ProductVariantsDetail `json:",inline" bson:",inline"`
+
+	DiscountData      *DiscountData `json:"discount_data" bson:"discount_data"`
+	ScheduledDiscount *DiscountData `json:"scheduled_discount" bson:"scheduled_discount"`
 }
 type ProductDetailWithTranslation struct {
 	Id           primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
@@ -94,6 +142,9 @@ type ProductDetailWithTranslation struct {
 	DiscountData *DiscountData      `json:"discount_data" bson:"discount_data"`
 	// Category     ProductDetailCategory    `json:"category" bson:"category"`
 	// Brand        ProductDetailBrand       `json:"brand" bson:"brand"`
+
+	ProductPricing  `json:",inline" bson:",inline"`
+	ProductVariants `json:",inline" bson:",inline"`
 }
 type ProductDetailForAdminChecker struct {
 	Id          primitive.ObjectID           `json:"_id" bson:"_id"`
@@ -106,6 +157,8 @@ type ProductDetailForAdminChecker struct {
 	Category    ProductCatForAdminChecker    `json:"category" bson:"category"`
 	Brand       ProductBrandForAdminChecker  `json:"brand" bson:"brand"`
 	Seller      SellerForAdminChecker        `json:"seller" bson:"seller"`
+
+	ProductVariantsDetail `json:",inline" bson:",inline"`
 }
 type ProductAttrForAdminChecker struct {
 	Id    primitive.ObjectID `json:"attr_id" bson:"attr_id"`
@@ -153,6 +206,11 @@ type DiscountData struct {
 	Type         string  `json:"type" bson:"type"`
 	Duration     int64   `json:"duration" bson:"duration"`
 	DurationType string  `json:"duration_type" bson:"duration_type"`
+
+	// job-lar haysy discount ucin goyulandygyny su id bilen barlayar.
+	Id       primitive.ObjectID `json:"_id" bson:"_id"`
+	StartsAt *time.Time         `json:"starts_at" bson:"starts_at"`
+	EndsAt   *time.Time         `json:"ends_at" bson:"ends_at"`
 }
 type NewProductForAutoPost struct {
 	Id     primitive.ObjectID `bson:"_id"`
@@ -169,3 +227,40 @@ type RelatedProductInfo struct {
 	Heading string             `json:"heading" bson:"heading"`
 	Image   string             `json:"image" bson:"image"`
 }
+
+// FilterProduct-yn attrs query-si: text attribute ucin values (islendigi),
+// number attribute ucin min/max (unit berilmese attribute-yn birinji birligi).
+type ProductAttrFilter struct {
+	Id     primitive.ObjectID `json:"attr_id"`
+	Values []string           `json:"values"`
+	Min    *float64           `json:"min"`
+	Max    *float64           `json:"max"`
+	Unit   string             `json:"unit"`
+}
+type FacetCount struct {
+	Id    primitive.ObjectID `json:"_id" bson:"_id"`
+	Name  string             `json:"name" bson:"name"`
+	Count int64              `json:"count" bson:"count"`
+}
+type AttributeFacetValue struct {
+	Value interface{} `json:"value" bson:"value"`
+	Unit  string      `json:"unit" bson:"unit"`
+	Count int64       `json:"count" bson:"count"`
+}
+type AttributeFacet struct {
+	Id       primitive.ObjectID    `json:"_id" bson:"_id"`
+	Name     string                `json:"name" bson:"name"`
+	IsNumber bool                  `json:"is_number" bson:"is_number"`
+	Units    []string              `json:"units_array" bson:"units_array"`
+	Values   []AttributeFacetValue `json:"values" bson:"values"`
+	Min      *float64              `json:"min" bson:"min"` // number attribute: Unit birliginde
+	Max      *float64              `json:"max" bson:"max"`
+	Unit     string                `json:"unit" bson:"unit"`
+}
+type ProductsFilterAggregations struct {
+	Total      int64            `json:"total"`
+	Categories []FacetCount     `json:"categories"`
+	Brands     []FacetCount     `json:"brands"`
+	Cities     []FacetCount     `json:"cities"`
+	Attributes []AttributeFacet `json:"attributes"`
+}
@@ -134,6 +134,17 @@ type SellerCurrentPackage struct {
 	PackageHistoryId primitive.ObjectID `json:"package_history_id" bson:"package_history_id"`
 	To               time.Time          `json:"to" bson:"to"`
 	Type             string             `json:"type" bson:"type"`
+	// active ya-da expired (package_lifecycle), grace_until-e cenli seller published galyar.
+	Status     string     `json:"status" bson:"status,omitempty"`
+	GraceUntil *time.Time `json:"grace_until" bson:"grace_until,omitempty"`
+	// package_auto_renew: package.to-dan on wallet-den tolenyar, yalnys bolsa birnace gezek synanysylyar.
+	AutoRenew     bool       `json:"auto_renew" bson:"auto_renew,omitempty"`
+	RenewAttempts int64      `json:"renew_attempts" bson:"renew_attempts,omitempty"`
+	RenewNextAt   *time.Time `json:"renew_next_at" bson:"renew_next_at,omitempty"`
+	RenewFailedAt *time.Time `json:"renew_failed_at" bson:"renew_failed_at,omitempty"`
+	RemindersSent []int64    `json:"reminders_sent" bson:"reminders_sent,omitempty"` // gun: 7, 3, 1
+	// downgrade: package.to-da su package-e gecilyar.
+	PendingType *string `json:"pending_type" bson:"pending_type,omitempty"`
 }
 type SellerProfileOwner struct {
 	Id    primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
@@ -14,6 +14,8 @@ type SellerWallet struct {
 	ClosedAt  *time.Time         `json:"closed_at" bson:"closed_at"`
 	Status    string             `json:"status" bson:"status"`
 	InAuction []InAuctionObject  `json:"in_auction" bson:"in_auction"`
+	// nil bolsa wallet entek ledger-e gecirilmedik (opening_balance yazylmadyk).
+	LedgerOpenedAt *time.Time `json:"ledger_opened_at" bson:"ledger_opened_at"`
 }
 type MyWallet struct {
 	Id        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
@@ -29,6 +31,7 @@ type MyWalletPackage struct {
 	Price       float64   `json:"price" bson:"price"`
 	MaxProducts int64     `json:"max_products" bson:"max_products"`
 	ExpiresAt   time.Time `json:"expires_at" bson:"expires_at"`
+	AutoRenew   bool      `json:"auto_renew" bson:"auto_renew"`
 }
 type InAuction struct {
 	AuctionId primitive.ObjectID `json:"auction_id,omitempty" bson:"auction_id,omitempty"`
@@ -46,6 +49,7 @@ type SellerWalletHistory struct {
 	Intent    string             `json:"intent" bson:"intent"`
 	Note      *string            `json:"note" bson:"note"`
 	Code      *string            `json:"code" bson:"code"`
+	Discount  *WalletDiscount    `json:"discount,omitempty" bson:"discount,omitempty"`
 	Status    string             `json:"status" bson:"status"`
 	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
 }
@@ -63,3 +67,50 @@ type MyWalletHistory struct {
 	CompletedAt *time.Time          `json:"completed_at" bson:"completed_at"`
 	EmployeeId  *primitive.ObjectID `json:"employee_id" bson:"employee_id"`
 }
+
+type WalletReconciliation struct {
+	Id             primitive.ObjectID  `json:"_id" bson:"_id"`
+	Status         string              `json:"status" bson:"status"`
+	WalletsChecked int64               `json:"wallets_checked" bson:"wallets_checked"`
+	Drifts         int64               `json:"drifts" bson:"drifts"`
+	Error          *string             `json:"error" bson:"error"`
+	TriggeredBy    *primitive.ObjectID `json:"triggered_by" bson:"triggered_by"` // nil bolsa ojocron
+	StartedAt      time.Time           `json:"started_at" bson:"started_at"`
+	FinishedAt     *time.Time          `json:"finished_at" bson:"finished_at"`
+}
+
+type WalletDriftHold struct {
+	AuctionId primitive.ObjectID `json:"auction_id" bson:"auction_id"`
+	Amount    float64            `json:"amount" bson:"amount"`
+	Name      Translation        `json:"name" bson:"name"`
+}
+
+type WalletDriftTotals struct {
+	History float64 `json:"history" bson:"history"`
+	Cashier float64 `json:"cashier" bson:"cashier"`
+}
+
+type WalletDrift struct {
+	Id                  primitive.ObjectID  `json:"_id" bson:"_id"`
+	ReconciliationId    primitive.ObjectID  `json:"reconciliation_id" bson:"reconciliation_id"`
+	SellerId            primitive.ObjectID  `json:"seller_id" bson:"seller_id"`
+	WalletId            primitive.ObjectID  `json:"wallet_id" bson:"wallet_id"`
+	Kinds               []string            `json:"kinds" bson:"kinds"`
+	Balance             float64             `json:"balance" bson:"balance"`
+	ExpectedBalance     float64             `json:"expected_balance" bson:"expected_balance"`
+	LedgerBalance       *float64            `json:"ledger_balance" bson:"ledger_balance"`
+	LedgerHeld          *float64            `json:"ledger_held" bson:"ledger_held"`
+	WithdrawPending     float64             `json:"withdraw_pending" bson:"withdraw_pending"`
+	LedgerPending       *float64            `json:"ledger_withdraw_pending" bson:"ledger_withdraw_pending"`
+	Holds               []WalletDriftHold   `json:"holds" bson:"holds"`
+	ExpectedHolds       []WalletDriftHold   `json:"expected_holds" bson:"expected_holds"`
+	Deposits            WalletDriftTotals   `json:"deposits" bson:"deposits"`
+	Payouts             WalletDriftTotals   `json:"payouts" bson:"payouts"`
+	Status              string              `json:"status" bson:"status"`
+	CreatedAt           time.Time           `json:"created_at" bson:"created_at"`
+	FixedAt             *time.Time          `json:"fixed_at" bson:"fixed_at"`
+	FixedBy             *primitive.ObjectID `json:"fixed_by" bson:"fixed_by"`
+	FixNote             *string             `json:"fix_note" bson:"fix_note"`
+	CorrectionEntryId   *primitive.ObjectID `json:"correction_entry_id" bson:"correction_entry_id"`
+	CorrectionHistoryId *primitive.ObjectID `json:"correction_history_id" bson:"correction_history_id"`
+}
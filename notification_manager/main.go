@@ -7,6 +7,7 @@ import (
 
 	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/messaging"
+	"github.com/devzatruk/bizhubBackend/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -18,6 +19,7 @@ type NotificationToken struct {
 	ClientId   *primitive.ObjectID `bson:"client_id"`
 	ClientType *string             `bson:"client_type"`
 	OS         string              `bson:"os"`
+	Lang       *string             `bson:"lang"` // yok bolsa DefaultLang
 }
 
 type NotificationEventClientType struct {
@@ -26,12 +28,43 @@ type NotificationEventClientType struct {
 	Sellers   bool
 }
 
+// berilse, her token ozunin dilinde (token.lang) Title we Description alyar.
+type NotificationEventTranslations struct {
+	Title       models.Translation
+	Description models.Translation
+}
+
 type NotificationEvent struct {
-	Title       string
-	Description string
-	ClientIds   []primitive.ObjectID
-	ClientType  NotificationEventClientType
-	RetryCount  int64
+	Title        string
+	Description  string
+	Translations *NotificationEventTranslations
+	Data         map[string]string // mobile app ucin: deep link we s.m.
+	ClientIds    []primitive.ObjectID
+	ClientType   NotificationEventClientType
+	RetryCount   int64
+	pendingLangs []string // retry-da dine ugradylmadyk diller, nil bolsa ahli Langs
+}
+
+const DefaultLang = "tm"
+
+var Langs = []string{"tm", "ru", "en", "tr"}
+
+func translate(t models.Translation, lang string) string {
+	var str string
+	switch lang {
+	case "ru":
+		str = t.Ru
+	case "en":
+		str = t.En
+	case "tr":
+		str = t.Tr
+	default:
+		str = t.Tm
+	}
+	if len(str) == 0 {
+		str = t.Tm
+	}
+	return str
 }
 
 type NotificationManager struct {
@@ -65,12 +98,53 @@ func (m *NotificationManager) SetDatabase(database *mongo.Database) {
 	m.database = database
 }
 
-func (m *NotificationManager) getTokensCount(event *NotificationEvent) (int64, error) {
+func (m *NotificationManager) getTokensCount(match bson.M) (int64, error) {
 	notificationsColl := m.database.Collection("notification_tokens")
-	match := m.prepareTokensMatchObjectForDb(event)
 	return notificationsColl.CountDocuments(context.Background(), match)
 }
 
+// lang-a gora token filter: DefaultLang lang-y yok token-lary hem oz icine alyar.
+func langMatch(match bson.M, lang string) bson.M {
+	withLang := bson.M{}
+	for k, v := range match {
+		withLang[k] = v
+	}
+	if lang == DefaultLang {
+		withLang["lang"] = bson.M{"$nin": Langs[1:]}
+	} else {
+		withLang["lang"] = lang
+	}
+	return withLang
+}
+
+// seller-leri olaryn owner customer-lerine owuryar, notification_tokens customer-e bagly.
+func (m *NotificationManager) SellerOwners(ctx context.Context, sellerIds []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	cursor, err := m.database.Collection("sellers").Find(ctx, bson.M{
+		"_id": bson.M{"$in": sellerIds},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	owners := []primitive.ObjectID{}
+	for cursor.Next(ctx) {
+		var seller struct {
+			OwnerId primitive.ObjectID `bson:"owner_id"`
+		}
+		err := cursor.Decode(&seller)
+		if err != nil {
+			return nil, err
+		}
+		if seller.OwnerId != primitive.NilObjectID {
+			owners = append(owners, seller.OwnerId)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return owners, nil
+}
+
 func (m *NotificationManager) prepareTokensMatchObjectForDb(event *NotificationEvent) bson.M {
 	match := bson.M{}
 
@@ -98,9 +172,8 @@ func (m *NotificationManager) prepareTokensMatchObjectForDb(event *NotificationE
 	return match
 }
 
-func (m *NotificationManager) getTokens(event *NotificationEvent, limit int, skip int) ([]string, error) {
+func (m *NotificationManager) getTokens(match bson.M, limit int, skip int) ([]string, error) {
 	notificationsColl := m.database.Collection("notification_tokens")
-	match := m.prepareTokensMatchObjectForDb(event)
 
 	ctx := context.Background()
 	tokens := []string{}
@@ -139,7 +212,7 @@ func (m *NotificationManager) getTokens(event *NotificationEvent, limit int, ski
 	return tokens, nil
 }
 
-func (m *NotificationManager) sendNotification(tokens []string, title string, description string) error {
+func (m *NotificationManager) sendNotification(tokens []string, title string, description string, data map[string]string) error {
 	// notification-i ugratmaly
 
 	message := &messaging.MulticastMessage{
@@ -148,6 +221,7 @@ func (m *NotificationManager) sendNotification(tokens []string, title string, de
 			Title: title,
 			Body:  description,
 		},
+		Data: data,
 	}
 
 	_, err := m.client.SendMulticast(context.Background(), message)
@@ -177,29 +251,57 @@ func (m *NotificationManager) pump() {
 			}
 
 			fmt.Printf("\n[notification] - event - %v\n", event)
-			tokensCount, err := m.getTokensCount(event)
-			if err != nil {
-				m.retryNotification(event)
-				fmt.Printf("\n[notification] - tokens count error - %v\n", err)
+			match := m.prepareTokensMatchObjectForDb(event)
+			if event.Translations == nil {
+				err := m.sendToMatch(event, match, event.Title, event.Description)
+				if err != nil {
+					m.retryNotification(event)
+				}
 				continue
 			}
-
-			limit := 50
-
-			loopCount := int(math.Ceil(float64(tokensCount) / float64(limit))) // 110 / 2
-
-			for i := 0; i < loopCount; i++ {
-				tokens, err := m.getTokens(event, limit, i*int(limit))
+			langs := event.pendingLangs
+			if langs == nil {
+				langs = Langs
+			}
+			failedLangs := []string{}
+			for _, lang := range langs {
+				err := m.sendToMatch(event, langMatch(match, lang),
+					translate(event.Translations.Title, lang),
+					translate(event.Translations.Description, lang))
 				if err != nil {
-					fmt.Printf("\n[notification] - tokens error - %v\n", err)
-					continue
+					failedLangs = append(failedLangs, lang)
 				}
-
-				m.sendNotification(tokens, event.Title, event.Description)
 			}
+			// ugradylan diller gaytadan ugradylmaz yaly dine sowsuz diller tazeden synanysylyar
+			if len(failedLangs) > 0 {
+				event.pendingLangs = failedLangs
+				m.retryNotification(event)
+			}
+		}
+	}
+}
+
+func (m *NotificationManager) sendToMatch(event *NotificationEvent, match bson.M, title string, description string) error {
+	tokensCount, err := m.getTokensCount(match)
+	if err != nil {
+		fmt.Printf("\n[notification] - tokens count error - %v\n", err)
+		return err
+	}
 
+	limit := 50
+
+	loopCount := int(math.Ceil(float64(tokensCount) / float64(limit))) // 110 / 2
+
+	for i := 0; i < loopCount; i++ {
+		tokens, err := m.getTokens(match, limit, i*int(limit))
+		if err != nil {
+			fmt.Printf("\n[notification] - tokens error - %v\n", err)
+			continue
 		}
+
+		m.sendNotification(tokens, title, description, event.Data)
 	}
+	return nil
 }
 
 func (m *NotificationManager) SaveNotificationToken(token NotificationToken) error {
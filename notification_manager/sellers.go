@@ -0,0 +1,114 @@
+package notificationmanager
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/devzatruk/bizhubBackend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// .env-daki `{{prefix}}En`, `{{prefix}}Tm`... setirleri, parametrsiz.
+func EnvTranslation(prefix string) models.Translation {
+	return models.Translation{
+		En: os.Getenv(prefix + "En"),
+		Tm: os.Getenv(prefix + "Tm"),
+		Tr: os.Getenv(prefix + "Tr"),
+		Ru: os.Getenv(prefix + "Ru"),
+	}
+}
+
+// .env-daki `{{prefix}}En`, `{{prefix}}Tm`... setirlerini ahli dillerde sol bir args bilen dolduryar.
+func EnvTranslationf(prefix string, args ...any) models.Translation {
+	format := func(lang string) string {
+		return fmt.Sprintf(os.Getenv(prefix+lang), args...)
+	}
+	return models.Translation{
+		En: format("En"),
+		Tm: format("Tm"),
+		Tr: format("Tr"),
+		Ru: format("Ru"),
+	}
+}
+
+// .env-daki `{{prefix}}En`, `{{prefix}}Tm`... setirlerini dolduryar, birinji %v auction-yn ady (sol dilde).
+func AuctionTranslation(prefix string, heading models.Translation, args ...any) models.Translation {
+	format := func(lang string, name string) string {
+		return fmt.Sprintf(os.Getenv(prefix+lang), append([]any{name}, args...)...)
+	}
+	return models.Translation{
+		En: format("En", heading.En),
+		Tm: format("Tm", heading.Tm),
+		Tr: format("Tr", heading.Tr),
+		Ru: format("Ru", heading.Ru),
+	}
+}
+
+// seller-in dili: owner customer-in sonky notification token-ynyn lang-y, yok bolsa DefaultLang.
+func (m *NotificationManager) SellerLang(ctx context.Context, sellerId primitive.ObjectID) (string, error) {
+	owners, err := m.SellerOwners(ctx, []primitive.ObjectID{sellerId})
+	if err != nil {
+		return "", err
+	}
+	if len(owners) == 0 {
+		return DefaultLang, nil
+	}
+	var token NotificationToken
+	err = m.database.Collection("notification_tokens").FindOne(ctx, bson.M{
+		"client_id":   owners[0],
+		"client_type": "customer",
+		"lang":        bson.M{"$in": Langs},
+	}, options.FindOne().SetSort(bson.M{"_id": -1})).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return DefaultLang, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return *token.Lang, nil
+}
+
+// seller-lerin owner customer-lerine push notification ugradyar, her kime oz dilinde.
+// data mobile app ucin: type, link...
+func (m *NotificationManager) NotifySellers(ctx context.Context, sellerIds []primitive.ObjectID, title models.Translation,
+	description models.Translation, data map[string]string) error {
+	if len(sellerIds) == 0 {
+		return nil
+	}
+	owners, err := m.SellerOwners(ctx, sellerIds)
+	if err != nil {
+		return err
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+	m.AddNotificationEvent(&NotificationEvent{
+		Title:       title.Tm,
+		Description: description.Tm,
+		Translations: &NotificationEventTranslations{
+			Title:       title,
+			Description: description,
+		},
+		Data:      data,
+		ClientIds: owners,
+		ClientType: NotificationEventClientType{
+			Customers: true,
+		},
+	})
+	return nil
+}
+
+// seller-lerin owner customer-lerine auction-a deep link bilen push notification ugradyar.
+// titlePrefix .env-daki title setirleri, description eyyam terjime edilen.
+func (m *NotificationManager) NotifyAuctionSellers(ctx context.Context, auctionId primitive.ObjectID, sellerIds []primitive.ObjectID,
+	titlePrefix string, description models.Translation) error {
+	return m.NotifySellers(ctx, sellerIds, EnvTranslation(titlePrefix), description, map[string]string{
+		"type":       "auction",
+		"auction_id": auctionId.Hex(),
+		"link":       fmt.Sprintf(os.Getenv("AuctionDeepLink"), auctionId.Hex()),
+	})
+}
@@ -9,7 +9,9 @@ import (
 
 	"github.com/devzatruk/bizhubBackend/ojologger"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ongoingJobAction struct {
@@ -72,7 +74,7 @@ func (s *OjoCronService) On(listenerName string, listener OjoCronListener) {
 func (s *OjoCronService) ongoingJobAction(j *OjoCronJob, action bool) {
 	action_ := "add"
 	if action == false {
-		action_ = "remove"
+		action_ = "delete" // channelCron dine "delete" kabul edyar
 	}
 
 	j.service.ongoingJobChan <- &ongoingJobAction{
@@ -161,6 +163,90 @@ func (s *OjoCronService) RemoveJobsByGroup(group interface{}) error {
 	return err
 }
 
+// group-yn dine berlen listener-li job-laryny pozyar, beyleki job-lar galyar.
+func (s *OjoCronService) RemoveJobsByGroupListeners(group interface{}, listenerNames ...string) error {
+	_, err := s.coll.DeleteMany(context.Background(), bson.M{
+		"group": group,
+		"listener": bson.M{
+			"$in": listenerNames,
+		},
+	})
+
+	return err
+}
+
+// group-yn job id-leri (listenerNames berilse dine solaryn): taze job-lar goyulandan son
+// kone job-lary RemoveJobsByIds() bilen pozmak ucin.
+func (s *OjoCronService) JobIdsByGroup(group interface{}, listenerNames ...string) ([]primitive.ObjectID, error) {
+	filter := bson.M{
+		"group": group,
+	}
+	if len(listenerNames) > 0 {
+		filter["listener"] = bson.M{
+			"$in": listenerNames,
+		}
+	}
+	ctx := context.Background()
+	cursor, err := s.coll.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var jobs []struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(jobs))
+	for _, job := range jobs {
+		ids = append(ids, job.Id)
+	}
+	return ids, nil
+}
+
+func (s *OjoCronService) RemoveJobsByIds(ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := s.coll.DeleteMany(context.Background(), bson.M{
+		"_id": bson.M{
+			"$in": ids,
+		},
+	})
+
+	return err
+}
+
+// group-yn ids-de bolmadyk job-laryny pozyar: yalnys bolan amalda taze goyulan job-lar.
+func (s *OjoCronService) RemoveJobsByGroupExcept(group interface{}, ids []primitive.ObjectID) error {
+	if ids == nil {
+		ids = []primitive.ObjectID{}
+	}
+	_, err := s.coll.DeleteMany(context.Background(), bson.M{
+		"group": group,
+		"_id": bson.M{
+			"$nin": ids,
+		},
+	})
+
+	return err
+}
+
+// group we listener boyunca entek islenmedik job-laryn run_at wagtyny uytgedyar.
+func (s *OjoCronService) RescheduleJobsByGroup(group interface{}, listenerName string, runAt time.Time) error {
+	_, err := s.coll.UpdateMany(context.Background(), bson.M{
+		"group":    group,
+		"listener": listenerName,
+		"status":   "active",
+	}, bson.M{
+		"$set": bson.M{
+			"run_at": runAt,
+		},
+	})
+
+	return err
+}
+
 func (s *OjoCronService) expiredJobsCron() {
 	log := s.logger.Group("expiredJobsCron()")
 	tick_every, err := time.ParseDuration(os.Getenv("OJOCRON_EXPIRES_TICKER"))
@@ -0,0 +1,92 @@
+package packageservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/helpers"
+	"github.com/devzatruk/bizhubBackend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrCouponNotFound    = errors.New("Coupon not found.")
+	ErrCouponNotValid    = errors.New("Coupon is not valid at this time.")
+	ErrCouponExhausted   = errors.New("Coupon usage limit reached.")
+	ErrCouponUsed        = errors.New("Coupon already used by this seller.")
+	ErrCouponNotEligible = errors.New("Coupon is not eligible for this package or seller.")
+)
+
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// PayPackage()-dan on: coupon su seller we package ucin ulanylyp bilyarmi.
+// Jemi we seller limiti PayPackage()-de atomic barlanyar, bu yerde dine on barlag.
+func findCouponForPayment(ctx context.Context, code string, sellerId primitive.ObjectID, sellerType string,
+	packageType string) (*models.Coupon, error) {
+	var coupon models.Coupon
+	err := config.MI.DB.Collection(config.COUPONS).FindOne(ctx, bson.M{
+		"code":   NormalizeCouponCode(code),
+		"status": config.STATUS_ACTIVE,
+	}).Decode(&coupon)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrCouponNotFound
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if now.Before(coupon.ValidFrom) || !now.Before(coupon.ValidTo) {
+		return nil, ErrCouponNotValid
+	}
+	if coupon.MaxRedemptions > 0 && coupon.Redemptions >= coupon.MaxRedemptions {
+		return nil, ErrCouponExhausted
+	}
+	if len(coupon.PackageTypes) > 0 && !helpers.SliceContains(coupon.PackageTypes, packageType) {
+		return nil, ErrCouponNotEligible
+	}
+	if len(coupon.SellerTypes) > 0 && !helpers.SliceContains(coupon.SellerTypes, sellerType) {
+		return nil, ErrCouponNotEligible
+	}
+	if coupon.MaxPerSeller > 0 {
+		used, err := config.MI.DB.Collection(config.COUPON_REDEMPTIONS).CountDocuments(ctx, bson.M{
+			"coupon_id": coupon.Id,
+			"seller_id": sellerId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if used >= coupon.MaxPerSeller {
+			return nil, ErrCouponUsed
+		}
+	}
+	return &coupon, nil
+}
+
+// arzanladys tolegden uly bolup bilmeyar.
+func couponDiscount(coupon *models.Coupon, price float64) float64 {
+	discount := coupon.Value
+	if coupon.DiscountType == config.DISCOUNT_PERCENT {
+		discount = price * coupon.Value / 100
+	}
+	if discount > price {
+		discount = price
+	}
+	return roundCents(discount)
+}
+
+func couponErrorCode(err error) string {
+	switch err {
+	case ErrCouponNotFound:
+		return config.NOT_FOUND
+	case ErrCouponNotValid, ErrCouponExhausted, ErrCouponUsed, ErrCouponNotEligible:
+		return config.NOT_ALLOWED
+	}
+	return config.DBQUERY_ERROR
+}
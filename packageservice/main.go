@@ -0,0 +1,27 @@
+package packageservice
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/devzatruk/bizhubBackend/ojologger"
+	ojoTr "github.com/devzatruk/bizhubBackend/transaction_manager"
+)
+
+// seller package tolegleri (pay, upgrade, downgrade), coupon-lar we toleg gecende seller-i
+// yzyna gaytarmak. PayForPackage/PreviewPackagePayment controller-leri we package_auto_renew,
+// package_lifecycle job-lary sunu ulanyar.
+
+var packageLogger = ojologger.LoggerService.Logger("PackageService")
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func rollbackWith(transaction_manager *ojoTr.TransactionManager, err error) error {
+	trErr := transaction_manager.Rollback()
+	if trErr != nil {
+		return fmt.Errorf("Source: %v - Rollback: %v", err.Error(), trErr.Error())
+	}
+	return err
+}
@@ -0,0 +1,447 @@
+package packageservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/ledger"
+	"github.com/devzatruk/bizhubBackend/models"
+	notificationmanager "github.com/devzatruk/bizhubBackend/notification_manager"
+	ojoTr "github.com/devzatruk/bizhubBackend/transaction_manager"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PayPackage() yalnys bolsa haysy funksiyada we haysy code bilen gaytarmalydygyny bilmek ucin.
+type PackagePaymentError struct {
+	Fn   string
+	Err  error
+	Code string
+}
+
+func (e *PackagePaymentError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Fn, e.Err)
+}
+
+func (e *PackagePaymentError) Unwrap() error {
+	return e.Err
+}
+
+var ErrBalanceNotEnough = errors.New("No enough funds.")
+
+// balans yetmezligi: auto renew dine sunda synanysyk sanayar, beyleki yalnyslyklar wagtlayyn.
+func IsBalanceNotEnough(err error) bool {
+	return errors.Is(err, ErrBalanceNotEnough) || errors.Is(err, ledger.ErrInsufficientFunds)
+}
+
+type PackagePayment struct {
+	Type      string
+	Price     float64
+	Balance   float64
+	ExpiresAt time.Time
+	Preview   *models.PackagePaymentPreview
+}
+
+type packagePaymentPlan struct {
+	preview        models.PackagePaymentPreview
+	sellerId       primitive.ObjectID
+	currentPackage models.SellerCurrentPackage
+	wallet         models.SellerWallet
+	effectiveAt    time.Time
+	oldPackage     *string
+	coupon         *models.Coupon
+	priceBefore    float64
+}
+
+// .env-daki `{{prefix}}En`, `{{prefix}}Tm`... setirlerinden lang-a gora biri.
+func envTextf(prefix string, lang string, args ...any) string {
+	translation := notificationmanager.EnvTranslationf(prefix, args...)
+	switch lang {
+	case "en":
+		return translation.En
+	case "ru":
+		return translation.Ru
+	case "tr":
+		return translation.Tr
+	}
+	return translation.Tm
+}
+
+func findPackage(ctx context.Context, packageType string) (*models.PackageWithoutName, error) {
+	var packageDetail models.PackageWithoutName
+	err := config.MI.DB.Collection(config.PACKAGES).FindOne(ctx, bson.M{
+		"type": packageType,
+	}).Decode(&packageDetail)
+	if err != nil {
+		return nil, err
+	}
+	return &packageDetail, nil
+}
+
+// PayPackage() name etjegini hasaplayar, hic zat uytgetmeyar.
+func planPackagePayment(ctx context.Context, sellerObjId primitive.ObjectID, payPayload models.PayPayload, lang string) (*packagePaymentPlan, error) {
+	var sellerInfo struct {
+		SellerId primitive.ObjectID          `bson:"_id"`
+		Type     string                      `bson:"type"`
+		Package  models.SellerCurrentPackage `bson:"package"`
+	}
+	err := config.MI.DB.Collection(config.SELLERS).FindOne(ctx, bson.M{"_id": sellerObjId}).Decode(&sellerInfo)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, &PackagePaymentError{"NilObjectID", errors.New("Seller not found."), config.NOT_FOUND}
+		}
+		return nil, &PackagePaymentError{"FindOne(seller)", err, config.DBQUERY_ERROR}
+	}
+	var sellerWallet models.SellerWallet
+	err = config.MI.DB.Collection(config.WALLETS).FindOne(ctx, bson.M{"seller_id": sellerObjId}).Decode(&sellerWallet)
+	if err != nil {
+		return nil, &PackagePaymentError{"FindOne(wallet)", err, config.NOT_FOUND}
+	}
+	current := sellerInfo.Package
+	currentPackage, err := findPackage(ctx, current.Type)
+	if err != nil {
+		return nil, &PackagePaymentError{"FindOne(package)", err, config.NOT_FOUND}
+	}
+	// pay: sol package (ya-da garasylyan downgrade), changed: taze package.
+	newType := current.Type
+	if payPayload.Action == config.PACKAGE_CHANGE {
+		newType = payPayload.PackageType
+		if newType == current.Type {
+			return nil, &PackagePaymentError{"SamePackage", errors.New("Seller already has this package."), config.NOT_ALLOWED}
+		}
+	} else if current.PendingType != nil {
+		newType = *current.PendingType
+	}
+	newPackage, err := findPackage(ctx, newType)
+	if err != nil {
+		return nil, &PackagePaymentError{"FindOne(package)", err, config.NOT_FOUND}
+	}
+	now := time.Now()
+	plan := &packagePaymentPlan{
+		sellerId:       sellerObjId,
+		currentPackage: current,
+		wallet:         sellerWallet,
+		preview: models.PackagePaymentPreview{
+			Action:       payPayload.Action,
+			CurrentType:  current.Type,
+			NewType:      newType,
+			CurrentPrice: currentPackage.Price,
+			NewPrice:     newPackage.Price,
+			Balance:      sellerWallet.Balance,
+		},
+	}
+	if newType != current.Type {
+		plan.oldPackage = &current.Type
+	}
+	preview := &plan.preview
+	switch {
+	case payPayload.Action != config.PACKAGE_CHANGE:
+		// package eyyam gutaran bolsa (grace/notpaid) 1 ay su gunden hasaplanyar.
+		preview.Mode = config.PACKAGE_MODE_RENEW
+		plan.effectiveAt = current.To
+		if plan.effectiveAt.Before(now) {
+			plan.effectiveAt = now
+		}
+		preview.Charge = newPackage.Price
+		preview.Note = envTextf("MonthlyPaymentNote", lang, newType, newPackage.Price)
+	case !current.To.After(now):
+		preview.Mode = config.PACKAGE_MODE_CHANGE
+		plan.effectiveAt = now
+		preview.Charge = newPackage.Price
+		preview.Note = envTextf("MonthlyPaymentNote", lang, newType, newPackage.Price)
+	case newPackage.Price >= currentPackage.Price:
+		// upgrade: taze period su gun baslayar, kone package-in galan gunleri credit.
+		preview.Mode = config.PACKAGE_MODE_UPGRADE
+		plan.effectiveAt = now
+		periodFrom := current.To.AddDate(0, -1, 0)
+		var history models.SellerPackageHistoryFull
+		err = config.MI.DB.Collection(config.PACKAGEHISTORY).FindOne(ctx, bson.M{"_id": current.PackageHistoryId}).Decode(&history)
+		if err == nil && history.From.Before(current.To) && history.To.Equal(current.To) {
+			periodFrom = history.From
+		}
+		remaining := current.To.Sub(now)
+		period := current.To.Sub(periodFrom)
+		if remaining > period {
+			remaining = period
+		}
+		preview.RemainingDays = int64(math.Ceil(remaining.Hours() / 24))
+		preview.Credit = roundCents(currentPackage.Price * float64(remaining) / float64(period))
+		preview.Charge = roundCents(math.Max(newPackage.Price-preview.Credit, 0))
+		preview.Note = envTextf("PackageUpgradeNote", lang, current.Type, newType, preview.Credit,
+			preview.RemainingDays, preview.Charge)
+	default:
+		// downgrade: su wagtky package package.to-a cenli galyar, son taze package tolenyar.
+		preview.Mode = config.PACKAGE_MODE_DOWNGRADE
+		plan.effectiveAt = current.To
+		preview.RemainingDays = int64(math.Ceil(current.To.Sub(now).Hours() / 24))
+		preview.Note = envTextf("PackageDowngradeNote", lang, current.Type, newType,
+			current.To.Format("02.01.2006"), newPackage.Price)
+	}
+	if payPayload.Coupon != "" {
+		// downgrade tolegsiz, coupon ulanyp bolmayar.
+		if preview.Mode == config.PACKAGE_MODE_DOWNGRADE {
+			return nil, &PackagePaymentError{"Coupon", ErrCouponNotEligible, config.NOT_ALLOWED}
+		}
+		coupon, err := findCouponForPayment(ctx, payPayload.Coupon, sellerObjId, sellerInfo.Type, newType)
+		if err != nil {
+			return nil, &PackagePaymentError{"findCouponForPayment()", err, couponErrorCode(err)}
+		}
+		plan.coupon = coupon
+		plan.priceBefore = preview.Charge
+		preview.Coupon = &coupon.Code
+		preview.Discount = couponDiscount(coupon, preview.Charge)
+		preview.Charge = roundCents(preview.Charge - preview.Discount)
+		preview.Note = fmt.Sprintf("%v %v", preview.Note, envTextf("CouponDiscountNote", lang, coupon.Code, preview.Discount))
+	}
+	preview.EffectiveAt = plan.effectiveAt
+	preview.ExpiresAt = plan.effectiveAt.AddDate(0, 1, 0) // 1 ay uzaldyas!
+	if preview.Mode == config.PACKAGE_MODE_DOWNGRADE {
+		preview.ExpiresAt = current.To
+	}
+	preview.Enough = sellerWallet.Balance >= preview.Charge
+	return plan, nil
+}
+
+// PayForPackage-dan on: name tolenjegi, credit we package hacan gutarjagy.
+func PreviewPackagePayment(ctx context.Context, sellerObjId primitive.ObjectID, payPayload models.PayPayload, lang string) (*models.PackagePaymentPreview, error) {
+	plan, err := planPackagePayment(ctx, sellerObjId, payPayload, lang)
+	if err != nil {
+		return nil, err
+	}
+	return &plan.preview, nil
+}
+
+// wallet-den package tolegi: wallet_history, seller.transfers[], package_history we ledger.
+// PayForPackage we package_auto_renew sunu ulanyar. Package eyyam gutaran bolsa (grace/notpaid),
+// 1 ay su gunden hasaplanyar we seller yzyna gaytarylyar. Downgrade tolegsiz, dine bellenilyar.
+func PayPackage(ctx context.Context, sellerObjId primitive.ObjectID, payPayload models.PayPayload, lang string) (*PackagePayment, error) {
+	plan, err := planPackagePayment(ctx, sellerObjId, payPayload, lang)
+	if err != nil {
+		return nil, err
+	}
+	preview := &plan.preview
+	if preview.Mode == config.PACKAGE_MODE_DOWNGRADE {
+		return scheduleDowngrade(ctx, plan)
+	}
+	if !preview.Enough {
+		return nil, &PackagePaymentError{"BalanceNotEnough", ErrBalanceNotEnough, config.NOT_ALLOWED}
+	}
+	transaction_manager := ojoTr.NewTransaction(&ctx, config.MI.DB, 3)
+	now := time.Now()
+	// upgrade-de credit bahadan uly bolup bilmeyar, yone 0 bolsa wallet_history/ledger gerek dal.
+	// Coupon bilen 0 bolsa wallet_history galyar: arzanladys hasabatda gorunsin.
+	var tr_historyId primitive.ObjectID
+	hasHistory := preview.Charge > 0 || plan.coupon != nil
+	if hasHistory {
+		// wallet_history.InsertNewTransfer()
+		newTransfer := models.WalletTransfer{
+			SellerId:    plan.wallet.SellerId,
+			WalletId:    plan.wallet.Id,
+			OldBalance:  plan.wallet.Balance,
+			Amount:      preview.Charge,
+			Intent:      config.INTENT_PAYMENT,
+			Note:        &preview.Note,
+			Code:        nil,
+			Status:      config.STATUS_COMPLETED,
+			EmployeeId:  nil,
+			CreatedAt:   now,
+			CompletedAt: &now,
+		}
+		if plan.coupon != nil {
+			newTransfer.Discount = &models.WalletDiscount{
+				CouponId:    plan.coupon.Id,
+				Code:        plan.coupon.Code,
+				PriceBefore: plan.priceBefore,
+				Amount:      preview.Discount,
+			}
+		}
+		tr_whistoryColl := transaction_manager.Collection(config.WALLETHISTORY)
+		tr_insertResult, err := tr_whistoryColl.InsertOne(ojoTr.NewModel().SetDocument(newTransfer))
+		if err != nil {
+			return nil, &PackagePaymentError{"InsertOne(transfer)", rollbackWith(transaction_manager, err), config.CANT_INSERT}
+		}
+		tr_historyId = tr_insertResult.InsertedID.(primitive.ObjectID)
+	}
+	newPackageHistory := models.SellerPackageHistoryFull{
+		SellerId:       sellerObjId,
+		From:           plan.effectiveAt,
+		To:             preview.ExpiresAt,
+		AmountPaid:     preview.Charge,
+		Action:         payPayload.Action,
+		OldPackage:     plan.oldPackage,
+		CurrentPackage: preview.NewType,
+		Text:           preview.Note,
+		CreatedAt:      now,
+	}
+	if plan.oldPackage != nil {
+		newPackageHistory.Action = config.PACKAGE_CHANGE
+	}
+	tr_packHisColl := transaction_manager.Collection(config.PACKAGEHISTORY)
+	tr_insertResult, err := tr_packHisColl.InsertOne(ojoTr.NewModel().SetDocument(newPackageHistory))
+	if err != nil {
+		return nil, &PackagePaymentError{"InsertOne(package_history)", rollbackWith(transaction_manager, err), config.CANT_INSERT}
+	}
+	packageHistoryId := tr_insertResult.InsertedID.(primitive.ObjectID)
+	if plan.coupon != nil {
+		err = redeemCoupon(ctx, transaction_manager, plan, tr_historyId, packageHistoryId, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// package subdocument-in beyleki field-leri (status, auto_renew...) galsyn diye dine su field-ler.
+	update := bson.M{
+		"$set": bson.M{
+			"package.package_history_id": packageHistoryId,
+			"package.to":                 preview.ExpiresAt,
+			"package.type":               preview.NewType,
+		},
+		"$unset": bson.M{
+			"package.pending_type": "",
+		},
+	}
+	rollbackUpdate := bson.M{
+		"$set": bson.M{
+			"package.package_history_id": plan.currentPackage.PackageHistoryId,
+			"package.to":                 plan.currentPackage.To,
+			"package.type":               plan.currentPackage.Type,
+			"package.pending_type":       plan.currentPackage.PendingType,
+		},
+	}
+	if hasHistory {
+		update["$push"] = bson.M{
+			"transfers": bson.M{
+				"$each":     bson.A{tr_historyId},
+				"$slice":    2,
+				"$position": 0,
+			},
+		}
+		rollbackUpdate["$pull"] = bson.M{
+			"transfers": tr_historyId,
+		}
+	}
+	tr_sellersColl := transaction_manager.Collection(config.SELLERS)
+	_, err = tr_sellersColl.UpdateOne(ojoTr.NewModel().
+		SetFilter(bson.M{"_id": sellerObjId}).
+		SetUpdate(update).
+		SetRollbackUpdate(rollbackUpdate))
+	if err != nil {
+		return nil, &PackagePaymentError{"UpdateOne(seller.package)", rollbackWith(transaction_manager, err), config.CANT_UPDATE}
+	}
+	balance := plan.wallet.Balance
+	if preview.Charge > 0 {
+		postResult, err := config.Ledger.Post(ctx, ledger.PackagePayment(sellerObjId, preview.Charge).
+			WithRef(config.WALLETHISTORY, tr_historyId).
+			WithNote(preview.Note))
+		if err != nil {
+			fn, code := "Ledger.Post(package_payment)", config.CANT_UPDATE
+			if err == ledger.ErrInsufficientFunds {
+				fn, code = "BalanceNotEnough", config.NOT_ALLOWED
+			}
+			return nil, &PackagePaymentError{fn, rollbackWith(transaction_manager, err), code}
+		}
+		balance = postResult.NewBalance(sellerObjId)
+	}
+	log := packageLogger.Group("PayPackage()")
+	// toleg eyyam gecdi: asakdakylar yalnys bolsa package_lifecycle/auto_renew job-lary dolduryar.
+	err = RestoreSellerPackage(ctx, sellerObjId)
+	if err != nil {
+		log.Errorf("RestoreSellerPackage(): %v", err)
+	}
+	err = resetPackageRenewal(ctx, sellerObjId)
+	if err != nil {
+		log.Errorf("resetPackageRenewal(): %v", err)
+	}
+	if plan.coupon != nil && preview.Discount > 0 {
+		config.StatisticsService.Writer.MoneyDiscounted(preview.Discount)
+	}
+	return &PackagePayment{
+		Type:      preview.NewType,
+		Price:     preview.Charge,
+		Balance:   balance,
+		ExpiresAt: preview.ExpiresAt,
+		Preview:   preview,
+	}, nil
+}
+
+// package.pending_type package_lifecycle-de package.to gecende ya-da indiki PayPackage(pay)-de ulanylyar.
+func scheduleDowngrade(ctx context.Context, plan *packagePaymentPlan) (*PackagePayment, error) {
+	_, err := config.MI.DB.Collection(config.SELLERS).UpdateOne(ctx,
+		bson.M{"_id": plan.sellerId},
+		bson.M{
+			"$set": bson.M{"package.pending_type": plan.preview.NewType},
+		})
+	if err != nil {
+		return nil, &PackagePaymentError{"UpdateOne(seller.package.pending_type)", err, config.CANT_UPDATE}
+	}
+	return &PackagePayment{
+		Type:      plan.preview.NewType,
+		Price:     0,
+		Balance:   plan.wallet.Balance,
+		ExpiresAt: plan.preview.ExpiresAt,
+		Preview:   &plan.preview,
+	}, nil
+}
+
+// coupon.redemptions limitden gecmezligi filter-de barlanyar: parallel tolegleden dine biri gecyar.
+// max_per_seller bolsa seller-in ulanysy n nomeri bilen yazylyar, unique index sol seller-in
+// parallel toleglerinin birini goyberyar.
+func redeemCoupon(ctx context.Context, transaction_manager *ojoTr.TransactionManager, plan *packagePaymentPlan, walletHistoryId primitive.ObjectID,
+	packageHistoryId primitive.ObjectID, now time.Time) error {
+	coupon := plan.coupon
+	tr_couponsColl := transaction_manager.Collection(config.COUPONS)
+	_, err := tr_couponsColl.UpdateOne(ojoTr.NewModel().
+		SetFilter(bson.M{
+			"_id":    coupon.Id,
+			"status": config.STATUS_ACTIVE,
+			"$or": bson.A{
+				bson.M{"max_redemptions": 0},
+				bson.M{"$expr": bson.M{"$lt": bson.A{"$redemptions", "$max_redemptions"}}},
+			},
+		}).
+		SetUpdate(bson.M{"$inc": bson.M{"redemptions": 1}}).
+		SetRollbackFilter(bson.M{"_id": coupon.Id}). // limit-e yetende filter gabat gelmez
+		SetRollbackUpdate(bson.M{"$inc": bson.M{"redemptions": -1}}))
+	if err != nil {
+		return &PackagePaymentError{"UpdateOne(coupon.redemptions)", rollbackWith(transaction_manager, ErrCouponExhausted), config.NOT_ALLOWED}
+	}
+	redemption := models.CouponRedemption{
+		CouponId:         coupon.Id,
+		Code:             coupon.Code,
+		SellerId:         plan.sellerId,
+		PackageType:      plan.preview.NewType,
+		Action:           plan.preview.Action,
+		PriceBefore:      plan.priceBefore,
+		Discount:         plan.preview.Discount,
+		PriceAfter:       plan.preview.Charge,
+		WalletHistoryId:  &walletHistoryId,
+		PackageHistoryId: packageHistoryId,
+		CreatedAt:        now,
+	}
+	if coupon.MaxPerSeller > 0 {
+		used, err := config.MI.DB.Collection(config.COUPON_REDEMPTIONS).CountDocuments(ctx, bson.M{
+			"coupon_id": coupon.Id,
+			"seller_id": plan.sellerId,
+		})
+		if err != nil {
+			return &PackagePaymentError{"CountDocuments(coupon_redemptions)", rollbackWith(transaction_manager, err), config.DBQUERY_ERROR}
+		}
+		if used >= coupon.MaxPerSeller {
+			return &PackagePaymentError{"CountDocuments(coupon_redemptions)", rollbackWith(transaction_manager, ErrCouponUsed), config.NOT_ALLOWED}
+		}
+		redemption.N = used + 1
+	}
+	tr_redemptionsColl := transaction_manager.Collection(config.COUPON_REDEMPTIONS)
+	_, err = tr_redemptionsColl.InsertOne(ojoTr.NewModel().SetDocument(redemption))
+	if mongo.IsDuplicateKeyError(err) {
+		return &PackagePaymentError{"InsertOne(coupon_redemption)", rollbackWith(transaction_manager, ErrCouponUsed), config.NOT_ALLOWED}
+	}
+	if err != nil {
+		return &PackagePaymentError{"InsertOne(coupon_redemption)", rollbackWith(transaction_manager, err), config.CANT_INSERT}
+	}
+	return nil
+}
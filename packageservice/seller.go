@@ -0,0 +1,70 @@
+package packageservice
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devzatruk/bizhubBackend/config"
+	"github.com/devzatruk/bizhubBackend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// package.to gelejekde bolsa seller-i published, package-i active edyar we gizlenen product-lary acyar.
+// package.to entek gecen bolsa hic zat etmeyar.
+func RestoreSellerPackage(ctx context.Context, sellerId primitive.ObjectID) error {
+	sellersColl := config.MI.DB.Collection(config.SELLERS)
+	var seller struct {
+		Status  string                      `bson:"status"`
+		Package models.SellerCurrentPackage `bson:"package"`
+	}
+	err := sellersColl.FindOne(ctx, bson.M{"_id": sellerId}).Decode(&seller)
+	if err != nil {
+		return fmt.Errorf("FindOne(seller): %v", err)
+	}
+	if !seller.Package.To.After(time.Now()) {
+		return nil
+	}
+	if seller.Status != config.SELLER_STATUS_NOTPAID && seller.Package.Status != config.STATUS_EXPIRED {
+		return nil
+	}
+	_, err = config.MI.DB.Collection(config.PRODUCTS).UpdateMany(ctx,
+		bson.M{
+			"seller_id": sellerId,
+			"status":    config.STATUS_HIDDEN,
+		},
+		bson.M{
+			"$set": bson.M{"status": config.STATUS_PUBLISHED},
+		})
+	if err != nil {
+		return fmt.Errorf("UpdateMany(products.published): %v", err)
+	}
+	set := bson.M{"package.status": config.STATUS_ACTIVE}
+	if seller.Status == config.SELLER_STATUS_NOTPAID {
+		set["status"] = config.SELLER_STATUS_PUBLISHED
+	}
+	_, err = sellersColl.UpdateOne(ctx,
+		bson.M{"_id": sellerId},
+		bson.M{
+			"$set":   set,
+			"$unset": bson.M{"package.grace_until": ""},
+		})
+	if err != nil {
+		return fmt.Errorf("UpdateOne(seller.restore): %v", err)
+	}
+	return nil
+}
+
+// taze period: auto renew synanysyklary we reminder-ler tazeden baslayar.
+func resetPackageRenewal(ctx context.Context, sellerId primitive.ObjectID) error {
+	_, err := config.MI.DB.Collection(config.SELLERS).UpdateOne(ctx, bson.M{"_id": sellerId}, bson.M{
+		"$unset": bson.M{
+			"package.renew_attempts":  "",
+			"package.renew_next_at":   "",
+			"package.renew_failed_at": "",
+			"package.reminders_sent":  "",
+		},
+	})
+	return err
+}
@@ -0,0 +1,280 @@
+package receiptservice
+
+import (
+	"bytes"
+	"compress/zlib"
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Inter (SIL Open Font License): latyn, turkmen, turk we kiril harplary bar.
+// PDF-e dolylygyna gomulyar (FontFile2), text glyph id bilen yazylyar (Identity-H).
+//
+//go:embed fonts/Inter-Regular.ttf
+var interRegular []byte
+
+const pdfFontName = "Inter-Regular"
+
+var errBadFont = errors.New("receipt font: not a valid TrueType font")
+
+type trueTypeFont struct {
+	unitsPerEm uint16
+	bbox       [4]int16
+	ascent     int16
+	descent    int16
+	capHeight  int16
+	glyphs     map[rune]uint16
+	advances   []uint16
+}
+
+var (
+	pdfFontOnce sync.Once
+	pdfFont     *trueTypeFont
+	pdfFontErr  error
+)
+
+func loadPDFFont() (*trueTypeFont, error) {
+	pdfFontOnce.Do(func() {
+		pdfFont, pdfFontErr = parseTrueType(interRegular)
+	})
+	return pdfFont, pdfFontErr
+}
+
+// PDF ucin gerek table-lar: head, hhea, hmtx, OS/2 we cmap (format 4 ya-da 12).
+func parseTrueType(data []byte) (*trueTypeFont, error) {
+	if len(data) < 12 {
+		return nil, errBadFont
+	}
+	tables := map[string][]byte{}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		record := 12 + 16*i
+		if record+16 > len(data) {
+			return nil, errBadFont
+		}
+		offset := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if offset+length > len(data) {
+			return nil, errBadFont
+		}
+		tables[string(data[record:record+4])] = data[offset : offset+length]
+	}
+	head, hhea, hmtx, cmap := tables["head"], tables["hhea"], tables["hmtx"], tables["cmap"]
+	if len(head) < 54 || len(hhea) < 36 || len(cmap) < 4 {
+		return nil, errBadFont
+	}
+	font := &trueTypeFont{
+		unitsPerEm: binary.BigEndian.Uint16(head[18:]),
+		ascent:     int16(binary.BigEndian.Uint16(hhea[4:])),
+		descent:    int16(binary.BigEndian.Uint16(hhea[6:])),
+	}
+	for i := range font.bbox {
+		font.bbox[i] = int16(binary.BigEndian.Uint16(head[36+2*i:]))
+	}
+	font.capHeight = font.ascent
+	if os2 := tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		font.capHeight = int16(binary.BigEndian.Uint16(os2[88:]))
+	}
+	numberOfHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	if len(hmtx) < 4*numberOfHMetrics {
+		return nil, errBadFont
+	}
+	font.advances = make([]uint16, numberOfHMetrics)
+	for i := range font.advances {
+		font.advances[i] = binary.BigEndian.Uint16(hmtx[4*i:])
+	}
+	glyphs, err := parseCmap(cmap)
+	if err != nil {
+		return nil, err
+	}
+	font.glyphs = glyphs
+	return font, nil
+}
+
+// windows unicode subtable-lary: ilki (3, 10) format 12, yok bolsa (3, 1) format 4.
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	var subtable4, subtable12 []byte
+	numTables := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < numTables; i++ {
+		record := 4 + 8*i
+		if record+8 > len(cmap) {
+			return nil, errBadFont
+		}
+		platformId := binary.BigEndian.Uint16(cmap[record:])
+		encodingId := binary.BigEndian.Uint16(cmap[record+2:])
+		offset := int(binary.BigEndian.Uint32(cmap[record+4:]))
+		if platformId != 3 || offset+4 > len(cmap) {
+			continue
+		}
+		format := binary.BigEndian.Uint16(cmap[offset:])
+		switch {
+		case encodingId == 10 && format == 12:
+			subtable12 = cmap[offset:]
+		case encodingId == 1 && format == 4:
+			subtable4 = cmap[offset:]
+		}
+	}
+	glyphs := map[rune]uint16{}
+	if subtable12 != nil && len(subtable12) >= 16 {
+		numGroups := int(binary.BigEndian.Uint32(subtable12[12:]))
+		if len(subtable12) < 16+12*numGroups {
+			return nil, errBadFont
+		}
+		for i := 0; i < numGroups; i++ {
+			group := subtable12[16+12*i:]
+			start := binary.BigEndian.Uint32(group)
+			end := binary.BigEndian.Uint32(group[4:])
+			glyph := binary.BigEndian.Uint32(group[8:])
+			for r := start; r <= end && r <= 0x10FFFF; r++ {
+				glyphs[rune(r)] = uint16(glyph + r - start)
+			}
+		}
+		return glyphs, nil
+	}
+	if subtable4 == nil || len(subtable4) < 14 {
+		return nil, errBadFont
+	}
+	segCount := int(binary.BigEndian.Uint16(subtable4[6:])) / 2
+	endCodes := 14
+	startCodes := endCodes + 2*segCount + 2
+	idDeltas := startCodes + 2*segCount
+	idRangeOffsets := idDeltas + 2*segCount
+	if len(subtable4) < idRangeOffsets+2*segCount {
+		return nil, errBadFont
+	}
+	for i := 0; i < segCount; i++ {
+		end := int(binary.BigEndian.Uint16(subtable4[endCodes+2*i:]))
+		start := int(binary.BigEndian.Uint16(subtable4[startCodes+2*i:]))
+		delta := binary.BigEndian.Uint16(subtable4[idDeltas+2*i:])
+		rangeOffset := int(binary.BigEndian.Uint16(subtable4[idRangeOffsets+2*i:]))
+		for c := start; c <= end && c != 0xFFFF; c++ {
+			var glyph uint16
+			if rangeOffset == 0 {
+				glyph = uint16(c) + delta
+			} else {
+				index := idRangeOffsets + 2*i + rangeOffset + 2*(c-start)
+				if index+2 > len(subtable4) {
+					continue
+				}
+				glyph = binary.BigEndian.Uint16(subtable4[index:])
+				if glyph != 0 {
+					glyph += delta
+				}
+			}
+			if glyph != 0 {
+				glyphs[rune(c)] = glyph
+			}
+		}
+	}
+	return glyphs, nil
+}
+
+// PDF-de 1000 birlikde.
+func (f *trueTypeFont) width(glyph uint16) int {
+	advance := f.advances[len(f.advances)-1]
+	if int(glyph) < len(f.advances) {
+		advance = f.advances[glyph]
+	}
+	return int(advance) * 1000 / int(f.unitsPerEm)
+}
+
+func (f *trueTypeFont) scale(value int16) int {
+	return int(value) * 1000 / int(f.unitsPerEm)
+}
+
+// renderPDF-de ulanylan glyph-ler: W (width) we ToUnicode dine solar ucin yazylyar.
+type pdfTextEncoder struct {
+	font *trueTypeFont
+	used map[uint16]rune
+}
+
+func newPDFTextEncoder(font *trueTypeFont) *pdfTextEncoder {
+	return &pdfTextEncoder{font: font, used: map[uint16]rune{}}
+}
+
+// Identity-H hex string: her harp iki baytly glyph id.
+func (e *pdfTextEncoder) encode(s string) string {
+	var builder strings.Builder
+	builder.WriteByte('<')
+	for _, r := range s {
+		glyph := e.font.glyphs[r]
+		if _, ok := e.used[glyph]; !ok && glyph != 0 {
+			e.used[glyph] = r
+		}
+		fmt.Fprintf(&builder, "%04X", glyph)
+	}
+	builder.WriteByte('>')
+	return builder.String()
+}
+
+func (e *pdfTextEncoder) sortedGlyphs() []uint16 {
+	glyphs := make([]uint16, 0, len(e.used))
+	for glyph := range e.used {
+		glyphs = append(glyphs, glyph)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	return glyphs
+}
+
+func (e *pdfTextEncoder) widths() string {
+	var builder strings.Builder
+	builder.WriteByte('[')
+	for _, glyph := range e.sortedGlyphs() {
+		fmt.Fprintf(&builder, " %d [%d]", glyph, e.font.width(glyph))
+	}
+	builder.WriteString(" ]")
+	return builder.String()
+}
+
+// text kopyalananda ya-da gozlenende dogry harplar cyksyn.
+func (e *pdfTextEncoder) toUnicode() string {
+	var builder strings.Builder
+	builder.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	builder.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	builder.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	builder.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	glyphs := e.sortedGlyphs()
+	for len(glyphs) > 0 {
+		n := 100 // bir blokda in kop 100 bfchar
+		if len(glyphs) < n {
+			n = len(glyphs)
+		}
+		fmt.Fprintf(&builder, "%d beginbfchar\n", n)
+		for _, glyph := range glyphs[:n] {
+			builder.WriteString(fmt.Sprintf("<%04X> <", glyph))
+			for _, unit := range utf16Units(e.used[glyph]) {
+				fmt.Fprintf(&builder, "%04X", unit)
+			}
+			builder.WriteString(">\n")
+		}
+		builder.WriteString("endbfchar\n")
+		glyphs = glyphs[n:]
+	}
+	builder.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return builder.String()
+}
+
+func utf16Units(r rune) []uint16 {
+	if r < 0x10000 {
+		return []uint16{uint16(r)}
+	}
+	r -= 0x10000
+	return []uint16{uint16(0xD800 + (r >> 10)), uint16(0xDC00 + (r & 0x3FF))}
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := zlib.NewWriter(&buffer)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
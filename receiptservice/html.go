@@ -0,0 +1,93 @@
+package receiptservice
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"time"
+)
+
+const timeLayout = "02.01.2006 15:04:05"
+
+type receiptRow struct {
+	Label string
+	Value string
+}
+
+// HTML we PDF ucin birmenzes setirler.
+func receiptRows(view *ReceiptView, l receiptLabels) []receiptRow {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Format(timeLayout)
+	}
+	optional := func(s *string) string {
+		if s == nil || *s == "" {
+			return "-"
+		}
+		return *s
+	}
+	rows := []receiptRow{
+		{l.Seller, view.SellerName},
+		{l.Amount, fmt.Sprintf("%.2f TMT", view.Amount)},
+		{l.Intent, l.intent(view.Intent)},
+		{l.Status, l.status(view.Status)},
+		{l.Cashier, optional(view.EmployeeName)},
+		{l.Created, formatTime(&view.CreatedAt)},
+		{l.Completed, formatTime(view.CompletedAt)},
+		{l.Issued, formatTime(&view.Receipt.IssuedAt)},
+	}
+	if view.Note != nil && *view.Note != "" {
+		rows = append(rows, receiptRow{l.Note, *view.Note})
+	}
+	return rows
+}
+
+var receiptTemplate = template.Must(template.New("receipt").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} № {{.Number}}</title>
+<style>
+body { font-family: Arial, Helvetica, sans-serif; color: #222; max-width: 480px; margin: 24px auto; }
+h1 { font-size: 20px; margin: 0; }
+h2 { font-size: 16px; margin: 4px 0 16px; font-weight: normal; }
+table { width: 100%; border-collapse: collapse; }
+td { padding: 6px 0; border-bottom: 1px solid #ddd; vertical-align: top; }
+td.label { color: #666; width: 40%; }
+.hash { font-family: monospace; font-size: 11px; word-break: break-all; }
+.footer { margin-top: 16px; font-size: 12px; color: #666; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>BIZHUB</h1>
+<h2>{{.Title}} № {{.Number}}</h2>
+<table>
+{{range .Rows}}<tr><td class="label">{{.Label}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+<div class="footer">
+<p>{{.VerificationLabel}}:<br><span class="hash">{{.Hash}}</span></p>
+<p>{{.TimeZone}}</p>
+</div>
+</body>
+</html>
+`))
+
+func renderHTML(view *ReceiptView, l receiptLabels, lang string) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := receiptTemplate.Execute(&buffer, map[string]any{
+		"Lang":              lang,
+		"Title":             l.Title,
+		"Number":            FormatNumber(view.Receipt.Number),
+		"Rows":              receiptRows(view, l),
+		"VerificationLabel": l.Verification,
+		"Hash":              view.Receipt.Hash,
+		"TimeZone":          l.TimeZone,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,150 @@
+package receiptservice
+
+type receiptLabels struct {
+	Title        string
+	Seller       string
+	Amount       string
+	Intent       string
+	Status       string
+	Cashier      string
+	Created      string
+	Completed    string
+	Issued       string
+	Note         string
+	Verification string
+	TimeZone     string
+	Intents      map[string]string
+	Statuses     map[string]string
+}
+
+func (l receiptLabels) intent(intent string) string {
+	if label, ok := l.Intents[intent]; ok {
+		return label
+	}
+	return intent
+}
+
+func (l receiptLabels) status(status string) string {
+	if label, ok := l.Statuses[status]; ok {
+		return label
+	}
+	return status
+}
+
+var labels = map[string]receiptLabels{
+	"tm": {
+		Title:        "Kwitansiýa",
+		Seller:       "Satyjy",
+		Amount:       "Mukdar",
+		Intent:       "Amal",
+		Status:       "Ýagdaýy",
+		Cashier:      "Kassir",
+		Created:      "Döredildi",
+		Completed:    "Tamamlandy",
+		Issued:       "Berildi",
+		Note:         "Bellik",
+		Verification: "Barlag hash-y",
+		TimeZone:     "Wagtlar Aşgabat wagty boýunça (UTC+5).",
+		Intents: map[string]string{
+			"deposit":    "Hasaba pul salmak",
+			"withdraw":   "Pul çykarmak",
+			"payment":    "Paket tölegi",
+			"refund":     "Yzyna gaýtarmak",
+			"correction": "Düzediş",
+		},
+		Statuses: map[string]string{
+			"completed": "Tamamlandy",
+			"waiting":   "Garaşylýar",
+			"cancelled": "Ýatyryldy",
+			"expired":   "Möhleti geçdi",
+		},
+	},
+	"ru": {
+		Title:        "Квитанция",
+		Seller:       "Продавец",
+		Amount:       "Сумма",
+		Intent:       "Операция",
+		Status:       "Статус",
+		Cashier:      "Кассир",
+		Created:      "Создано",
+		Completed:    "Завершено",
+		Issued:       "Выдано",
+		Note:         "Примечание",
+		Verification: "Проверочный хеш",
+		TimeZone:     "Время указано по Ашхабаду (UTC+5).",
+		Intents: map[string]string{
+			"deposit":    "Пополнение",
+			"withdraw":   "Снятие",
+			"payment":    "Оплата пакета",
+			"refund":     "Возврат",
+			"correction": "Корректировка",
+		},
+		Statuses: map[string]string{
+			"completed": "Завершено",
+			"waiting":   "В ожидании",
+			"cancelled": "Отменено",
+			"expired":   "Истекло",
+		},
+	},
+	"en": {
+		Title:        "Receipt",
+		Seller:       "Seller",
+		Amount:       "Amount",
+		Intent:       "Operation",
+		Status:       "Status",
+		Cashier:      "Cashier",
+		Created:      "Created",
+		Completed:    "Completed",
+		Issued:       "Issued",
+		Note:         "Note",
+		Verification: "Verification hash",
+		TimeZone:     "Times are in Ashgabat time (UTC+5).",
+		Intents: map[string]string{
+			"deposit":    "Deposit",
+			"withdraw":   "Withdrawal",
+			"payment":    "Package payment",
+			"refund":     "Refund",
+			"correction": "Correction",
+		},
+		Statuses: map[string]string{
+			"completed": "Completed",
+			"waiting":   "Waiting",
+			"cancelled": "Cancelled",
+			"expired":   "Expired",
+		},
+	},
+	"tr": {
+		Title:        "Makbuz",
+		Seller:       "Satıcı",
+		Amount:       "Tutar",
+		Intent:       "İşlem",
+		Status:       "Durum",
+		Cashier:      "Kasiyer",
+		Created:      "Oluşturuldu",
+		Completed:    "Tamamlandı",
+		Issued:       "Verildi",
+		Note:         "Not",
+		Verification: "Doğrulama hash'i",
+		TimeZone:     "Saatler Aşkabat saatine göredir (UTC+5).",
+		Intents: map[string]string{
+			"deposit":    "Para yatırma",
+			"withdraw":   "Para çekme",
+			"payment":    "Paket ödemesi",
+			"refund":     "İade",
+			"correction": "Düzeltme",
+		},
+		Statuses: map[string]string{
+			"completed": "Tamamlandı",
+			"waiting":   "Bekliyor",
+			"cancelled": "İptal edildi",
+			"expired":   "Süresi doldu",
+		},
+	},
+}
+
+func labelsFor(lang string) receiptLabels {
+	if l, ok := labels[lang]; ok {
+		return l
+	}
+	return labels["tm"]
+}
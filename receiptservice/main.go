@@ -0,0 +1,296 @@
+package receiptservice
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	CollReceipts      = "receipts"
+	CollCounters      = "counters"
+	CollWalletHistory = "wallet_history"
+	CollSellers       = "sellers"
+	CollEmployees     = "employees"
+)
+
+const (
+	FormatHTML     = "html"
+	FormatPDF      = "pdf"
+	receiptCounter = "receipts"
+)
+
+var (
+	ErrHistoryNotFound = errors.New("receipt: wallet_history not found")
+	ErrReceiptNotFound = errors.New("receipt: not found")
+	ErrInvalidHash     = errors.New("receipt: verification hash doesn't match")
+	ErrNoSecret        = errors.New("receipt: secret not set")
+)
+
+// wallet_history entry-e berlen kwitansiya. Nomer bir gezek berilyar we uytgemeyar.
+type Receipt struct {
+	Id              primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Number          int64              `json:"number" bson:"number"`
+	WalletHistoryId primitive.ObjectID `json:"wallet_history_id" bson:"wallet_history_id"`
+	SellerId        primitive.ObjectID `json:"seller_id" bson:"seller_id"`
+	Hash            string             `json:"hash" bson:"hash"`
+	IssuedAt        time.Time          `json:"issued_at" bson:"issued_at"`
+}
+
+// kwitansiyada gorkezilyan maglumatlar, wagtlar Ashgabat wagtynda.
+type ReceiptView struct {
+	Receipt      Receipt    `json:"receipt"`
+	SellerName   string     `json:"seller_name"`
+	Amount       float64    `json:"amount"`
+	Intent       string     `json:"intent"`
+	Status       string     `json:"status"`
+	Note         *string    `json:"note"`
+	EmployeeName *string    `json:"employee_name"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at"`
+}
+
+type walletHistory struct {
+	Id          primitive.ObjectID  `bson:"_id"`
+	SellerId    primitive.ObjectID  `bson:"seller_id"`
+	Amount      float64             `bson:"amount"`
+	Intent      string              `bson:"intent"`
+	Note        bson.RawValue       `bson:"note"` // string ya-da {tm, ru, en, tr}
+	Status      string              `bson:"status"`
+	EmployeeId  *primitive.ObjectID `bson:"employee_id"`
+	CreatedAt   time.Time           `bson:"created_at"`
+	CompletedAt *time.Time          `bson:"completed_at"`
+}
+
+// wallet_history-den kwitansiya: yzygider nomer we HMAC bilen barlag hash-y.
+type ReceiptService struct {
+	db       *mongo.Database
+	secret   []byte
+	location *time.Location
+}
+
+func NewReceiptService() *ReceiptService {
+	location, err := time.LoadLocation("Asia/Ashgabat")
+	if err != nil {
+		// tzdata yok bolsa: Ashgabat UTC+5, tomusky wagt yok.
+		location = time.FixedZone("TMT", 5*60*60)
+	}
+	return &ReceiptService{location: location}
+}
+
+func (s *ReceiptService) Init(db *mongo.Database, secret string) {
+	s.db = db
+	s.secret = []byte(secret)
+}
+
+// bir wallet_history-e bir kwitansiya: parallel issue() upsert-lerinden dine biri yazylyar.
+func (s *ReceiptService) EnsureIndexes(ctx context.Context) error {
+	_, err := s.db.Collection(CollReceipts).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "wallet_history_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("CreateOne(receipts): %v", err)
+	}
+	return nil
+}
+
+func (s *ReceiptService) Location() *time.Location {
+	return s.location
+}
+
+// hash dine uytgemeyan field-lerden: status uytgese-de kwitansiya dogry galyar.
+func (s *ReceiptService) sign(number int64, history *walletHistory) (string, error) {
+	if len(s.secret) == 0 {
+		return "", ErrNoSecret
+	}
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%d|%v|%v|%.2f|%v|%d", number, history.Id.Hex(), history.SellerId.Hex(),
+		history.Amount, history.Intent, history.CreatedAt.Unix())
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (s *ReceiptService) findHistory(ctx context.Context, walletHistoryId primitive.ObjectID) (*walletHistory, error) {
+	var history walletHistory
+	err := s.db.Collection(CollWalletHistory).FindOne(ctx, bson.M{"_id": walletHistoryId}).Decode(&history)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrHistoryNotFound
+		}
+		return nil, err
+	}
+	return &history, nil
+}
+
+func (s *ReceiptService) nextNumber(ctx context.Context) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := s.db.Collection(CollCounters).FindOneAndUpdate(ctx,
+		bson.M{"_id": receiptCounter},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+	return counter.Seq, nil
+}
+
+// entry-nin kwitansiyasy, yok bolsa taze nomer bilen doredilyar.
+// Parallel cagyrylsa dine biri yazylyar, beyleki nomer bos galyar.
+func (s *ReceiptService) issue(ctx context.Context, history *walletHistory) (*Receipt, error) {
+	coll := s.db.Collection(CollReceipts)
+	var receipt Receipt
+	err := coll.FindOne(ctx, bson.M{"wallet_history_id": history.Id}).Decode(&receipt)
+	if err == nil {
+		return &receipt, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	number, err := s.nextNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := s.sign(number, history)
+	if err != nil {
+		return nil, err
+	}
+	receipt = Receipt{
+		Id:              primitive.NewObjectID(),
+		Number:          number,
+		WalletHistoryId: history.Id,
+		SellerId:        history.SellerId,
+		Hash:            hash,
+		IssuedAt:        time.Now(),
+	}
+	_, err = coll.UpdateOne(ctx,
+		bson.M{"wallet_history_id": history.Id},
+		bson.M{"$setOnInsert": receipt},
+		options.Update().SetUpsert(true))
+	if err != nil && !mongo.IsDuplicateKeyError(err) { // parallel issue() eyyam yazdy
+		return nil, err
+	}
+	err = coll.FindOne(ctx, bson.M{"wallet_history_id": history.Id}).Decode(&receipt)
+	if err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// sellerId nil dal bolsa entry sol seller-inki bolmaly (mobile), nil: cashier/admin.
+func (s *ReceiptService) Build(ctx context.Context, walletHistoryId primitive.ObjectID, sellerId *primitive.ObjectID,
+	lang string) (*ReceiptView, error) {
+	history, err := s.findHistory(ctx, walletHistoryId)
+	if err != nil {
+		return nil, err
+	}
+	if sellerId != nil && history.SellerId != *sellerId {
+		return nil, ErrHistoryNotFound
+	}
+	receipt, err := s.issue(ctx, history)
+	if err != nil {
+		return nil, err
+	}
+	view := ReceiptView{
+		Receipt:   *receipt,
+		Amount:    history.Amount,
+		Intent:    history.Intent,
+		Status:    history.Status,
+		Note:      noteText(history.Note, lang),
+		CreatedAt: history.CreatedAt.In(s.location),
+	}
+	view.Receipt.IssuedAt = receipt.IssuedAt.In(s.location)
+	if history.CompletedAt != nil {
+		completedAt := history.CompletedAt.In(s.location)
+		view.CompletedAt = &completedAt
+	}
+	var seller struct {
+		Name string `bson:"name"`
+	}
+	err = s.db.Collection(CollSellers).FindOne(ctx, bson.M{"_id": history.SellerId}).Decode(&seller)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	view.SellerName = seller.Name
+	if history.EmployeeId != nil {
+		var employee struct {
+			FullName string `bson:"full_name"`
+		}
+		err = s.db.Collection(CollEmployees).FindOne(ctx, bson.M{"_id": *history.EmployeeId}).Decode(&employee)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+		if employee.FullName != "" {
+			view.EmployeeName = &employee.FullName
+		}
+	}
+	return &view, nil
+}
+
+// kwitansiyadaky nomer we hash entry-nin haziki maglumatlary bilen gabat gelyarmi.
+func (s *ReceiptService) Verify(ctx context.Context, number int64, hash string) (*Receipt, error) {
+	var receipt Receipt
+	err := s.db.Collection(CollReceipts).FindOne(ctx, bson.M{"number": number}).Decode(&receipt)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrReceiptNotFound
+		}
+		return nil, err
+	}
+	history, err := s.findHistory(ctx, receipt.WalletHistoryId)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := s.sign(receipt.Number, history)
+	if err != nil {
+		return nil, err
+	}
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if !hmac.Equal([]byte(expected), []byte(hash)) || !hmac.Equal([]byte(receipt.Hash), []byte(hash)) {
+		return nil, ErrInvalidHash
+	}
+	return &receipt, nil
+}
+
+func noteText(note bson.RawValue, lang string) *string {
+	switch note.Type {
+	case bson.TypeString:
+		text := note.StringValue()
+		return &text
+	case bson.TypeEmbeddedDocument:
+		value, err := note.Document().LookupErr(lang)
+		if err != nil {
+			return nil
+		}
+		if text, ok := value.StringValueOK(); ok {
+			return &text
+		}
+	}
+	return nil
+}
+
+// HTML ya-da PDF: content type we body.
+func (s *ReceiptService) Render(view *ReceiptView, format string, lang string) (string, []byte, error) {
+	labels := labelsFor(lang)
+	if format == FormatPDF {
+		body, err := renderPDF(view, labels)
+		return "application/pdf", body, err
+	}
+	body, err := renderHTML(view, labels, lang)
+	return "text/html; charset=utf-8", body, err
+}
+
+func FormatNumber(number int64) string {
+	return fmt.Sprintf("%06d", number)
+}
@@ -0,0 +1,148 @@
+package receiptservice
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Gosmaca kitaphanasyz PDF: text gomulen Inter font-y bilen (font.go), barlag hash-y
+// standart Courier bilen (dine hex harplar).
+const (
+	pdfPageWidth  = 420 // A5
+	pdfPageHeight = 595
+	pdfMargin     = 40
+	pdfValueX     = 170
+	pdfWrapAt     = 42
+)
+
+// Courier ucin ASCII literal string: (, ) we \ escape edilyar.
+func pdfString(s string) string {
+	var builder strings.Builder
+	builder.WriteByte('(')
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			builder.WriteByte('\\')
+			builder.WriteRune(r)
+		case r < 32 || r > 126:
+			builder.WriteByte('?')
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	builder.WriteByte(')')
+	return builder.String()
+}
+
+// uzyn bahalary sozlere gora bolyar.
+func wrapText(s string, width int) []string {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return []string{""}
+	}
+	lines := []string{}
+	line := ""
+	for _, word := range words {
+		for len([]rune(word)) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:width]))
+			word = string(runes[width:])
+		}
+		if line == "" {
+			line = word
+		} else if len([]rune(line))+1+len([]rune(word)) <= width {
+			line += " " + word
+		} else {
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	return append(lines, line)
+}
+
+func renderPDF(view *ReceiptView, l receiptLabels) ([]byte, error) {
+	font, err := loadPDFFont()
+	if err != nil {
+		return nil, err
+	}
+	encoder := newPDFTextEncoder(font)
+	var content bytes.Buffer
+	text := func(size int, x int, y int, s string) {
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d %d Td %v Tj ET\n", size, x, y, encoder.encode(s))
+	}
+	// Inter-in bold-y yok: harplar hem doldurylyar hem cyzylyar.
+	boldText := func(size int, x int, y int, s string) {
+		fmt.Fprintf(&content, "q 0.4 w BT /F1 %d Tf 2 Tr %d %d Td %v Tj ET Q\n", size, x, y, encoder.encode(s))
+	}
+	y := pdfPageHeight - pdfMargin - 16
+	boldText(16, pdfMargin, y, "BIZHUB")
+	y -= 22
+	boldText(12, pdfMargin, y, fmt.Sprintf("%v № %v", l.Title, FormatNumber(view.Receipt.Number)))
+	y -= 12
+	fmt.Fprintf(&content, "0.8 G %d %d m %d %d l S\n", pdfMargin, y, pdfPageWidth-pdfMargin, y)
+	y -= 20
+	for _, row := range receiptRows(view, l) {
+		text(10, pdfMargin, y, row.Label)
+		for _, line := range wrapText(row.Value, pdfWrapAt) {
+			text(10, pdfValueX, y, line)
+			y -= 14
+		}
+		y -= 4
+	}
+	fmt.Fprintf(&content, "%d %d m %d %d l S\n", pdfMargin, y+8, pdfPageWidth-pdfMargin, y+8)
+	y -= 10
+	text(9, pdfMargin, y, l.Verification+":")
+	hash := view.Receipt.Hash
+	for len(hash) > 0 {
+		y -= 12
+		n := 32
+		if len(hash) < n {
+			n = len(hash)
+		}
+		fmt.Fprintf(&content, "BT /F2 9 Tf %d %d Td %v Tj ET\n", pdfMargin, y, pdfString(hash[:n]))
+		hash = hash[n:]
+	}
+	y -= 18
+	text(8, pdfMargin, y, l.TimeZone)
+
+	fontFile, err := deflate(interRegular)
+	if err != nil {
+		return nil, err
+	}
+	toUnicode := encoder.toUnicode()
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 10 0 R >>",
+			pdfPageWidth, pdfPageHeight),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /" + pdfFontName + " /Encoding /Identity-H /DescendantFonts [6 0 R] /ToUnicode 9 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%v /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor 7 0 R /CIDToGIDMap /Identity /DW %d /W %v >>",
+			pdfFontName, font.width(0), encoder.widths()),
+		fmt.Sprintf("<< /Type /FontDescriptor /FontName /%v /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 8 0 R >>",
+			pdfFontName, font.scale(font.bbox[0]), font.scale(font.bbox[1]), font.scale(font.bbox[2]), font.scale(font.bbox[3]),
+			font.scale(font.ascent), font.scale(font.descent), font.scale(font.capHeight)),
+		fmt.Sprintf("<< /Length %d /Length1 %d /Filter /FlateDecode >>\nstream\n%s\nendstream", len(fontFile), len(interRegular), fontFile),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%vendstream", len(toUnicode), toUnicode),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%vendstream", content.Len(), content.String()),
+	}
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%v\nendobj\n", i+1, object)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return pdf.Bytes(), nil
+}
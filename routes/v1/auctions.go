@@ -1,13 +1,92 @@
 package v1
 
 import (
+	"os"
+
+	"github.com/devzatruk/bizhubBackend/auctionservice"
+	"github.com/devzatruk/bizhubBackend/config"
 	controllers "github.com/devzatruk/bizhubBackend/controllers/v1"
+	"github.com/devzatruk/bizhubBackend/helpers"
 	"github.com/devzatruk/bizhubBackend/middlewares"
+	"github.com/devzatruk/bizhubBackend/ojologger"
+	"github.com/devzatruk/bizhubBackend/ws"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func SetupV1AuctionRoutes(router fiber.Router) {
 	auctions := router.Group("/auctions")
+	auctions.Use("/realtime", func(c *fiber.Ctx) error {
+		if websocket.IsWebSocketUpgrade(c) {
+			c.Locals("allowed", true)
+			return c.Next()
+		}
+		return fiber.ErrUpgradeRequired
+	})
+	auctions.Get("/realtime", config.OjoWS.NewClient(func(ojoWS *ws.OjoWS, client *ws.OjoWebsocketClient) {
+		logger := ojologger.LoggerService.Logger("AuctionRealtimeClient")
+
+		client.On("secret", func(data ...any) {
+			log := logger.Group("realtime()")
+			if len(data) == 0 {
+				client.Close()
+				return
+			}
+			accessToken, ok := data[0].(string)
+			if !ok {
+				client.Close()
+				return
+			}
+
+			customer, err := helpers.ValidateToken(accessToken, os.Getenv(config.ACCT_PUBLIC_KEY))
+			if err != nil {
+				log.Error(err)
+				client.Close()
+				return
+			}
+			customerAsMap := customer.(map[string]any)
+			if customerAsMap["seller_id"] == nil {
+				log.Errorf("customer %v is not a seller", customerAsMap["_id"])
+				client.Close()
+				return
+			}
+
+			// payload: auction_id
+			client.On("join-auction", func(data ...any) {
+				if len(data) == 0 {
+					return
+				}
+				auctionIdStr, ok := data[0].(string)
+				if !ok {
+					return
+				}
+				auctionId, err := primitive.ObjectIDFromHex(auctionIdStr)
+				if err != nil {
+					log.Error(err)
+					return
+				}
+				client.Join(auctionservice.RoomName(auctionId))
+				client.Emit("joined-auction", auctionId.Hex())
+			})
+
+			client.On("leave-auction", func(data ...any) {
+				if len(data) == 0 {
+					return
+				}
+				auctionIdStr, ok := data[0].(string)
+				if !ok {
+					return
+				}
+				auctionId, err := primitive.ObjectIDFromHex(auctionIdStr)
+				if err != nil {
+					log.Error(err)
+					return
+				}
+				client.Leave(auctionservice.RoomName(auctionId))
+			})
+		})
+	}))
 	auctions.Get("/",
 		middlewares.DeSerializeCustomer,
 		middlewares.AllowSeller(),
@@ -20,4 +99,16 @@ func SetupV1AuctionRoutes(router fiber.Router) {
 		middlewares.DeSerializeCustomer,
 		middlewares.AllowSeller(),
 		controllers.BidAuction)
+	auctions.Get("/:id/proxy",
+		middlewares.DeSerializeCustomer,
+		middlewares.AllowSeller(),
+		controllers.GetProxyBid)
+	auctions.Post("/:id/proxy",
+		middlewares.DeSerializeCustomer,
+		middlewares.AllowSeller(),
+		controllers.SetProxyBid)
+	auctions.Delete("/:id/proxy",
+		middlewares.DeSerializeCustomer,
+		middlewares.AllowSeller(),
+		controllers.RemoveProxyBid)
 }
@@ -9,7 +9,9 @@ import (
 func SetupV1ProductsRoutes(router fiber.Router) {
 	products := router.Group("products")
 	products.Get("/search", v1.SearchProduct)
+	products.Get("/suggest", v1.SuggestProducts)
 	products.Get("/filter", v1.FilterProduct)
+	products.Get("/filter/aggregations", v1.GetProductsFilterAggregations)
 	products.Get("/:id", v1.GetProductDetail)
 	products.Post("/:id/discount",
 		middlewares.DeSerializeCustomer,
@@ -20,12 +20,29 @@ func SetupV1WalletRoutes(router fiber.Router) {
 		middlewares.DeSerializeCustomer,
 		middlewares.AllowSeller(),
 		controllers.PayForPackage)
+	wallet.Get("/pay/preview",
+		middlewares.DeSerializeCustomer,
+		middlewares.AllowSeller(),
+		controllers.PreviewPackagePayment)
+	wallet.Put("/package/auto-renew",
+		middlewares.DeSerializeCustomer,
+		middlewares.AllowSeller(),
+		controllers.SetPackageAutoRenew)
 	wallet.Post("/withdraw/:id/cancel",
 		middlewares.DeSerializeCustomer,
 		middlewares.AllowSeller(),
 		controllers.CancelWithdraw)
+	wallet.Post("/withdraw/:id/code",
+		middlewares.DeSerializeCustomer,
+		middlewares.AllowSeller(),
+		controllers.ReissueWithdrawCode)
 	wallet.Get("/history",
 		middlewares.DeSerializeCustomer,
 		middlewares.AllowSeller(),
 		controllers.GetWalletHistory)
+	wallet.Get("/history/:id/receipt",
+		middlewares.DeSerializeCustomer,
+		middlewares.AllowSeller(),
+		controllers.GetWalletReceipt)
+	wallet.Get("/receipt/verify", controllers.VerifyWalletReceipt)
 }
@@ -0,0 +1,352 @@
+package searchservice
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	CollProducts   = "products"
+	CollSellers    = "sellers"
+	CollBrands     = "brands"
+	CollCategories = "categories"
+	CollCities     = "cities"
+)
+
+// agramlar: heading/name in wajyp, meta (brand, category, seller, city) son, details in az.
+const (
+	weightHeading = 3.0
+	weightMeta    = 2.0
+	weightDetails = 1.0
+	weightName    = 3.0
+	weightCity    = 1.0
+	// query-nin sonky sozi entek yazylyp durka: prefix gabat gelmesi yarym bal.
+	prefixFactor    = 0.5
+	minPrefixLength = 3
+	indexBatchSize  = 500
+)
+
+// product we seller-lerin "search" subdocument-i: normalizasiya edilen term-ler,
+// multikey index-ler bilen $in arkaly gozlenyar (regex yok). Suggest ucin bolsa
+// yatda prefix index (suggest.go).
+type SearchService struct {
+	db            *mongo.Database
+	productFilter bson.M
+	sellerFilter  bson.M
+	suggestions   suggestIndex
+}
+
+func NewSearchService() *SearchService {
+	return &SearchService{
+		productFilter: bson.M{},
+		sellerFilter:  bson.M{},
+		suggestions:   suggestIndex{entries: map[string]*suggestEntry{}},
+	}
+}
+
+func (s *SearchService) Init(db *mongo.Database) {
+	s.db = db
+}
+
+func (s *SearchService) EnsureIndexes(ctx context.Context) error {
+	productIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "search.heading", Value: 1}}},
+		{Keys: bson.D{{Key: "search.details", Value: 1}}},
+		{Keys: bson.D{{Key: "search.meta", Value: 1}}},
+		{Keys: bson.D{{Key: "search.indexed_at", Value: 1}}},
+	}
+	_, err := s.db.Collection(CollProducts).Indexes().CreateMany(ctx, productIndexes)
+	if err != nil {
+		return fmt.Errorf("CreateMany(products): %v", err)
+	}
+	sellerIndexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "search.name", Value: 1}}},
+		{Keys: bson.D{{Key: "search.meta", Value: 1}}},
+		{Keys: bson.D{{Key: "search.indexed_at", Value: 1}}},
+	}
+	_, err = s.db.Collection(CollSellers).Indexes().CreateMany(ctx, sellerIndexes)
+	if err != nil {
+		return fmt.Errorf("CreateMany(sellers): %v", err)
+	}
+	return nil
+}
+
+// indeksi yok ya-da maxAge-den kone bolan dokumentler.
+func StaleFilter(maxAge time.Duration) bson.M {
+	return bson.M{
+		"$or": bson.A{
+			bson.M{"search.indexed_at": bson.M{"$exists": false}},
+			bson.M{"search.indexed_at": bson.M{"$lt": time.Now().Add(-maxAge)}},
+		},
+	}
+}
+
+type productSource struct {
+	Id          interface{}       `bson:"_id"`
+	Heading     map[string]string `bson:"heading"`
+	MoreDetails map[string]string `bson:"more_details"`
+	Brand       string            `bson:"brand"`
+	Category    map[string]string `bson:"category"`
+	Seller      string            `bson:"seller"`
+	Variants    [][]interface{}   `bson:"variants"`
+}
+
+type sellerSource struct {
+	Id   interface{}       `bson:"_id"`
+	Name string            `bson:"name"`
+	City map[string]string `bson:"city"`
+}
+
+func translationTerms(translation map[string]string) []string {
+	terms := []string{}
+	for _, lang := range Languages {
+		terms = append(terms, Terms(translation[lang], lang)...)
+	}
+	return unique(terms)
+}
+
+// filter-e gabat gelyan product-laryn (limit 0 bolsa hemmesi) search term-lerini tazeleyar.
+func (s *SearchService) IndexProducts(ctx context.Context, filter bson.M, limit int64) (int64, error) {
+	pipeline := bson.A{bson.M{"$match": filter}}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.M{"$limit": limit})
+	}
+	pipeline = append(pipeline,
+		bson.M{"$lookup": bson.M{"from": CollBrands, "localField": "brand_id", "foreignField": "_id", "as": "brand"}},
+		bson.M{"$lookup": bson.M{"from": CollCategories, "localField": "category_id", "foreignField": "_id", "as": "category"}},
+		bson.M{"$lookup": bson.M{"from": CollSellers, "localField": "seller_id", "foreignField": "_id", "as": "seller"}},
+		bson.M{
+			"$project": bson.M{
+				"heading":      1,
+				"more_details": 1,
+				"brand":        bson.M{"$first": "$brand.name"},
+				"category":     bson.M{"$first": "$category.name"},
+				"seller":       bson.M{"$first": "$seller.name"},
+				"variants":     "$variants.attrs.value",
+			},
+		},
+	)
+	cursor, err := s.db.Collection(CollProducts).Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, fmt.Errorf("Aggregate(products): %v", err)
+	}
+	defer cursor.Close(ctx)
+	var indexed int64
+	writes := []mongo.WriteModel{}
+	now := time.Now()
+	for cursor.Next(ctx) {
+		var product productSource
+		if err := cursor.Decode(&product); err != nil {
+			return indexed, fmt.Errorf("Decode(product): %v", err)
+		}
+		meta := append(TermsAll(product.Brand), TermsAll(product.Seller)...)
+		meta = append(meta, translationTerms(product.Category)...)
+		// variant bahalary (mysal "gyzyl", "XL") hem tapylsyn
+		for _, values := range product.Variants {
+			for _, value := range values {
+				if text, ok := value.(string); ok {
+					meta = append(meta, TermsAll(text)...)
+				}
+			}
+		}
+		search := bson.M{
+			"heading":    translationTerms(product.Heading),
+			"details":    translationTerms(product.MoreDetails),
+			"meta":       unique(meta),
+			"indexed_at": now,
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": product.Id}).
+			SetUpdate(bson.M{"$set": bson.M{"search": search}}))
+		if len(writes) == indexBatchSize {
+			n, err := s.flush(ctx, CollProducts, writes)
+			indexed += n
+			if err != nil {
+				return indexed, err
+			}
+			writes = writes[:0]
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return indexed, fmt.Errorf("cursor.Err(products): %v", err)
+	}
+	n, err := s.flush(ctx, CollProducts, writes)
+	return indexed + n, err
+}
+
+func (s *SearchService) IndexSellers(ctx context.Context, filter bson.M, limit int64) (int64, error) {
+	pipeline := bson.A{bson.M{"$match": filter}}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.M{"$limit": limit})
+	}
+	pipeline = append(pipeline,
+		bson.M{"$lookup": bson.M{"from": CollCities, "localField": "city_id", "foreignField": "_id", "as": "city"}},
+		bson.M{
+			"$project": bson.M{
+				"name": 1,
+				"city": bson.M{"$first": "$city.name"},
+			},
+		},
+	)
+	cursor, err := s.db.Collection(CollSellers).Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, fmt.Errorf("Aggregate(sellers): %v", err)
+	}
+	defer cursor.Close(ctx)
+	var indexed int64
+	writes := []mongo.WriteModel{}
+	now := time.Now()
+	for cursor.Next(ctx) {
+		var seller sellerSource
+		if err := cursor.Decode(&seller); err != nil {
+			return indexed, fmt.Errorf("Decode(seller): %v", err)
+		}
+		search := bson.M{
+			"name":       TermsAll(seller.Name),
+			"meta":       translationTerms(seller.City),
+			"indexed_at": now,
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": seller.Id}).
+			SetUpdate(bson.M{"$set": bson.M{"search": search}}))
+		if len(writes) == indexBatchSize {
+			n, err := s.flush(ctx, CollSellers, writes)
+			indexed += n
+			if err != nil {
+				return indexed, err
+			}
+			writes = writes[:0]
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return indexed, fmt.Errorf("cursor.Err(sellers): %v", err)
+	}
+	n, err := s.flush(ctx, CollSellers, writes)
+	return indexed + n, err
+}
+
+func (s *SearchService) flush(ctx context.Context, coll string, writes []mongo.WriteModel) (int64, error) {
+	if len(writes) == 0 {
+		return 0, nil
+	}
+	result, err := s.db.Collection(coll).BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, fmt.Errorf("BulkWrite(%v): %v", coll, err)
+	}
+	return result.MatchedCount, nil
+}
+
+type searchField struct {
+	path   string
+	weight float64
+}
+
+var (
+	productFields = []searchField{
+		{"search.heading", weightHeading},
+		{"search.meta", weightMeta},
+		{"search.details", weightDetails},
+	}
+	sellerFields = []searchField{
+		{"search.name", weightName},
+		{"search.meta", weightCity},
+	}
+)
+
+// gozleg pipeline-y: $match (her soz haysy-da bolsa bir field-de), "search_score"
+// (relevance + popularity), $sort, $skip, $limit. Query-de soz yok bolsa nil.
+// Gaytarylyan dokumentlere caller oz $project-ini gosyar.
+func (s *SearchService) ProductsPipeline(query string, match bson.M, skip, limit int64) bson.A {
+	popularity := bson.M{
+		"$add": bson.A{
+			bson.M{"$multiply": bson.A{0.5, logCount("$viewed")}},
+			logCount("$likes"),
+		},
+	}
+	return searchPipeline(query, productFields, popularity, match, skip, limit)
+}
+
+func (s *SearchService) SellersPipeline(query string, match bson.M, skip, limit int64) bson.A {
+	return searchPipeline(query, sellerFields, logCount("$likes"), match, skip, limit)
+}
+
+// log10(1 + count): 10 like → 1 bal, 1000 viewed → 1.5 bal; relevance-den agdyk dal.
+func logCount(field string) bson.M {
+	return bson.M{
+		"$log10": bson.M{
+			"$add": bson.A{1, bson.M{"$max": bson.A{0, bson.M{"$ifNull": bson.A{field, 0}}}}},
+		},
+	}
+}
+
+func searchPipeline(query string, fields []searchField, popularity bson.M, match bson.M, skip, limit int64) bson.A {
+	words := QueryTerms(query)
+	if len(words) == 0 {
+		return nil
+	}
+	conditions := bson.A{}
+	scores := bson.A{popularity}
+	for i, variants := range words {
+		prefix := ""
+		if i == len(words)-1 && len(variants[0]) >= minPrefixLength {
+			prefix = "^" + regexp.QuoteMeta(variants[0])
+		}
+		anyField := bson.A{}
+		for _, field := range fields {
+			anyField = append(anyField, bson.M{field.path: bson.M{"$in": variants}})
+			if prefix != "" {
+				anyField = append(anyField, bson.M{field.path: bson.M{"$regex": prefix}})
+			}
+			scores = append(scores, fieldScore(field, variants, prefix))
+		}
+		conditions = append(conditions, bson.M{"$or": anyField})
+	}
+	filter := bson.M{"$and": conditions}
+	for key, value := range match {
+		filter[key] = value
+	}
+	return bson.A{
+		bson.M{"$match": filter},
+		bson.M{"$addFields": bson.M{"search_score": bson.M{"$add": scores}}},
+		bson.M{"$sort": bson.D{{Key: "search_score", Value: -1}, {Key: "_id", Value: -1}}},
+		bson.M{"$skip": skip},
+		bson.M{"$limit": limit},
+	}
+}
+
+func fieldScore(field searchField, variants []string, prefix string) bson.M {
+	terms := bson.M{"$ifNull": bson.A{"$" + field.path, bson.A{}}}
+	exact := bson.M{
+		"$cond": bson.A{
+			bson.M{"$gt": bson.A{bson.M{"$size": bson.M{"$setIntersection": bson.A{terms, variants}}}, 0}},
+			field.weight,
+			0,
+		},
+	}
+	if prefix == "" {
+		return exact
+	}
+	partial := bson.M{
+		"$cond": bson.A{
+			bson.M{
+				"$anyElementTrue": bson.A{
+					bson.M{
+						"$map": bson.M{
+							"input": terms,
+							"in":    bson.M{"$regexMatch": bson.M{"input": "$$this", "regex": prefix}},
+						},
+					},
+				},
+			},
+			field.weight * prefixFactor,
+			0,
+		},
+	}
+	return bson.M{"$max": bson.A{exact, partial}}
+}
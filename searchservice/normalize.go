@@ -0,0 +1,135 @@
+package searchservice
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Ahli tekst (indeks we query) bir latyn "key"-e owrulyar: diakritik harplar
+// yonekeylesyar (ä→a, ň→n, ş→s...), kiril latyna gecyar, son bolsa ses taydan
+// birmenzes yazgylar birlesyar (ch/ç/ч → c, sh/ş/ш → s, zh/ž/ж → z, w/в → v).
+// Sonun ucin "шоколад", "shokolad" we "şokolad" bir key beryar: iki tarapa
+// transliterasiya ayratyn gerek dal.
+
+var Languages = []string{"tm", "ru", "tr", "en"}
+
+var foldRunes = map[rune]string{
+	'ä': "a", 'á': "a", 'à': "a", 'â': "a", 'ã': "a", 'å': "a",
+	'ç': "c", 'č': "c",
+	'é': "e", 'è': "e", 'ê': "e", 'ë': "e",
+	'ğ': "g",
+	'ı': "i", 'í': "i", 'ì': "i", 'î': "i", 'ï': "i",
+	'ň': "n", 'ñ': "n",
+	'ö': "o", 'ó': "o", 'ò': "o", 'ô': "o", 'õ': "o",
+	'ş': "s", 'š': "s", 'ß': "ss",
+	'ü': "u", 'ú': "u", 'ù': "u", 'û': "u",
+	'ý': "y", 'ÿ': "y",
+	'ž': "z",
+	// kiril
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+	// kone turkmen kiril harplary
+	'ә': "a", 'ң': "n", 'ө': "o", 'ү': "u", 'җ': "j",
+}
+
+// uzynlaryndan baslap: "shch" "sh"-dan on.
+var keyReplacements = strings.NewReplacer(
+	"shch", "s", "sch", "s", "dzh", "j",
+	"zh", "z", "kh", "h", "ch", "c", "sh", "s", "ts", "c",
+	"yo", "o", "yu", "u", "ya", "a", "ye", "e",
+	"w", "v", "q", "k", "x", "ks",
+)
+
+// kicijik harp, diakritiksiz latyn; harp we sandan basga zatlar bosluk.
+func Fold(text string) string {
+	var builder strings.Builder
+	for _, r := range text {
+		if r == 'İ' || r == 'I' {
+			builder.WriteByte('i')
+			continue
+		}
+		r = unicode.ToLower(r)
+		if folded, ok := foldRunes[r]; ok {
+			builder.WriteString(folded)
+			continue
+		}
+		switch {
+		case unicode.Is(unicode.Mn, r): // birlesyan nokat/diakritik
+		case r < 128 && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			builder.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			// basga elipbiyler (arap, hytay...) uytgemeyar.
+			builder.WriteRune(r)
+		default:
+			builder.WriteByte(' ')
+		}
+	}
+	return builder.String()
+}
+
+func Tokenize(text string) []string {
+	return strings.Fields(Fold(text))
+}
+
+// ses taydan birmenzes yazgylary birlesdiryar we goslanan harplary ayyryar ("ll" → "l").
+func Key(token string) string {
+	key := keyReplacements.Replace(token)
+	var builder strings.Builder
+	var last rune
+	for _, r := range key {
+		if r == last {
+			continue
+		}
+		builder.WriteRune(r)
+		last = r
+	}
+	return builder.String()
+}
+
+// indeks ucin: her token-den key we lang-yn stem-inden key.
+func Terms(text string, lang string) []string {
+	terms := []string{}
+	for _, token := range Tokenize(text) {
+		terms = append(terms, Key(token), Key(Stem(token, lang)))
+	}
+	return unique(terms)
+}
+
+// dil belli dal bolsa (seller, brand atlary): dort dilin stem-leri hem.
+func TermsAll(text string) []string {
+	terms := []string{}
+	for _, lang := range Languages {
+		terms = append(terms, Terms(text, lang)...)
+	}
+	return unique(terms)
+}
+
+// query-nin her sozi ucin mumkin bolan key-ler: query haysy dilde yazylanyny bilemizok.
+func QueryTerms(query string) [][]string {
+	tokens := Tokenize(query)
+	result := make([][]string, 0, len(tokens))
+	for _, token := range tokens {
+		variants := []string{Key(token)}
+		for _, lang := range Languages {
+			variants = append(variants, Key(Stem(token, lang)))
+		}
+		result = append(result, unique(variants))
+	}
+	return result
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := values[:0]
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
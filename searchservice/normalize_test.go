@@ -0,0 +1,133 @@
+package searchservice
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	cases := []struct {
+		text string
+		want string
+	}{
+		// turkmen
+		{"Ýüzüm", "yuzum"},
+		{"Köýnek", "koynek"},
+		{"Gülälek", "gulalek"},
+		{"Ňa", "na"},
+		{"Žurnal", "zurnal"},
+		// turk: İ/I we ı hem i bolyar
+		{"İstanbul", "istanbul"},
+		{"ISTANBUL", "istanbul"},
+		{"Çörek", "corek"},
+		{"Dağ ışık", "dag isik"},
+		// kiril latyna
+		{"шоколад", "shokolad"},
+		{"Чай", "chay"},
+		{"Мыло", "mylo"},
+		// kone turkmen kiril
+		{"әңөүҗ", "anouj"},
+		// harp we sandan basga zatlar bosluk
+		{"iPhone-14!", "iphone 14 "},
+	}
+	for _, c := range cases {
+		if got := Fold(c.text); got != c.want {
+			t.Errorf("Fold(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+// dursi bir key bermeli: query haysy yazgyda bolsa-da indeks bilen gabat gelyar.
+func TestKeyAcrossScripts(t *testing.T) {
+	groups := [][]string{
+		{"шоколад", "shokolad", "şokolad", "Şokolad"},
+		{"чай", "çay", "chay", "Çaý"},
+		{"ýüzüm", "yuzum", "üzüm", "Юзум"},
+		{"журнал", "zhurnal", "žurnal"},
+		{"хлеб", "khleb", "hleb"},
+		{"watsap", "vatsap", "ватсап"},
+		{"Allo", "alo", "алло"},
+	}
+	for _, group := range groups {
+		want := Key(Fold(group[0]))
+		for _, text := range group[1:] {
+			if got := Key(Fold(text)); got != want {
+				t.Errorf("Key(Fold(%q)) = %q, want %q (as %q)", text, got, want, group[0])
+			}
+		}
+	}
+}
+
+func TestStem(t *testing.T) {
+	cases := []struct {
+		token string
+		lang  string
+		want  string
+	}{
+		// turkmen: iki gosulma
+		{"kitaplarda", "tm", "kitap"},
+		{"kitaplarynda", "tm", "kitap"},
+		{"telefonlar", "tm", "telefon"},
+		{"kitap", "tm", "kitap"},
+		// turk
+		{"kitaplardan", "tr", "kitap"},
+		{"evlerde", "tr", "evler"}, // "ev" minStemLength-den gysga
+		// rus (Fold()-dan son)
+		{"telefony", "ru", "telefon"},
+		{"telefonami", "ru", "telefon"},
+		// inlis
+		{"batteries", "en", "battery"},
+		{"phones", "en", "phon"},
+		{"glass", "en", "glass"},
+		{"status", "en", "status"},
+		// gysga, sanly we latyn dal sozler uytgemeyar
+		{"sut", "tm", "sut"},
+		{"iphone14", "en", "iphone14"},
+		{"çay", "tm", "çay"},
+		// belli dal dil
+		{"kitaplarda", "de", "kitaplarda"},
+	}
+	for _, c := range cases {
+		if got := Stem(c.token, c.lang); got != c.want {
+			t.Errorf("Stem(%q, %q) = %q, want %q", c.token, c.lang, got, c.want)
+		}
+	}
+}
+
+// query-nin her sozunin key-lerinden biri indeks term-lerinde bolmaly.
+func TestQueryTermsMatchIndex(t *testing.T) {
+	cases := []struct {
+		query string
+		text  string
+		lang  string
+	}{
+		{"телефоны", "Telefon", "tm"},
+		{"telefonlar", "Телефон", "ru"},
+		{"kitaplarda", "Kitap", "tm"},
+		{"Şokolad", "шоколад", "ru"},
+		{"yuzum", "Üzüm", "tr"},
+		{"batteries", "Battery", "en"},
+		{"çay telefon", "Чай и телефон", "ru"},
+	}
+	for _, c := range cases {
+		terms := map[string]bool{}
+		for _, term := range Terms(c.text, c.lang) {
+			terms[term] = true
+		}
+		for i, variants := range QueryTerms(c.query) {
+			found := false
+			for _, variant := range variants {
+				if terms[variant] {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("query %q word %v (%v) not in Terms(%q, %q) = %v", c.query, i, variants, c.text, c.lang, Terms(c.text, c.lang))
+			}
+		}
+	}
+}
+
+func TestQueryTermsEmpty(t *testing.T) {
+	if terms := QueryTerms(" -!? "); len(terms) != 0 {
+		t.Errorf("QueryTerms(punctuation) = %v, want none", terms)
+	}
+}
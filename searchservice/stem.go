@@ -0,0 +1,75 @@
+package searchservice
+
+import "strings"
+
+// Yenil stemming: Fold()-dan son latyn yazgyda sozun sonundaky gosulmalar ayrylyar.
+// Doly morfologiya dal, yone "kitaplarda"/"kitap", "телефоны"/"телефон" yaly
+// gornusleri bir key-e getiryar. Kok minStemLength-den gysga bolmaz.
+const minStemLength = 3
+
+// uzynlaryndan baslap, birinji gabat geleni ayrylyar.
+var suffixes = map[string][]string{
+	"tm": {
+		"laryndan", "lerinden", "larynda", "lerinde", "larynyn", "lerinin",
+		"lary", "leri", "lar", "ler", "dan", "den", "nyn", "nin", "nun",
+		"da", "de", "ny", "ni", "na", "ne", "ym", "im", "yn", "in", "y", "i", "a", "e",
+	},
+	"tr": {
+		"larindan", "lerinden", "larinda", "lerinde", "larin", "lerin",
+		"lari", "leri", "lar", "ler", "dan", "den", "tan", "ten", "nin", "nun",
+		"in", "un", "da", "de", "ta", "te", "ya", "ye", "yi", "yu", "i", "u", "a", "e",
+	},
+	"ru": {
+		"iyami", "yami", "ami", "ykh", "ikh", "ymi", "imi", "ogo", "ego", "omu", "emu",
+		"aya", "yaya", "oye", "yye", "iye", "uyu", "yuyu", "ayu",
+		"iy", "yy", "oy", "ey", "om", "em", "ov", "ev", "am", "ya", "yu", "ye",
+		"a", "y", "i", "o", "u", "e",
+	},
+	"en": {
+		"ing", "ies", "es", "ed", "ly", "s",
+	},
+}
+
+// turkmen we turk dilleri agglutinatiw: iki gezek ("kitap+lar+da").
+var stemPasses = map[string]int{"tm": 2, "tr": 2, "ru": 1, "en": 1}
+
+func Stem(token string, lang string) string {
+	list, ok := suffixes[lang]
+	if !ok {
+		return token
+	}
+	for pass := 0; pass < stemPasses[lang]; pass++ {
+		stripped := stripSuffix(token, list, lang)
+		if stripped == token {
+			break
+		}
+		token = stripped
+	}
+	return token
+}
+
+func stripSuffix(token string, list []string, lang string) string {
+	// sanlar we latyn dal sozler uytgemeyar.
+	for _, r := range token {
+		if r >= 128 || (r >= '0' && r <= '9') {
+			return token
+		}
+	}
+	longest := ""
+	for _, suffix := range list {
+		if len(suffix) > len(longest) && strings.HasSuffix(token, suffix) && len(token)-len(suffix) >= minStemLength {
+			longest = suffix
+		}
+	}
+	if longest == "" {
+		return token
+	}
+	stem := token[:len(token)-len(longest)]
+	switch {
+	case lang == "en" && longest == "ies":
+		return stem + "y"
+	case lang == "en" && longest == "s" && (strings.HasSuffix(stem, "s") || strings.HasSuffix(stem, "u")):
+		return token // "glass", "status"
+	}
+	return stem
+}
@@ -0,0 +1,397 @@
+package searchservice
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var CollSearchQueries = "search_queries"
+
+const (
+	SuggestQuery    = "query"
+	SuggestProduct  = "product"
+	SuggestCategory = "category"
+	SuggestBrand    = "brand"
+	SuggestSeller   = "seller"
+
+	// search_queries-dan yatda saklanyan in kop query, in az gozlenen sany.
+	suggestQueriesLimit   = 2000
+	suggestQueryMinCount  = 2
+	suggestQueryMaxLength = 64
+	// category/brand/seller bir product-dan on gorunsin.
+	taxonomyBonus = 1.0
+	sellerBonus   = 0.5
+)
+
+// typeahead netijesi: query-ler (yzygiderli gozlenenler) hemise birinji.
+type Suggestion struct {
+	Id    *primitive.ObjectID `json:"_id"`
+	Type  string              `json:"type"`
+	Text  string              `json:"text"`
+	Image *string             `json:"image"`
+}
+
+type suggestEntry struct {
+	id     *primitive.ObjectID
+	kind   string
+	text   map[string]string // dil → tekst; brand/seller atlary ucin hemme dilde birmenzes
+	image  *string
+	weight float64
+	keys   []string
+}
+
+type suggestToken struct {
+	key   string
+	entry *suggestEntry
+}
+
+// yatda saklanyan prefix index: entry-ler id boyunca, token-ler bolsa key boyunca
+// tertiplenen sanaw (binary search). Uytgande tokens tazeden gurulyar.
+type suggestIndex struct {
+	mu      sync.RWMutex
+	entries map[string]*suggestEntry
+	tokens  []suggestToken
+	dirty   bool
+}
+
+func entryKey(kind string, id string) string {
+	return kind + ":" + id
+}
+
+func newSuggestEntry(id *primitive.ObjectID, kind string, text map[string]string, image *string, weight float64) *suggestEntry {
+	keys := []string{}
+	for _, value := range text {
+		for _, token := range Tokenize(value) {
+			keys = append(keys, Key(token))
+		}
+	}
+	return &suggestEntry{id: id, kind: kind, text: text, image: image, weight: weight, keys: unique(keys)}
+}
+
+func sameText(text string) map[string]string {
+	translations := map[string]string{}
+	for _, lang := range Languages {
+		translations[lang] = text
+	}
+	return translations
+}
+
+func (index *suggestIndex) set(key string, entry *suggestEntry) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if entry == nil {
+		delete(index.entries, key)
+	} else {
+		index.entries[key] = entry
+	}
+	index.dirty = true
+}
+
+func (index *suggestIndex) replace(entries map[string]*suggestEntry) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.entries = entries
+	index.dirty = true
+}
+
+// dirty bolsa tokens tazeden gurulyar; sonky read lock bilen okalyar.
+func (index *suggestIndex) sorted() []suggestToken {
+	index.mu.RLock()
+	if !index.dirty {
+		tokens := index.tokens
+		index.mu.RUnlock()
+		return tokens
+	}
+	index.mu.RUnlock()
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if index.dirty {
+		tokens := []suggestToken{}
+		for _, entry := range index.entries {
+			for _, key := range entry.keys {
+				tokens = append(tokens, suggestToken{key: key, entry: entry})
+			}
+		}
+		sort.Slice(tokens, func(a, b int) bool { return tokens[a].key < tokens[b].key })
+		index.tokens = tokens
+		index.dirty = false
+	}
+	return index.tokens
+}
+
+func hasKeyWithPrefix(keys []string, prefix string) bool {
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// query-nin her sozi entry-nin haysy-da bolsa bir sozunin basy bolmaly.
+func (s *SearchService) Suggest(query string, lang string, limit int) []Suggestion {
+	suggestions := []Suggestion{}
+	prefixes := []string{}
+	for _, token := range Tokenize(query) {
+		prefixes = append(prefixes, Key(token))
+	}
+	prefixes = unique(prefixes)
+	if len(prefixes) == 0 || limit <= 0 {
+		return suggestions
+	}
+	// in uzyn prefix in az token-i beryar.
+	longest := prefixes[0]
+	for _, prefix := range prefixes {
+		if len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+	tokens := s.suggestions.sorted()
+	start := sort.Search(len(tokens), func(i int) bool { return tokens[i].key >= longest })
+	seen := map[*suggestEntry]bool{}
+	candidates := []*suggestEntry{}
+	for i := start; i < len(tokens) && strings.HasPrefix(tokens[i].key, longest); i++ {
+		entry := tokens[i].entry
+		if seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		matches := true
+		for _, prefix := range prefixes {
+			if !hasKeyWithPrefix(entry.keys, prefix) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			candidates = append(candidates, entry)
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		queryA, queryB := candidates[a].kind == SuggestQuery, candidates[b].kind == SuggestQuery
+		if queryA != queryB {
+			return queryA
+		}
+		return candidates[a].weight > candidates[b].weight
+	})
+	for _, entry := range candidates {
+		if len(suggestions) == limit {
+			break
+		}
+		text := entry.text[lang]
+		if text == "" {
+			text = entry.text[Languages[0]]
+		}
+		if text == "" {
+			continue
+		}
+		suggestions = append(suggestions, Suggestion{Id: entry.id, Type: entry.kind, Text: text, Image: entry.image})
+	}
+	return suggestions
+}
+
+func popularity(viewed int64, likes int64) float64 {
+	return 0.5*math.Log10(1+math.Max(0, float64(viewed))) + math.Log10(1+math.Max(0, float64(likes)))
+}
+
+type suggestProduct struct {
+	Id      primitive.ObjectID `bson:"_id"`
+	Heading map[string]string  `bson:"heading"`
+	Image   *string            `bson:"image"`
+	Viewed  int64              `bson:"viewed"`
+	Likes   int64              `bson:"likes"`
+}
+
+func (s *SearchService) findSuggestProducts(ctx context.Context, filter bson.M) ([]suggestProduct, error) {
+	match := bson.M{}
+	for key, value := range s.productFilter {
+		match[key] = value
+	}
+	for key, value := range filter {
+		match[key] = value
+	}
+	cursor, err := s.db.Collection(CollProducts).Aggregate(ctx, bson.A{
+		bson.M{"$match": match},
+		bson.M{
+			"$project": bson.M{
+				"heading": 1,
+				"image":   bson.M{"$first": "$images"},
+				"viewed":  1,
+				"likes":   1,
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Aggregate(products): %v", err)
+	}
+	var products []suggestProduct
+	if err = cursor.All(ctx, &products); err != nil {
+		return nil, fmt.Errorf("cursor.All(products): %v", err)
+	}
+	return products, nil
+}
+
+func productEntry(product suggestProduct) *suggestEntry {
+	id := product.Id
+	return newSuggestEntry(&id, SuggestProduct, product.Heading, product.Image, popularity(product.Viewed, product.Likes))
+}
+
+// checker product-y publish edende: indiki RefreshSuggestions-a garasman.
+func (s *SearchService) RefreshProductSuggestion(ctx context.Context, productId primitive.ObjectID) error {
+	products, err := s.findSuggestProducts(ctx, bson.M{"_id": productId})
+	if err != nil {
+		return err
+	}
+	key := entryKey(SuggestProduct, productId.Hex())
+	if len(products) == 0 {
+		s.suggestions.set(key, nil)
+		return nil
+	}
+	s.suggestions.set(key, productEntry(products[0]))
+	return nil
+}
+
+// hemme suggestion-lary DB-den tazeden yukleyar.
+func (s *SearchService) RefreshSuggestions(ctx context.Context) error {
+	entries := map[string]*suggestEntry{}
+
+	products, err := s.findSuggestProducts(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	productCounts := map[string]map[primitive.ObjectID]int64{"category_id": {}, "brand_id": {}}
+	for _, product := range products {
+		entries[entryKey(SuggestProduct, product.Id.Hex())] = productEntry(product)
+	}
+	for field, counts := range productCounts {
+		cursor, err := s.db.Collection(CollProducts).Aggregate(ctx, bson.A{
+			bson.M{"$match": s.productFilter},
+			bson.M{"$group": bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}},
+		})
+		if err != nil {
+			return fmt.Errorf("Aggregate(%v): %v", field, err)
+		}
+		var groups []struct {
+			Id    primitive.ObjectID `bson:"_id"`
+			Count int64              `bson:"count"`
+		}
+		if err = cursor.All(ctx, &groups); err != nil {
+			return fmt.Errorf("cursor.All(%v): %v", field, err)
+		}
+		for _, group := range groups {
+			counts[group.Id] = group.Count
+		}
+	}
+
+	var categories []struct {
+		Id    primitive.ObjectID `bson:"_id"`
+		Name  map[string]string  `bson:"name"`
+		Image *string            `bson:"image"`
+	}
+	cursor, err := s.db.Collection(CollCategories).Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"name": 1, "image": 1}))
+	if err != nil {
+		return fmt.Errorf("Find(categories): %v", err)
+	}
+	if err = cursor.All(ctx, &categories); err != nil {
+		return fmt.Errorf("cursor.All(categories): %v", err)
+	}
+	for _, category := range categories {
+		id := category.Id
+		weight := taxonomyBonus + math.Log10(1+float64(productCounts["category_id"][id]))
+		entries[entryKey(SuggestCategory, id.Hex())] = newSuggestEntry(&id, SuggestCategory, category.Name, category.Image, weight)
+	}
+
+	var brands []struct {
+		Id   primitive.ObjectID `bson:"_id"`
+		Name string             `bson:"name"`
+		Logo *string            `bson:"logo"`
+	}
+	cursor, err = s.db.Collection(CollBrands).Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"name": 1, "logo": 1}))
+	if err != nil {
+		return fmt.Errorf("Find(brands): %v", err)
+	}
+	if err = cursor.All(ctx, &brands); err != nil {
+		return fmt.Errorf("cursor.All(brands): %v", err)
+	}
+	for _, brand := range brands {
+		id := brand.Id
+		weight := taxonomyBonus + math.Log10(1+float64(productCounts["brand_id"][id]))
+		entries[entryKey(SuggestBrand, id.Hex())] = newSuggestEntry(&id, SuggestBrand, sameText(brand.Name), brand.Logo, weight)
+	}
+
+	var sellers []struct {
+		Id    primitive.ObjectID `bson:"_id"`
+		Name  string             `bson:"name"`
+		Logo  *string            `bson:"logo"`
+		Likes int64              `bson:"likes"`
+	}
+	cursor, err = s.db.Collection(CollSellers).Find(ctx, s.sellerFilter, options.Find().SetProjection(bson.M{"name": 1, "logo": 1, "likes": 1}))
+	if err != nil {
+		return fmt.Errorf("Find(sellers): %v", err)
+	}
+	if err = cursor.All(ctx, &sellers); err != nil {
+		return fmt.Errorf("cursor.All(sellers): %v", err)
+	}
+	for _, seller := range sellers {
+		id := seller.Id
+		weight := sellerBonus + popularity(0, seller.Likes)
+		entries[entryKey(SuggestSeller, id.Hex())] = newSuggestEntry(&id, SuggestSeller, sameText(seller.Name), seller.Logo, weight)
+	}
+
+	var queries []struct {
+		Key   string `bson:"_id"`
+		Text  string `bson:"text"`
+		Count int64  `bson:"count"`
+	}
+	cursor, err = s.db.Collection(CollSearchQueries).Find(ctx,
+		bson.M{"count": bson.M{"$gte": suggestQueryMinCount}},
+		options.Find().SetSort(bson.M{"count": -1}).SetLimit(suggestQueriesLimit))
+	if err != nil {
+		return fmt.Errorf("Find(search_queries): %v", err)
+	}
+	if err = cursor.All(ctx, &queries); err != nil {
+		return fmt.Errorf("cursor.All(search_queries): %v", err)
+	}
+	for _, query := range queries {
+		entries[entryKey(SuggestQuery, query.Key)] = newSuggestEntry(nil, SuggestQuery, sameText(query.Text), nil, float64(query.Count))
+	}
+
+	s.suggestions.replace(entries)
+	return nil
+}
+
+// netije beren gozleg query-leri sanalyar: kop gozlenenler suggest-de birinji.
+func (s *SearchService) LogQuery(ctx context.Context, query string) error {
+	tokens := Tokenize(query)
+	key := strings.Join(tokens, " ")
+	if key == "" || len(key) > suggestQueryMaxLength {
+		return nil
+	}
+	text := strings.ToLower(strings.Join(strings.Fields(query), " "))
+	_, err := s.db.Collection(CollSearchQueries).UpdateOne(ctx,
+		bson.M{"_id": key},
+		bson.M{
+			"$inc": bson.M{"count": 1},
+			"$set": bson.M{"text": text, "last_at": time.Now()},
+		},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("UpdateOne(search_queries): %v", err)
+	}
+	return nil
+}
+
+func (s *SearchService) SetSuggestFilters(productFilter bson.M, sellerFilter bson.M) *SearchService {
+	s.productFilter = productFilter
+	s.sellerFilter = sellerFilter
+	return s
+}
@@ -143,9 +143,10 @@ func (s *StatisticsService) createNewEmptyStatisticDocument(date time.Time) {
 	statistic := Statistic{
 		Date: date,
 		Money: StatisticMoney{
-			Total:     0,
-			Deposited: 0,
-			Withdrew:  0,
+			Total:      0,
+			Deposited:  0,
+			Withdrew:   0,
+			Discounted: 0,
 		},
 		PublishedPosts:    0,
 		PublishedProducts: 0,
@@ -228,9 +229,10 @@ func (s *StatisticsService) createNewStatisticDocument(date time.Time) {
 	statistic := Statistic{
 		Date: date,
 		Money: StatisticMoney{
-			Total:     oldStatistic.Money.Total,
-			Deposited: 0,
-			Withdrew:  0,
+			Total:      oldStatistic.Money.Total,
+			Deposited:  0,
+			Withdrew:   0,
+			Discounted: 0,
 		},
 		PublishedPosts:    0,
 		PublishedProducts: 0,
@@ -88,9 +88,10 @@ type StatisticReportPublishedProductsAndPosts struct {
 }
 
 type StatisticReportMoneyActivity struct {
-	Deposited float64 `json:"deposited" bson:"deposited"`
-	Withdrew  float64 `json:"withdrew"  bson:"withdrew"`
-	Total     float64 `json:"total" bson:"total"`
+	Deposited  float64 `json:"deposited" bson:"deposited"`
+	Withdrew   float64 `json:"withdrew"  bson:"withdrew"`
+	Discounted float64 `json:"discounted" bson:"discounted"`
+	Total      float64 `json:"total" bson:"total"`
 }
 
 type StatisticReportExpensesActivity struct {
@@ -105,10 +106,11 @@ type StatisticReportUsersAndSellersActivityDetail struct {
 }
 
 type StatisticReportMoneyActivityDetail struct {
-	Date      time.Time `json:"date" bson:"date"`
-	Deposited float64   `json:"deposited" bson:"deposited"`
-	Withdrew  float64   `json:"withdrew" bson:"withdrew"`
-	Total     float64   `json:"total" bson:"total"`
+	Date       time.Time `json:"date" bson:"date"`
+	Deposited  float64   `json:"deposited" bson:"deposited"`
+	Withdrew   float64   `json:"withdrew" bson:"withdrew"`
+	Discounted float64   `json:"discounted" bson:"discounted"`
+	Total      float64   `json:"total" bson:"total"`
 }
 
 type StatisticReportPublishedProductsAndPostsDetail struct {
@@ -644,7 +646,10 @@ func (r *StatisticsServiceReader) MoneyActivity() (*StatisticReportMoneyActivity
 				"$project": bson.M{
 					"deposited": "$money.deposited",
 					"withdrew":  "$money.withdrew",
-					"total":     "$money.total",
+					"discounted": bson.M{
+						"$ifNull": bson.A{"$money.discounted", 0},
+					},
+					"total": "$money.total",
 				},
 			},
 		})
@@ -671,7 +676,10 @@ func (r *StatisticsServiceReader) MoneyActivity() (*StatisticReportMoneyActivity
 				"$project": bson.M{
 					"deposited": "$money.deposited",
 					"withdrew":  "$money.withdrew",
-					"total":     "$money.total",
+					"discounted": bson.M{
+						"$ifNull": bson.A{"$money.discounted", 0},
+					},
+					"total": "$money.total",
 					"value_": bson.M{
 						"$dateToParts": bson.M{
 							"date": "$date",
@@ -708,6 +716,7 @@ func (r *StatisticsServiceReader) MoneyActivity() (*StatisticReportMoneyActivity
 
 			moneyActivity.Deposited += activity.Deposited
 			moneyActivity.Withdrew += activity.Withdrew
+			moneyActivity.Discounted += activity.Discounted
 		}
 
 		if err := cursor.Err(); err != nil {
@@ -921,7 +930,10 @@ func (r *StatisticsServiceReader) MoneyActivityDetail(page int, limit int) ([]St
 				"date":      1,
 				"deposited": "$money.deposited",
 				"withdrew":  "$money.withdrew",
-				"total":     "$money.total",
+				"discounted": bson.M{
+					"$ifNull": bson.A{"$money.discounted", 0},
+				},
+				"total": "$money.total",
 			},
 		},
 	})
@@ -7,9 +7,10 @@ import (
 )
 
 type StatisticMoney struct {
-	Total     float64 `json:"total" bson:"total"`
-	Deposited float64 `json:"deposited" bson:"deposited"`
-	Withdrew  float64 `json:"withdrew" bson:"withdrew"`
+	Total      float64 `json:"total" bson:"total"`
+	Deposited  float64 `json:"deposited" bson:"deposited"`
+	Withdrew   float64 `json:"withdrew" bson:"withdrew"`
+	Discounted float64 `json:"discounted" bson:"discounted"` // coupon arzanladyslary, total-a gosulmayar
 }
 type StatisticExpense struct {
 	Id     primitive.ObjectID `json:"_id" bson:"_id" mapstructure:"_id"`
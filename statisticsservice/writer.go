@@ -75,6 +75,10 @@ func (t *StatisticWriterEventType) moneyWithdrawEventString() string {
 	return "money_withdraw"
 }
 
+func (t *StatisticWriterEventType) moneyDiscountEventString() string {
+	return "money_discount"
+}
+
 // event
 
 type StatisticWriterEvent struct {
@@ -159,6 +163,21 @@ func (w *StatisticsServiceWriter) run() {
 				}
 
 				log.Logf("amount withdrawn: -%v", amount)
+			} else if event.Type.Name == event.Type.moneyDiscountEventString() {
+				log := w.logger.Group("Run.MoneyDiscounted()")
+
+				amount := event.Payload.(float64)
+				_, err := w.service.coll.UpdateOne(context.Background(), bsonFilter, bson.M{
+					"$inc": bson.M{"money.discounted": amount},
+				})
+
+				if err != nil {
+					log.Error(err)
+					w.errorEvent(event, err)
+					continue
+				}
+
+				log.Logf("amount discounted: %v", amount)
 			} else if event.Type.Name == event.Type.newActiveSellerEventString() {
 				log := w.logger.Group("Run.NewActiveSeller()")
 				_, err := w.service.coll.UpdateOne(context.Background(), bsonFilter, bson.M{
@@ -473,7 +492,7 @@ func (w *StatisticsServiceWriter) NewPublishedPost() {
 	}
 }
 
-// money deposit/withdraw 2
+// money deposit/withdraw/discount 3
 
 func (w *StatisticsServiceWriter) MoneyDeposited(amount float64) {
 	type_ := StatisticWriterEventType{}
@@ -494,3 +513,13 @@ func (w *StatisticsServiceWriter) MoneyWithdrew(amount float64) {
 		Payload: amount,
 	}
 }
+
+func (w *StatisticsServiceWriter) MoneyDiscounted(amount float64) {
+	type_ := StatisticWriterEventType{}
+	type_.setType(type_.moneyDiscountEventString())
+
+	w.queue <- &StatisticWriterEvent{
+		Type:    type_,
+		Payload: amount,
+	}
+}
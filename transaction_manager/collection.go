@@ -45,7 +45,7 @@ func (c *TransactionManagerCollection) FindOneAndUpdate(
 	if err == nil {
 		action := (&TransactionAction{Collection: c}).
 			SetType(TransactionActionType{Update: TransactionActionTypeDetail{One: true}}).
-			SetFilter(model.filter).
+			SetFilter(model.getRollbackFilter()).
 			SetUpdate(model.update).
 			SetRollbackUpdateWithOldData(model.rollbackUpdateWithOldData)
 
@@ -86,7 +86,7 @@ func (c *TransactionManagerCollection) UpdateOne(model *TransactionModel,
 	if err == nil {
 		action := (&TransactionAction{Collection: c}).
 			SetType(TransactionActionType{Update: TransactionActionTypeDetail{One: true}}).
-			SetFilter(model.filter).
+			SetFilter(model.getRollbackFilter()).
 			SetUpdate(model.update).
 			SetRollbackUpdate(model.rollbackUpdate)
 		c.manager.newAction(action)
@@ -6,6 +6,7 @@ type TransactionModel struct {
 	filter                    bson.M //interface{}
 	update                    bson.M // interface{}
 	rollbackUpdate            bson.M // interface{}
+	rollbackFilter            bson.M
 	rollbackUpdateWithOldData func(interface{}) bson.M
 	insertDocument            interface{}
 	insertManyDocuments       []interface{}
@@ -30,6 +31,19 @@ func (t *TransactionModel) SetRollbackUpdate(rollbackUpdate bson.M) *Transaction
 	return t
 }
 
+// filter update-den son gabat gelmeyan bolsa (mes. $inc edilen field barlanyar), rollback su filter-i ulanyar.
+func (t *TransactionModel) SetRollbackFilter(filter bson.M) *TransactionModel {
+	t.rollbackFilter = filter
+	return t
+}
+
+func (t *TransactionModel) getRollbackFilter() bson.M {
+	if t.rollbackFilter != nil {
+		return t.rollbackFilter
+	}
+	return t.filter
+}
+
 func (t *TransactionModel) SetRollbackUpdateWithOldData(f func(interface{}) bson.M) *TransactionModel {
 	t.rollbackUpdateWithOldData = f
 	return t